@@ -29,6 +29,7 @@ import (
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
 	"github.com/stolostron/managedcluster-import-controller/pkg/source"
+	"github.com/stolostron/managedcluster-import-controller/pkg/webhook"
 	"k8s.io/client-go/informers"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/tools/cache"
@@ -49,6 +50,7 @@ import (
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 
+	corev1 "k8s.io/api/core/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -64,14 +66,22 @@ import (
 	_ "net/http/pprof"
 
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
 // Change below variables to serve metrics on different host or port.
 var metricsPort = 8383
 
+// Change below variables to serve the validating webhooks on different port or with different certificates.
+var (
+	webhookPort    = 9443
+	webhookCertDir = "/webhook"
+)
+
 var (
 	Burst int     = 100
 	QPS   float32 = 50
@@ -95,6 +105,12 @@ func init() {
 }
 
 func main() {
+	// the render subcommand renders the import manifests of a managed cluster offline, e.g. to diff the manifests
+	// across the controller versions before upgrading the hub
+	if len(os.Args) > 1 && os.Args[1] == renderCommand {
+		os.Exit(runRender(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	var leaderElectionNamespace = ""
 	var enablePprof = false
 	if enablePprofEnv, exists := os.LookupEnv("ENABLE_PPROF"); exists {
@@ -109,9 +125,15 @@ func main() {
 	var clusterIngressDomain string
 	var enableFlightCtl = false
 	var flightctlServer string
+	var agentRegistrationQPS float64
+	var agentRegistrationBurst int
 	pflag.StringVar(&clusterIngressDomain, "cluster-ingress-domain", "", "the ingress domain of the cluster")
 	pflag.BoolVar(&enableFlightCtl, "enable-flightctl", false, "enable flightctl")
 	pflag.StringVar(&flightctlServer, "flightctl-server", "", "the server address of the flightctl")
+	pflag.Float64Var(&agentRegistrationQPS, "agent-registration-qps", agentregistration.DefaultRateLimitQPS,
+		"the maximum QPS of the requests from a user to the agent registration server")
+	pflag.IntVar(&agentRegistrationBurst, "agent-registration-burst", agentregistration.DefaultRateLimitBurst,
+		"the maximum burst of the requests from a user to the agent registration server")
 
 	pflag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "", "required when the process is not running in cluster")
 	pflag.BoolVar(&helpers.DeployOnOCP, "deploy-on-ocp", true, "used to deploy the controller on OCP or not")
@@ -284,6 +306,7 @@ func main() {
 	if err := managedclusterInformer.AddIndexers(
 		cache.Indexers{
 			importconfig.ManagedClusterKlusterletConfigAnnotationIndexKey: importconfig.IndexManagedClusterByKlusterletconfigAnnotation,
+			importconfig.ManagedClusterLabelIndexKey:                      importconfig.IndexManagedClusterByLabels,
 		},
 	); err != nil {
 		setupLog.Error(err, "failed to add indexers to managedcluster informer")
@@ -297,9 +320,21 @@ func main() {
 		Metrics: metricsserver.Options{
 			BindAddress: fmt.Sprintf(":%d", metricsPort),
 		},
+		// the webhook server is only started when the webhooks are registered
+		WebhookServer: ctrlwebhook.NewServer(ctrlwebhook.Options{
+			Port:    webhookPort,
+			CertDir: webhookCertDir,
+		}),
 		LeaderElection:          true,
 		LeaderElectionID:        "managedcluster-import-controller.open-cluster-management.io",
 		LeaderElectionNamespace: leaderElectionNamespace,
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				// the import history configmaps, and the configmaps and service used to discover the hub kube
+				// apiserver URL are read directly to avoid caching all of them
+				DisableFor: []client.Object{&corev1.ConfigMap{}, &corev1.Service{}},
+			},
+		},
 	})
 	if err != nil {
 		setupLog.Error(err, "failed to create manager")
@@ -363,6 +398,15 @@ func main() {
 		return
 	}
 
+	if features.DefaultMutableFeatureGate.Enabled(features.ValidatingWebhook) {
+		setupLog.Info("Registering Webhooks")
+		if err := webhook.Add(mgr); err != nil {
+			setupLog.Error(err, "failed to register webhook")
+			exitCode = 1
+			return
+		}
+	}
+
 	controllerConfigInformerF.Start(ctx.Done())
 	importSecertInformerF.Start(ctx.Done())
 	autoimportSecretInformerF.Start(ctx.Done())
@@ -382,7 +426,7 @@ func main() {
 	if features.DefaultMutableFeatureGate.Enabled(features.AgentRegistration) {
 		go func() {
 			if err := agentregistration.RunAgentRegistrationServer(ctx, 9091, clientHolder,
-				klusterletconfigLister); err != nil {
+				klusterletconfigLister, agentRegistrationQPS, agentRegistrationBurst); err != nil {
 				setupLog.Error(err, "failed to start agent registration server")
 			}
 		}()
@@ -0,0 +1,258 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/pflag"
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+)
+
+const (
+	renderCommand = "render"
+
+	// renderNamespace is the namespace of the import controller when the import manifests are rendered offline
+	renderNamespace = "multicluster-engine"
+	// renderPullSecretName is the name of the default image pull secret when the import manifests are rendered
+	// offline
+	renderPullSecretName = "render-pull-secret"
+)
+
+// renderOptions are the inputs to render the import manifests of a managed cluster offline
+type renderOptions struct {
+	clusterName               string
+	mode                      string
+	managedClusterFile        string
+	klusterletConfigFiles     []string
+	hubKubeAPIServer          string
+	caFile                    string
+	tokenFile                 string
+	pullSecretFile            string
+	registrationOperatorImage string
+	registrationImage         string
+	workImage                 string
+	outputDir                 string
+}
+
+// runRender renders the import manifests of a managed cluster with the inputs from the files and flags instead of
+// the kube apiserver, the manifests are written to the output directory or the stdout. It returns the exit code.
+func runRender(args []string, stdout, stderr io.Writer) int {
+	o := &renderOptions{}
+	flags := pflag.NewFlagSet(renderCommand, pflag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&o.clusterName, "cluster-name", "",
+		"the name of the managed cluster, it is required if the managed cluster file is not specified")
+	flags.StringVar(&o.mode, "mode", "",
+		"the install mode of the klusterlet: Default, Singleton, Hosted or SingletonHosted. "+
+			"The default is determined by the annotations of the managed cluster")
+	flags.StringVar(&o.managedClusterFile, "managed-cluster", "",
+		"the YAML file of the ManagedCluster, its annotations and labels are used to render the manifests")
+	flags.StringArrayVar(&o.klusterletConfigFiles, "klusterletconfig", nil,
+		"the YAML file of a KlusterletConfig, it can be repeated and the KlusterletConfigs are merged in order")
+	flags.StringVar(&o.hubKubeAPIServer, "hub-kube-apiserver", "https://api.hub.example.com:6443",
+		"the hub kube apiserver URL in the bootstrap kubeconfig")
+	flags.StringVar(&o.caFile, "ca-file", "", "the file of the CA bundle of the hub kube apiserver")
+	flags.StringVar(&o.tokenFile, "token-file", "", "the file of the bootstrap token, a fake token is used by default")
+	flags.StringVar(&o.pullSecretFile, "pull-secret-file", "",
+		"the .dockerconfigjson file of the image pull secret, an empty pull secret is used by default")
+	flags.StringVar(&o.registrationOperatorImage, "registration-operator-image",
+		os.Getenv(constants.RegistrationOperatorImageEnvVarName), "the image of the registration operator")
+	flags.StringVar(&o.registrationImage, "registration-image",
+		os.Getenv(constants.RegistrationImageEnvVarName), "the image of the registration agent")
+	flags.StringVar(&o.workImage, "work-image", os.Getenv(constants.WorkImageEnvVarName),
+		"the image of the work agent")
+	flags.StringVar(&o.outputDir, "output-dir", "",
+		"the directory to write the import.yaml and crds.yaml, the manifests are written to the stdout by default")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	manifests, crds, err := o.render(context.Background())
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to render the import manifests: %v\n", err)
+		return 1
+	}
+
+	if len(o.outputDir) == 0 {
+		if _, err := stdout.Write(manifests); err != nil {
+			fmt.Fprintf(stderr, "failed to write the import manifests: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if err := os.MkdirAll(o.outputDir, 0o750); err != nil {
+		fmt.Fprintf(stderr, "failed to create the output directory: %v\n", err)
+		return 1
+	}
+	for name, content := range map[string][]byte{"import.yaml": manifests, "crds.yaml": crds} {
+		if err := os.WriteFile(filepath.Join(o.outputDir, name), content, 0o600); err != nil {
+			fmt.Fprintf(stderr, "failed to write %s: %v\n", name, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// render generates the import manifests in the same way as the import secret of the managed cluster, the kube
+// clients are fake clients which only have the image pull secret.
+func (o *renderOptions) render(ctx context.Context) ([]byte, []byte, error) {
+	managedCluster := &clusterv1.ManagedCluster{}
+	if len(o.managedClusterFile) > 0 {
+		if err := readYAMLFile(o.managedClusterFile, managedCluster); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(o.clusterName) > 0 {
+		managedCluster.Name = o.clusterName
+	}
+	if len(managedCluster.Name) == 0 {
+		return nil, nil, fmt.Errorf("the cluster name is required")
+	}
+
+	klusterletConfigs := []*klusterletconfigv1alpha1.KlusterletConfig{}
+	for _, file := range o.klusterletConfigFiles {
+		kc := &klusterletconfigv1alpha1.KlusterletConfig{}
+		if err := readYAMLFile(file, kc); err != nil {
+			return nil, nil, err
+		}
+		klusterletConfigs = append(klusterletConfigs, kc)
+	}
+	klusterletConfig, err := helpers.MergeKlusterletConfigs(klusterletConfigs...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mode := helpers.DetermineKlusterletMode(managedCluster)
+	if len(o.mode) > 0 {
+		mode = operatorv1.InstallMode(o.mode)
+	}
+
+	bootstrapKubeConfig, err := o.bootstrapKubeConfig(klusterletConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the images and the default image pull secret are read from the environment variables by the generation
+	for env, value := range map[string]string{
+		constants.RegistrationOperatorImageEnvVarName: o.registrationOperatorImage,
+		constants.RegistrationImageEnvVarName:         o.registrationImage,
+		constants.WorkImageEnvVarName:                 o.workImage,
+		constants.PodNamespaceEnvVarName:              renderNamespace,
+		constants.DefaultImagePullSecretEnvVarName:    "",
+	} {
+		if err := os.Setenv(env, value); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	objects := []runtime.Object{}
+	if len(o.pullSecretFile) > 0 {
+		dockerConfigJSON, err := os.ReadFile(o.pullSecretFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := os.Setenv(constants.DefaultImagePullSecretEnvVarName, renderPullSecretName); err != nil {
+			return nil, nil, err
+		}
+		pullSecretRefs := []corev1.ObjectReference{{Namespace: renderNamespace, Name: renderPullSecretName}}
+		if klusterletConfig != nil && len(klusterletConfig.Spec.PullSecret.Name) > 0 {
+			// the pull secret file is used for the pull secret referenced by the KlusterletConfig as well
+			pullSecretRefs = append(pullSecretRefs, klusterletConfig.Spec.PullSecret)
+		}
+		for _, ref := range pullSecretRefs {
+			objects = append(objects, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ref.Namespace, Name: ref.Name},
+				Type:       corev1.SecretTypeDockerConfigJson,
+				Data:       map[string][]byte{corev1.DockerConfigJsonKey: dockerConfigJSON},
+			})
+		}
+	}
+
+	kubeClient := kubefake.NewSimpleClientset(objects...)
+	clientHolder := &helpers.ClientHolder{
+		KubeClient:          kubeClient,
+		RuntimeClient:       fake.NewClientBuilder().WithScheme(scheme).Build(),
+		ImageRegistryClient: imageregistry.NewClient(kubeClient),
+	}
+
+	config, err := bootstrap.NewManagedClusterKlusterletManifestsConfig(mode, managedCluster, klusterletConfig,
+		bootstrapKubeConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return config.Generate(ctx, clientHolder)
+}
+
+// bootstrapKubeConfig returns the bootstrap kubeconfig with the CA and the token from the files, the hub kube
+// apiserver URL and the proxy URL of the KlusterletConfig take precedence over the flags.
+func (o *renderOptions) bootstrapKubeConfig(
+	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig) ([]byte, error) {
+	token := []byte("fake-token")
+	if len(o.tokenFile) > 0 {
+		var err error
+		if token, err = os.ReadFile(o.tokenFile); err != nil {
+			return nil, err
+		}
+	}
+	var caData []byte
+	if len(o.caFile) > 0 {
+		var err error
+		if caData, err = os.ReadFile(o.caFile); err != nil {
+			return nil, err
+		}
+	}
+
+	kubeAPIServer, proxyURL := o.hubKubeAPIServer, ""
+	additionalConfigs := []bootstrap.KubeAPIServerConfig{}
+	if klusterletConfig != nil {
+		if config := klusterletConfig.Spec.HubKubeAPIServerConfig; config != nil {
+			if len(config.URL) > 0 {
+				kubeAPIServer = config.URL
+			}
+			proxyURL = config.ProxyURL
+		}
+		urls, err := bootstrap.GetAdditionalKubeAPIServerURLs(klusterletConfig)
+		if err != nil {
+			return nil, err
+		}
+		for _, url := range urls {
+			additionalConfigs = append(additionalConfigs, bootstrap.KubeAPIServerConfig{
+				URL:      url,
+				ProxyURL: proxyURL,
+				CAData:   caData,
+			})
+		}
+	}
+
+	return bootstrap.CreateBootstrapKubeConfig("default-cluster", kubeAPIServer, proxyURL, "", caData, token,
+		additionalConfigs...)
+}
+
+func readYAMLFile(file string, obj interface{}) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, obj); err != nil {
+		return fmt.Errorf("failed to decode %s: %v", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunRender(t *testing.T) {
+	dir := t.TempDir()
+	kcFile := filepath.Join(dir, "klusterletconfig.yaml")
+	if err := os.WriteFile(kcFile, []byte(`apiVersion: config.open-cluster-management.io/v1alpha1
+kind: KlusterletConfig
+metadata:
+  name: test
+spec:
+  registries:
+  - source: quay.io/open-cluster-management
+    mirror: quay.io/mirror
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	pullSecretFile := filepath.Join(dir, "pull-secret.json")
+	if err := os.WriteFile(pullSecretFile, []byte(`{"auths":{"quay.io":{"auth":"dGVzdA=="}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	images := []string{
+		"--registration-operator-image", "quay.io/open-cluster-management/registration-operator:latest",
+		"--registration-image", "quay.io/open-cluster-management/registration:latest",
+		"--work-image", "quay.io/open-cluster-management/work:latest",
+	}
+
+	cases := []struct {
+		name             string
+		args             []string
+		expectedCode     int
+		expectedOutputs  []string
+		expectedErrors   []string
+		expectedFilesDir string
+	}{
+		{
+			name:           "without cluster name",
+			args:           images,
+			expectedCode:   1,
+			expectedErrors: []string{"the cluster name is required"},
+		},
+		{
+			name:           "unsupported mode",
+			args:           append([]string{"--cluster-name", "cluster1", "--mode", "Unknown"}, images...),
+			expectedCode:   1,
+			expectedErrors: []string{"klusterlet deploy mode Unknown not supported"},
+		},
+		{
+			name: "render to stdout",
+			args: append([]string{"--cluster-name", "cluster1", "--klusterletconfig", kcFile,
+				"--pull-secret-file", pullSecretFile}, images...),
+			expectedOutputs: []string{
+				"quay.io/mirror/registration-operator:latest",
+				`clusterName: "cluster1"`,
+				// the base64 encoded pull secret
+				"eyJhdXRocyI6eyJxdWF5LmlvIjp7ImF1dGgiOiJkR1Z6ZEE9PSJ9fX0=",
+			},
+		},
+		{
+			name:             "render to output directory",
+			args:             append([]string{"--cluster-name", "cluster1", "--output-dir", filepath.Join(dir, "out")}, images...),
+			expectedFilesDir: filepath.Join(dir, "out"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			if code := runRender(c.args, stdout, stderr); code != c.expectedCode {
+				t.Fatalf("expected exit code %d, but got %d: %s", c.expectedCode, code, stderr.String())
+			}
+			for _, output := range c.expectedOutputs {
+				if !strings.Contains(stdout.String(), output) {
+					t.Errorf("expected %q in the output", output)
+				}
+			}
+			for _, e := range c.expectedErrors {
+				if !strings.Contains(stderr.String(), e) {
+					t.Errorf("expected %q in the error, but got %s", e, stderr.String())
+				}
+			}
+			if len(c.expectedFilesDir) > 0 {
+				for _, name := range []string{"import.yaml", "crds.yaml"} {
+					content, err := os.ReadFile(filepath.Join(c.expectedFilesDir, name))
+					if err != nil {
+						t.Fatalf("failed to read %s: %v", name, err)
+					}
+					if len(content) == 0 {
+						t.Errorf("%s is empty", name)
+					}
+				}
+			}
+		})
+	}
+}
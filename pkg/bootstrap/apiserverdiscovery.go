@@ -0,0 +1,153 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+// kubeconfigConfigMap is a well-known ConfigMap which contains a kubeconfig of the cluster
+type kubeconfigConfigMap struct {
+	namespace string
+	name      string
+	keys      []string
+}
+
+// kubeconfigConfigMaps are the well-known ConfigMaps which contain the public kube apiserver URL:
+//   - the cluster-info ConfigMap is published by kubeadm based clusters, e.g. kind, for the bootstrap of the nodes
+//   - the kube-proxy ConfigMap contains the kubeconfig of kube-proxy on EKS (kubeconfig) and on kubeadm based
+//     clusters (kubeconfig.conf)
+var kubeconfigConfigMaps = []kubeconfigConfigMap{
+	{namespace: "kube-public", name: "cluster-info", keys: []string{"kubeconfig"}},
+	{namespace: "kube-system", name: "kube-proxy", keys: []string{"kubeconfig", "kubeconfig.conf"}},
+}
+
+// discoverKubeAPIServerAddress discovers the kube apiserver URL of a non-OCP cluster from the well-known
+// kubeconfig ConfigMaps and the LoadBalancer of the kubernetes Service in order. The in-cluster addresses are
+// ignored, e.g. the service names and the loopback addresses in the kubeconfigs, and the endpoints of the kubernetes
+// Service, which are usually not reachable from the managed clusters. An empty URL is returned if it is not found.
+func discoverKubeAPIServerAddress(ctx context.Context, c client.Client) (string, error) {
+	for _, cm := range kubeconfigConfigMaps {
+		server, err := getServerFromKubeconfigConfigMap(ctx, c, cm)
+		if err != nil {
+			return "", err
+		}
+		if len(server) > 0 {
+			klog.V(4).Infof("Discovered the kube apiserver URL %s from the ConfigMap %s/%s", server, cm.namespace, cm.name)
+			return server, nil
+		}
+	}
+
+	server, err := getServerFromKubernetesService(ctx, c)
+	if err != nil {
+		return "", err
+	}
+	if len(server) > 0 {
+		klog.V(4).Infof("Discovered the kube apiserver URL %s from the kubernetes Service", server)
+		return server, nil
+	}
+
+	return "", nil
+}
+
+func getServerFromKubeconfigConfigMap(ctx context.Context, c client.Client, cm kubeconfigConfigMap) (string, error) {
+	configMap := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: cm.namespace, Name: cm.name}, configMap)
+	if helpers.ResourceIsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, key := range cm.keys {
+		data, ok := configMap.Data[key]
+		if !ok {
+			continue
+		}
+
+		config, err := clientcmd.Load([]byte(data))
+		if err != nil {
+			klog.Warningf("failed to load the kubeconfig %s in the ConfigMap %s/%s: %v", key, cm.namespace, cm.name, err)
+			continue
+		}
+
+		// use the server of the current context, or the only cluster in the kubeconfig
+		var server string
+		if kubeContext, ok := config.Contexts[config.CurrentContext]; ok {
+			if cluster, ok := config.Clusters[kubeContext.Cluster]; ok {
+				server = cluster.Server
+			}
+		} else if len(config.Clusters) == 1 {
+			for _, cluster := range config.Clusters {
+				server = cluster.Server
+			}
+		}
+		if isPublicServerURL(server) {
+			return server, nil
+		}
+	}
+	return "", nil
+}
+
+// getServerFromKubernetesService returns the address of the LoadBalancer of the kubernetes Service if the Service
+// is exposed with a LoadBalancer
+func getServerFromKubernetesService(ctx context.Context, c client.Client) (string, error) {
+	svc := &corev1.Service{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "kubernetes"}, svc)
+	if helpers.ResourceIsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if svc.Spec.Type != corev1.ServiceTypeLoadBalancer || len(svc.Spec.Ports) == 0 {
+		return "", nil
+	}
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		host := ingress.Hostname
+		if len(host) == 0 {
+			host = ingress.IP
+		}
+		if len(host) > 0 {
+			return serverURL(host, svc.Spec.Ports[0].Port), nil
+		}
+	}
+	return "", nil
+}
+
+func serverURL(host string, port int32) string {
+	return fmt.Sprintf("https://%s", net.JoinHostPort(host, strconv.Itoa(int(port))))
+}
+
+// isPublicServerURL checks if the server URL can be accessed from outside of the cluster
+func isPublicServerURL(server string) bool {
+	u, err := url.Parse(server)
+	if err != nil || len(u.Hostname()) == 0 {
+		return false
+	}
+
+	host := u.Hostname()
+	if host == "kubernetes" || strings.HasPrefix(host, "kubernetes.default") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+func newKubeconfigData(server string) string {
+	return `apiVersion: v1
+kind: Config
+clusters:
+- name: ""
+  cluster:
+    server: ` + server + `
+contexts: []
+current-context: ""
+`
+}
+
+func TestDiscoverKubeAPIServerAddress(t *testing.T) {
+	kubernetesService := func(serviceType corev1.ServiceType, ingress ...corev1.LoadBalancerIngress) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kubernetes"},
+			Spec: corev1.ServiceSpec{
+				Type:  serviceType,
+				Ports: []corev1.ServicePort{{Name: "https", Port: 443}},
+			},
+			Status: corev1.ServiceStatus{
+				LoadBalancer: corev1.LoadBalancerStatus{Ingress: ingress},
+			},
+		}
+	}
+	endpointSlice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "kubernetes",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "kubernetes"},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Ports:       []discoveryv1.EndpointPort{{Name: ptr.To("https"), Port: ptr.To(int32(6443))}},
+		Endpoints: []discoveryv1.Endpoint{
+			{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(false)}},
+			{Addresses: []string{"10.0.0.2"}, Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(true)}},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		objects  []client.Object
+		expected string
+	}{
+		{
+			name: "nothing discovered",
+		},
+		{
+			name: "from cluster-info",
+			objects: []client.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "kube-public", Name: "cluster-info"},
+					Data:       map[string]string{"kubeconfig": newKubeconfigData("https://kind-control-plane:6443")},
+				},
+				kubernetesService(corev1.ServiceTypeClusterIP),
+				endpointSlice,
+			},
+			expected: "https://kind-control-plane:6443",
+		},
+		{
+			name: "from kube-proxy",
+			objects: []client.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "kube-public", Name: "cluster-info"},
+					Data:       map[string]string{"kubeconfig": newKubeconfigData("https://127.0.0.1:6443")},
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "kube-proxy"},
+					Data: map[string]string{
+						"kubeconfig": newKubeconfigData("https://abc.gr7.us-east-1.eks.amazonaws.com"),
+					},
+				},
+			},
+			expected: "https://abc.gr7.us-east-1.eks.amazonaws.com",
+		},
+		{
+			name: "from the load balancer of the kubernetes service",
+			objects: []client.Object{
+				kubernetesService(corev1.ServiceTypeLoadBalancer, corev1.LoadBalancerIngress{Hostname: "api.example.com"}),
+				endpointSlice,
+			},
+			expected: "https://api.example.com:443",
+		},
+		{
+			name: "the endpoints of the kubernetes service are ignored",
+			objects: []client.Object{
+				kubernetesService(corev1.ServiceTypeClusterIP),
+				endpointSlice,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server, err := discoverKubeAPIServerAddress(context.Background(),
+				fake.NewClientBuilder().WithScheme(testscheme).WithObjects(c.objects...).Build())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if server != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, server)
+			}
+		})
+	}
+}
+
+func TestNonOCPKubeAPIServerAddressAndClusterName(t *testing.T) {
+	deployOnOCP := helpers.DeployOnOCP
+	helpers.DeployOnOCP = false
+	defer func() { helpers.DeployOnOCP = deployOnOCP }()
+
+	runtimeClient := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: types.UID("kube-system-uid")}},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-public", Name: "cluster-info"},
+			Data:       map[string]string{"kubeconfig": newKubeconfigData("https://kind-control-plane:6443")},
+		},
+	).Build()
+
+	server, err := GetKubeAPIServerAddress(context.Background(), runtimeClient, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if server != "https://kind-control-plane:6443" {
+		t.Errorf("unexpected kube apiserver address %q", server)
+	}
+
+	clusterName, err := GetKubeconfigClusterName(context.Background(), runtimeClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if clusterName != "kube-system-uid" {
+		t.Errorf("unexpected cluster name %q", clusterName)
+	}
+
+	// the URL must be specified with a KlusterletConfig if it cannot be discovered
+	_, err = GetKubeAPIServerAddress(context.Background(), fake.NewClientBuilder().WithScheme(testscheme).Build(), nil)
+	if err == nil || !strings.Contains(err.Error(), "please use klusterletConfig") {
+		t.Errorf("expected the error to set the URL with a klusterletConfig, but got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	klusterletchart "open-cluster-management.io/ocm/deploy/klusterlet/chart"
+	"open-cluster-management.io/ocm/pkg/operator/helpers/chart"
+	"sigs.k8s.io/yaml"
+)
+
+// ArchiveFile is a file in an archive
+type ArchiveFile struct {
+	Name    string
+	Content []byte
+}
+
+// TarGzArchive returns the gzipped tar archive of the files
+func TarGzArchive(files []ArchiveFile) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	modTime := time.Now()
+	for _, file := range files {
+		if err := tarWriter.WriteHeader(&tar.Header{
+			Name:    file.Name,
+			Mode:    0600,
+			Size:    int64(len(file.Content)),
+			ModTime: modTime,
+		}); err != nil {
+			return nil, err
+		}
+		if _, err := tarWriter.Write(file.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ZipArchive returns the zip archive of the files
+func ZipArchive(files []ArchiveFile) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buf)
+
+	for _, file := range files {
+		writer, err := zipWriter.Create(file.Name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(file.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GenerateHelmChart returns the klusterlet helm chart packaged in a gzipped tar. The values.yaml of the chart is
+// pre-filled with the rendered chart config, so installing the chart without any values gets the same resources
+// as the manifests returned by Generate.
+func (c *KlusterletManifestsConfig) GenerateHelmChart(ctx context.Context,
+	clientHolder *helpers.ClientHolder) ([]byte, error) {
+	// render the manifests to fill the chart config
+	if _, _, err := c.Generate(ctx, clientHolder); err != nil {
+		return nil, err
+	}
+
+	values, err := chart.JsonStructToValues(c.chartConfig)
+	if err != nil {
+		return nil, err
+	}
+	valuesYAML, err := values.YAML()
+	if err != nil {
+		return nil, err
+	}
+
+	files := []ArchiveFile{}
+	err = fs.WalkDir(klusterletchart.ChartFiles, klusterletchart.ChartName, func(filePath string, d fs.DirEntry,
+		err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		content, err := fs.ReadFile(klusterletchart.ChartFiles, filePath)
+		if err != nil {
+			return err
+		}
+		if filePath == path.Join(klusterletchart.ChartName, "values.yaml") {
+			content = []byte(valuesYAML)
+		}
+		files = append(files, ArchiveFile{Name: filePath, Content: content})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.chartConfig.NoOperator {
+		// the additional cluster roles are not a part of the klusterlet chart, add them as rendered templates
+		for _, file := range additionalClusterRoleFiles {
+			content, err := filesToTemplateBytes([]string{file}, c.chartConfig)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, ArchiveFile{
+				Name:    path.Join(klusterletchart.ChartName, "templates", path.Base(file)),
+				Content: []byte(strings.TrimPrefix(string(content), "\n")),
+			})
+		}
+	}
+
+	return TarGzArchive(files)
+}
+
+// kustomization is the kustomization.yaml of the kustomize base
+type kustomization struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Resources  []string `json:"resources"`
+}
+
+// GenerateKustomizeBase returns a kustomize base directory of the rendered klusterlet manifests in a gzipped tar,
+// the directory is named with the cluster name.
+func (c *KlusterletManifestsConfig) GenerateKustomizeBase(ctx context.Context,
+	clientHolder *helpers.ClientHolder) ([]byte, error) {
+	manifests, crds, err := c.Generate(ctx, clientHolder)
+	if err != nil {
+		return nil, err
+	}
+
+	base := c.chartConfig.Klusterlet.ClusterName
+	k := kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+	}
+	files := []ArchiveFile{}
+	if len(crds) > 0 {
+		// list the CRDs first, so they are applied before the klusterlet
+		k.Resources = append(k.Resources, "crds.yaml")
+		files = append(files, ArchiveFile{Name: path.Join(base, "crds.yaml"), Content: crds})
+	}
+	k.Resources = append(k.Resources, "klusterlet.yaml")
+	files = append(files, ArchiveFile{Name: path.Join(base, "klusterlet.yaml"), Content: manifests})
+
+	kustomizationYAML, err := yaml.Marshal(k)
+	if err != nil {
+		return nil, err
+	}
+	files = append([]ArchiveFile{{Name: path.Join(base, "kustomization.yaml"), Content: kustomizationYAML}}, files...)
+
+	return TarGzArchive(files)
+}
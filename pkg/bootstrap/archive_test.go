@@ -0,0 +1,130 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+)
+
+func newArchiveTestClientHolder() *helpers.ClientHolder {
+	kubeClient := kubefake.NewSimpleClientset()
+	return &helpers.ClientHolder{
+		KubeClient:          kubeClient,
+		RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).Build(),
+		ImageRegistryClient: imageregistry.NewClient(kubeClient),
+	}
+}
+
+func untarGz(t *testing.T, data []byte) map[string]string {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to read gzip: %v", err)
+	}
+
+	files := map[string]string{}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("failed to read tar: %v", err)
+		}
+		content, err := io.ReadAll(tarReader)
+		if err != nil {
+			t.Fatalf("failed to read tar: %v", err)
+		}
+		files[header.Name] = string(content)
+	}
+}
+
+func TestGenerateHelmChart(t *testing.T) {
+	chartBytes, err := NewKlusterletManifestsConfig(
+		operatorv1.InstallModeDefault,
+		"test",
+		[]byte("bootstrap kubeconfig"),
+	).WithoutImagePullSecretGenerate().GenerateHelmChart(context.Background(), newArchiveTestClientHolder())
+	if err != nil {
+		t.Fatalf("failed to generate helm chart: %v", err)
+	}
+
+	files := untarGz(t, chartBytes)
+	for _, name := range []string{"klusterlet/Chart.yaml", "klusterlet/values.yaml",
+		"klusterlet/templates/clusterrole_bootstrap.yaml", "klusterlet/templates/clusterrole_aggregate.yaml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected file %s in the chart", name)
+		}
+	}
+
+	klusterletChart, err := loader.LoadArchive(bytes.NewReader(chartBytes))
+	if err != nil {
+		t.Fatalf("failed to load helm chart: %v", err)
+	}
+	klusterlet, ok := klusterletChart.Values["klusterlet"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected klusterlet values, but got %v", klusterletChart.Values)
+	}
+	if klusterlet["clusterName"] != "test" {
+		t.Errorf("expected the cluster name test in the values, but got %v", klusterlet["clusterName"])
+	}
+	if klusterletChart.Values["bootstrapHubKubeConfig"] != "bootstrap kubeconfig" {
+		t.Errorf("expected the bootstrap kubeconfig in the values, but got %v",
+			klusterletChart.Values["bootstrapHubKubeConfig"])
+	}
+}
+
+func TestGenerateKustomizeBase(t *testing.T) {
+	clientHolder := newArchiveTestClientHolder()
+	config := NewKlusterletManifestsConfig(
+		operatorv1.InstallModeDefault,
+		"test",
+		[]byte("bootstrap kubeconfig"),
+	).WithoutImagePullSecretGenerate()
+	manifests, crds, err := NewKlusterletManifestsConfig(
+		operatorv1.InstallModeDefault,
+		"test",
+		[]byte("bootstrap kubeconfig"),
+	).WithoutImagePullSecretGenerate().Generate(context.Background(), clientHolder)
+	if err != nil {
+		t.Fatalf("failed to generate manifests: %v", err)
+	}
+
+	base, err := config.GenerateKustomizeBase(context.Background(), clientHolder)
+	if err != nil {
+		t.Fatalf("failed to generate kustomize base: %v", err)
+	}
+
+	files := untarGz(t, base)
+	expected := map[string]string{
+		"test/crds.yaml":       string(crds),
+		"test/klusterlet.yaml": string(manifests),
+	}
+	for name, content := range expected {
+		if files[name] != content {
+			t.Errorf("expected the content of %s is the same as the rendered manifests", name)
+		}
+	}
+	if !strings.Contains(files["test/kustomization.yaml"], "- crds.yaml\n- klusterlet.yaml\n") {
+		t.Errorf("expected the resources in the kustomization, but got %s", files["test/kustomization.yaml"])
+	}
+	if len(files) != 3 {
+		t.Errorf("expected 3 files, but got %v", reflect.ValueOf(files).MapKeys())
+	}
+}
@@ -41,36 +41,37 @@ const (
 	apiServerInternalEndpointCA = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
 )
 
-// create kubeconfig for bootstrap
+// create kubeconfig for bootstrap, the current context uses the kube apiserver, and each of the additional kube
+// apiservers has its own cluster and context in order.
 func CreateBootstrapKubeConfig(ctxClusterName string,
-	kubeAPIServer, proxyURL, ca string, caData, token []byte) ([]byte, error) {
-
-	// CA file and CA data cannot be set simultaneously
-	if len(caData) > 0 {
-		ca = ""
-	}
+	kubeAPIServer, proxyURL, ca string, caData, token []byte,
+	additionalConfigs ...KubeAPIServerConfig) ([]byte, error) {
 
 	bootstrapConfig := clientcmdapi.Config{
 		// Define a cluster stanza based on the bootstrap kubeconfig.
 		Clusters: map[string]*clientcmdapi.Cluster{
-			ctxClusterName: {
-				Server:                   kubeAPIServer,
-				InsecureSkipTLSVerify:    false,
-				CertificateAuthority:     ca,
-				CertificateAuthorityData: caData,
-				ProxyURL:                 proxyURL,
-			}},
+			ctxClusterName: newBootstrapCluster(kubeAPIServer, proxyURL, ca, caData)},
 		// Define auth based on the obtained client cert.
-		AuthInfos: map[string]*clientcmdapi.AuthInfo{"default-auth": {
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{defaultAuthInfoName: {
 			Token: string(token),
 		}},
 		// Define a context that connects the auth info and cluster, and set it as the default
-		Contexts: map[string]*clientcmdapi.Context{"default-context": {
+		Contexts: map[string]*clientcmdapi.Context{defaultContextName: {
 			Cluster:   ctxClusterName,
-			AuthInfo:  "default-auth",
+			AuthInfo:  defaultAuthInfoName,
 			Namespace: "default",
 		}},
-		CurrentContext: "default-context",
+		CurrentContext: defaultContextName,
+	}
+
+	for i, config := range additionalConfigs {
+		clusterName := fmt.Sprintf("%s-%d", ctxClusterName, i+1)
+		bootstrapConfig.Clusters[clusterName] = newBootstrapCluster(config.URL, config.ProxyURL, config.CA, config.CAData)
+		bootstrapConfig.Contexts[additionalContextName(i+1)] = &clientcmdapi.Context{
+			Cluster:   clusterName,
+			AuthInfo:  defaultAuthInfoName,
+			Namespace: "default",
+		}
 	}
 
 	boostrapConfigData, err := runtime.Encode(clientcmdlatest.Codec, &bootstrapConfig)
@@ -81,6 +82,21 @@ func CreateBootstrapKubeConfig(ctxClusterName string,
 	return boostrapConfigData, err
 }
 
+func newBootstrapCluster(kubeAPIServer, proxyURL, ca string, caData []byte) *clientcmdapi.Cluster {
+	// CA file and CA data cannot be set simultaneously
+	if len(caData) > 0 {
+		ca = ""
+	}
+
+	return &clientcmdapi.Cluster{
+		Server:                   kubeAPIServer,
+		InsecureSkipTLSVerify:    false,
+		CertificateAuthority:     ca,
+		CertificateAuthorityData: caData,
+		ProxyURL:                 proxyURL,
+	}
+}
+
 // GetKubeAPIServerConfig returns the expected apiserver url, proxy url, ca file and ca data
 // for cluster registration.
 func GetKubeAPIServerConfig(ctx context.Context, clientHolder *helpers.ClientHolder, ns string,
@@ -224,47 +240,60 @@ func GetKubeAPIServerAddress(ctx context.Context, client client.Client,
 		return klusterletConfig.Spec.HubKubeAPIServerURL, nil
 	}
 
-	if !helpers.DeployOnOCP {
-		return "", fmt.Errorf("failed get Hub kube apiserver on non-OCP cluster")
+	if helpers.DeployOnOCP {
+		infraConfig := &ocinfrav1.Infrastructure{}
+		err := client.Get(ctx, types.NamespacedName{Name: "cluster"}, infraConfig)
+		if err == nil {
+			return infraConfig.Status.APIServerURL, nil
+		}
+		if !helpers.ResourceIsNotFound(err) {
+			return "", err
+		}
 	}
 
-	infraConfig := &ocinfrav1.Infrastructure{}
-	err := client.Get(ctx, types.NamespacedName{Name: "cluster"}, infraConfig)
-	if err == nil {
-		return infraConfig.Status.APIServerURL, nil
+	// the Infrastructure is not available on non-OCP cluster, discover the URL from the cluster
+	url, err := discoverKubeAPIServerAddress(ctx, client)
+	if err != nil {
+		return "", err
 	}
-	if helpers.ResourceIsNotFound(err) {
-		return "", fmt.Errorf("cannot get kubeAPIServer URL since the Infrastructure is not found, please use" +
-			"klusterletConfig to set the hub kubeAPIServer URL")
+	if len(url) == 0 {
+		return "", fmt.Errorf("cannot get kubeAPIServer URL since it cannot be discovered from the cluster, please " +
+			"use klusterletConfig to set the hub kubeAPIServer URL")
 	}
-
-	return "", err
+	return url, nil
 }
 
 // GetKubeconfigClusterName returns the cluster name used in the bootstrap kubeconfig current context.
 // This is to fix the issue that when the hub cluster is rebuilt, and we backup restore the resources
 // on the same hub cluster, even the bootstrp kubeconfig is generated by the new hub cluster, it will not
 // trigger the agent to rebootstrap. Using a different cluster name will make the agent to rebootstrap.
-// Currently, the above issue is only fixed on OCP as we get the cluster name from the infrastructure.
-// TODO: On non-OCP, we use "default-cluster" as the cluster name now and need to fix it in the future.
+// On OCP, the cluster name is the UID of the infrastructure, otherwise it is the UID of the kube-system
+// namespace, both are unique and stable for a cluster.
 func GetKubeconfigClusterName(ctx context.Context, client client.Client) (string, error) {
-	defaultCluster := "default-cluster"
-	if !helpers.DeployOnOCP {
-		klog.Infof("Deploying on non-OCP cluster, using %s as the cluster name", defaultCluster)
-		return defaultCluster, nil
+	if helpers.DeployOnOCP {
+		infraConfig := &ocinfrav1.Infrastructure{}
+		err := client.Get(ctx, types.NamespacedName{Name: "cluster"}, infraConfig)
+		if err == nil {
+			return string(infraConfig.UID), nil
+		}
+		if !helpers.ResourceIsNotFound(err) {
+			return "", err
+		}
 	}
 
-	infraConfig := &ocinfrav1.Infrastructure{}
-	err := client.Get(ctx, types.NamespacedName{Name: "cluster"}, infraConfig)
-	if err == nil {
-		return string(infraConfig.UID), nil
+	ns := &corev1.Namespace{}
+	err := client.Get(ctx, types.NamespacedName{Name: "kube-system"}, ns)
+	if err == nil && len(ns.UID) > 0 {
+		return string(ns.UID), nil
 	}
-	if helpers.ResourceIsNotFound(err) {
-		klog.Infof("Infrastructure is not found, using %s as the cluster name", defaultCluster)
-		return defaultCluster, nil
+	if err != nil && !helpers.ResourceIsNotFound(err) {
+		return "", err
 	}
 
-	return "", err
+	defaultCluster := "default-cluster"
+	klog.Infof("Neither the Infrastructure nor the kube-system namespace is found, using %s as the cluster name",
+		defaultCluster)
+	return defaultCluster, nil
 }
 
 func GetBootstrapCAData(ctx context.Context, clientHolder *helpers.ClientHolder, kubeAPIServer string,
@@ -597,10 +626,11 @@ func mergeCertificateData(caBundles ...[]byte) ([]byte, error) {
 //   - the CA data
 //   - the proxy url
 //   - the context cluster name
+//   - the additional kube apiservers
 func ValidateBootstrapKubeconfig(clusterName string,
 	kubeAPIServer, proxyURL, ca string, caData []byte, ctxClusterName string,
 	requiredKubeAPIServer, requiredProxyURL, requiredCA string, requiredCAData []byte,
-	requiredCtxClusterName string) bool {
+	requiredCtxClusterName string, additionalConfigs, requiredAdditionalConfigs []KubeAPIServerConfig) bool {
 	// validate kube api server endpoint
 	if kubeAPIServer != requiredKubeAPIServer {
 		klog.Infof("KubeAPIServer invalid for the managed cluster %s: %s", clusterName, kubeAPIServer)
@@ -631,5 +661,11 @@ func ValidateBootstrapKubeconfig(clusterName string,
 		return false
 	}
 
+	// validate the additional kube api servers
+	if !equalKubeAPIServerConfigs(additionalConfigs, requiredAdditionalConfigs) {
+		klog.Infof("Additional KubeAPIServers are invalid for the managed cluster %s", clusterName)
+		return false
+	}
+
 	return true
 }
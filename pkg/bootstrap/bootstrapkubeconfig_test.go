@@ -1317,6 +1317,9 @@ func TestValidateBootstrapKubeconfig(t *testing.T) {
 		requiredCAData         []byte
 		requiredCtxClusterName string
 
+		additionalConfigs         []KubeAPIServerConfig
+		requiredAdditionalConfigs []KubeAPIServerConfig
+
 		valid bool
 	}{
 		{
@@ -1365,6 +1368,50 @@ func TestValidateBootstrapKubeconfig(t *testing.T) {
 			ca:         "/etc/ca.crt",
 			requiredCA: "/etc/new-ca.crt",
 		},
+		{
+			name: "additional kube apiserver added",
+			requiredAdditionalConfigs: []KubeAPIServerConfig{
+				{URL: "https://api-2.my-cluster.example.com:6443", CAData: certData1},
+			},
+		},
+		{
+			name: "additional kube apiserver changed",
+			additionalConfigs: []KubeAPIServerConfig{
+				{URL: "https://api-2.my-cluster.example.com:6443", CAData: certData1},
+			},
+			requiredAdditionalConfigs: []KubeAPIServerConfig{
+				{URL: "https://api-3.my-cluster.example.com:6443", CAData: certData1},
+			},
+		},
+		{
+			name: "additional kube apiserver ca data changed",
+			additionalConfigs: []KubeAPIServerConfig{
+				{URL: "https://api-2.my-cluster.example.com:6443", CAData: certData1},
+			},
+			requiredAdditionalConfigs: []KubeAPIServerConfig{
+				{URL: "https://api-2.my-cluster.example.com:6443", CAData: certData2},
+			},
+		},
+		{
+			name: "additional kube apiserver removed",
+			additionalConfigs: []KubeAPIServerConfig{
+				{URL: "https://api-2.my-cluster.example.com:6443", CAData: certData1},
+			},
+		},
+		{
+			name:                   "all valid with additional kube apiservers",
+			kubeAPIServer:          "https://api.my-cluster.example.com:6443",
+			requiredKubeAPIServer:  "https://api.my-cluster.example.com:6443",
+			ctxClusterName:         "my-cluster",
+			requiredCtxClusterName: "my-cluster",
+			additionalConfigs: []KubeAPIServerConfig{
+				{URL: "https://api-2.my-cluster.example.com:6443", CAData: certData1},
+			},
+			requiredAdditionalConfigs: []KubeAPIServerConfig{
+				{URL: "https://api-2.my-cluster.example.com:6443", CAData: certData1},
+			},
+			valid: true,
+		},
 		{
 			name:                   "all valid",
 			kubeAPIServer:          "https://api.my-cluster.example.com:6443",
@@ -1381,7 +1428,8 @@ func TestValidateBootstrapKubeconfig(t *testing.T) {
 		t.Run(c.name, func(t *testing.T) {
 			t.Logf("Test name: %s", c.name)
 			valid := ValidateBootstrapKubeconfig("cluster1", c.kubeAPIServer, c.proxyURL, c.ca, c.caData, c.ctxClusterName,
-				c.requiredKubeAPIServer, c.requiredProxyURL, c.requiredCA, c.requiredCAData, c.requiredCtxClusterName)
+				c.requiredKubeAPIServer, c.requiredProxyURL, c.requiredCA, c.requiredCAData, c.requiredCtxClusterName,
+				c.additionalConfigs, c.requiredAdditionalConfigs)
 			if valid != c.valid {
 				t.Errorf("expected %v, but got %v", c.valid, valid)
 			}
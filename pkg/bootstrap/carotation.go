@@ -0,0 +1,104 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/klog/v2"
+)
+
+// RotateCAData returns the CA data of the bootstrap kubeconfig in the CA rotation mode. If the CA data of the
+// existing bootstrap kubeconfig has the certificates which are removed from the required CA data, the unexpired ones
+// are kept after the required CA data until the overlap period since the rotation is started passes. It also returns
+// when the rotation is started in RFC3339, it is empty if there is no rotation in progress.
+func RotateCAData(caData, requiredCAData []byte, rotationStartedAt string, overlap time.Duration,
+	now time.Time) ([]byte, string, error) {
+	if overlap <= 0 || len(caData) == 0 || len(requiredCAData) == 0 {
+		return requiredCAData, "", nil
+	}
+
+	certs, err := certutil.ParseCertsPEM(caData)
+	if err != nil {
+		// the existing CA data is invalid, it is not kept
+		klog.Infof("failed to parse the CA data of the bootstrap kubeconfig: %v", err)
+		return requiredCAData, "", nil
+	}
+	requiredCerts, err := certutil.ParseCertsPEM(requiredCAData)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var retiring []*x509.Certificate
+	for _, cert := range certs {
+		if now.After(cert.NotAfter) || containsCertificate(requiredCerts, cert) {
+			continue
+		}
+		retiring = append(retiring, cert)
+	}
+	if len(retiring) == 0 {
+		return requiredCAData, "", nil
+	}
+
+	startedAt, err := time.Parse(time.RFC3339, rotationStartedAt)
+	if err != nil {
+		startedAt = now
+	}
+	if !now.Before(startedAt.Add(overlap)) {
+		// the overlap period passes, the removed certificates are dropped
+		return requiredCAData, "", nil
+	}
+
+	retiringData := bytes.Buffer{}
+	for _, cert := range retiring {
+		if err := pem.Encode(&retiringData, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}); err != nil {
+			return nil, "", err
+		}
+	}
+	rotatedCAData, err := mergeCertificateData(requiredCAData, retiringData.Bytes())
+	if err != nil {
+		return nil, "", err
+	}
+	return rotatedCAData, startedAt.UTC().Format(time.RFC3339), nil
+}
+
+func containsCertificate(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range certs {
+		if bytes.Equal(c.Raw, cert.Raw) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBootstrapCAExpiry returns when all of the certificates in the CA bundles of the bootstrap kubeconfig expire,
+// it is the latest expiry of the certificates. A zero time is returned if the bootstrap kubeconfig has no CA data.
+func GetBootstrapCAExpiry(kubeconfigData []byte) (time.Time, error) {
+	config, err := clientcmd.Load(kubeconfigData)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var expiry time.Time
+	for _, cluster := range config.Clusters {
+		if len(cluster.CertificateAuthorityData) == 0 {
+			continue
+		}
+		certs, err := certutil.ParseCertsPEM(cluster.CertificateAuthorityData)
+		if err != nil {
+			return time.Time{}, err
+		}
+		for _, cert := range certs {
+			if cert.NotAfter.After(expiry) {
+				expiry = cert.NotAfter
+			}
+		}
+	}
+	return expiry, nil
+}
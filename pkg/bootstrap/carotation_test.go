@@ -0,0 +1,158 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"testing"
+	"time"
+
+	certutil "k8s.io/client-go/util/cert"
+
+	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
+)
+
+func TestRotateCAData(t *testing.T) {
+	oldCA, _, err := testinghelpers.NewRootCA("old-ca")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	newCA, _, err := testinghelpers.NewRootCA("new-ca")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	cases := []struct {
+		name              string
+		caData            []byte
+		requiredCAData    []byte
+		rotationStartedAt string
+		overlap           time.Duration
+		now               time.Time
+		expectedCerts     int
+		expectedStarted   bool
+	}{
+		{
+			name:           "rotation disabled",
+			caData:         oldCA,
+			requiredCAData: newCA,
+			now:            now,
+			expectedCerts:  1,
+		},
+		{
+			name:           "ca is not changed",
+			caData:         newCA,
+			requiredCAData: newCA,
+			overlap:        time.Hour,
+			now:            now,
+			expectedCerts:  1,
+		},
+		{
+			name:            "rotation is started",
+			caData:          oldCA,
+			requiredCAData:  newCA,
+			overlap:         time.Hour,
+			now:             now,
+			expectedCerts:   2,
+			expectedStarted: true,
+		},
+		{
+			name:              "within the overlap period",
+			caData:            append(append([]byte{}, newCA...), oldCA...),
+			requiredCAData:    newCA,
+			rotationStartedAt: now.Add(-30 * time.Minute).UTC().Format(time.RFC3339),
+			overlap:           time.Hour,
+			now:               now,
+			expectedCerts:     2,
+			expectedStarted:   true,
+		},
+		{
+			name:              "overlap period passes",
+			caData:            append(append([]byte{}, newCA...), oldCA...),
+			requiredCAData:    newCA,
+			rotationStartedAt: now.Add(-2 * time.Hour).UTC().Format(time.RFC3339),
+			overlap:           time.Hour,
+			now:               now,
+			expectedCerts:     1,
+		},
+		{
+			name:           "old ca expires",
+			caData:         oldCA,
+			requiredCAData: newCA,
+			overlap:        time.Hour,
+			now:            now.AddDate(2, 0, 0),
+			expectedCerts:  1,
+		},
+		{
+			name:           "invalid existing ca",
+			caData:         []byte("invalid"),
+			requiredCAData: newCA,
+			overlap:        time.Hour,
+			now:            now,
+			expectedCerts:  1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			caData, startedAt, err := RotateCAData(c.caData, c.requiredCAData, c.rotationStartedAt, c.overlap, c.now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			certs, err := certutil.ParseCertsPEM(caData)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(certs) != c.expectedCerts {
+				t.Errorf("expected %d certificates, but got %d", c.expectedCerts, len(certs))
+			}
+			if certs[0].Subject.CommonName != "new-ca" {
+				t.Errorf("expected the required ca is the first one, but got %s", certs[0].Subject.CommonName)
+			}
+			if (len(startedAt) > 0) != c.expectedStarted {
+				t.Errorf("expected rotation started %v, but got %q", c.expectedStarted, startedAt)
+			}
+			if len(c.rotationStartedAt) > 0 && c.expectedStarted && startedAt != c.rotationStartedAt {
+				t.Errorf("expected rotation started at %s, but got %s", c.rotationStartedAt, startedAt)
+			}
+		})
+	}
+}
+
+func TestGetBootstrapCAExpiry(t *testing.T) {
+	ca, _, err := testinghelpers.NewRootCA("ca")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	certs, err := certutil.ParseCertsPEM(ca)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	kubeconfigData, err := CreateBootstrapKubeConfig("hub", "https://api.example.com:6443", "", "",
+		ca, []byte("token"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expiry, err := GetBootstrapCAExpiry(kubeconfigData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !expiry.Equal(certs[0].NotAfter) {
+		t.Errorf("expected expiry %v, but got %v", certs[0].NotAfter, expiry)
+	}
+
+	insecureKubeconfigData, err := CreateBootstrapKubeConfig("hub", "https://api.example.com:6443", "", "",
+		nil, []byte("token"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expiry, err = GetBootstrapCAExpiry(insecureKubeconfigData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !expiry.IsZero() {
+		t.Errorf("expected zero expiry, but got %v", expiry)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"open-cluster-management.io/ocm/pkg/operator/helpers/chart"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+)
+
+// The types of the sources of the effective configurations of the klusterlet manifests
+const (
+	// ValueSourceKlusterletConfig means the value is from a field of the merged KlusterletConfig spec
+	ValueSourceKlusterletConfig = "KlusterletConfig"
+	// ValueSourceManagedClusterAnnotation means the value is from an annotation of the managed cluster
+	ValueSourceManagedClusterAnnotation = "ManagedClusterAnnotation"
+	// ValueSourceInstallMode means the value is the default of the install mode of the klusterlet
+	ValueSourceInstallMode = "InstallMode"
+	// ValueSourceDefault means the value is the default of the import controller
+	ValueSourceDefault = "Default"
+)
+
+// ValueSource describes where an effective configuration of the klusterlet manifests comes from.
+type ValueSource struct {
+	// Type is the type of the source, e.g. KlusterletConfig, ManagedClusterAnnotation, InstallMode or Default
+	Type string `json:"type"`
+	// Key is the json field of the KlusterletConfig spec or the annotation key of the KlusterletConfig, the
+	// annotation key of the managed cluster, or the install mode
+	Key string `json:"key,omitempty"`
+	// KlusterletConfigs are the names of the KlusterletConfigs which the field of the merged KlusterletConfig spec
+	// is from, it is only set by the callers which know the KlusterletConfigs before merging.
+	KlusterletConfigs []string `json:"klusterletConfigs,omitempty"`
+}
+
+func klusterletConfigSource(field string) ValueSource {
+	return ValueSource{Type: ValueSourceKlusterletConfig, Key: field}
+}
+
+func managedClusterAnnotationSource(key string) ValueSource {
+	return ValueSource{Type: ValueSourceManagedClusterAnnotation, Key: key}
+}
+
+func defaultSource() ValueSource {
+	return ValueSource{Type: ValueSourceDefault}
+}
+
+// annotationOrDefaultSource returns the annotation source if the managed cluster has the annotation, otherwise the
+// default source
+func annotationOrDefaultSource(annotations map[string]string, key string) ValueSource {
+	if _, ok := annotations[key]; ok {
+		return managedClusterAnnotationSource(key)
+	}
+	return defaultSource()
+}
+
+// registriesSource returns the source of the images, the registries of the KlusterletConfig take precedence over
+// the image registries annotation of the managed cluster.
+func registriesSource(kcRegistries []klusterletconfigv1alpha1.Registries, annotations map[string]string) ValueSource {
+	if len(kcRegistries) != 0 {
+		return klusterletConfigSource("registries")
+	}
+	return annotationOrDefaultSource(annotations, imageregistry.ClusterImageRegistriesAnnotation)
+}
+
+// klusterletNamespaceSource returns the source of the klusterlet namespace, it follows the precedence of
+// getKlusterletNamespaceName.
+func klusterletNamespaceSource(config *klusterletconfigv1alpha1.KlusterletConfig, annotations map[string]string,
+	mode operatorv1.InstallMode) ValueSource {
+	if config != nil && config.Spec.InstallMode != nil &&
+		config.Spec.InstallMode.Type == klusterletconfigv1alpha1.InstallModeNoOperator &&
+		config.Spec.InstallMode.NoOperator != nil {
+		return klusterletConfigSource("installMode")
+	}
+	if _, ok := annotations[constants.KlusterletNamespaceAnnotation]; ok {
+		return managedClusterAnnotationSource(constants.KlusterletNamespaceAnnotation)
+	}
+	if mode == operatorv1.InstallModeHosted || mode == operatorv1.InstallModeSingletonHosted {
+		return ValueSource{Type: ValueSourceInstallMode, Key: string(mode)}
+	}
+	return defaultSource()
+}
+
+// ChartConfig returns the values of the klusterlet chart, the values are completed by Generate.
+func (c *KlusterletManifestsConfig) ChartConfig() *chart.KlusterletChartConfig {
+	return c.chartConfig
+}
+
+// ValueSources returns the sources of the effective configurations of the klusterlet manifests, e.g. nodeSelector,
+// tolerations and images. The sources are recorded by Generate.
+func (c *KlusterletManifestsConfig) ValueSources() map[string]ValueSource {
+	return c.sources
+}
+
+func (c *KlusterletManifestsConfig) recordSource(name string, source ValueSource) {
+	if c.sources == nil {
+		c.sources = map[string]ValueSource{}
+	}
+	c.sources[name] = source
+}
@@ -0,0 +1,161 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+)
+
+func TestValueSources(t *testing.T) {
+	cases := []struct {
+		name             string
+		mode             operatorv1.InstallMode
+		annotations      map[string]string
+		klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig
+		expectedSources  map[string]ValueSource
+	}{
+		{
+			name: "defaults",
+			mode: operatorv1.InstallModeSingleton,
+			expectedSources: map[string]ValueSource{
+				"noOperator":          {Type: ValueSourceDefault},
+				"images":              {Type: ValueSourceDefault},
+				"nodeSelector":        {Type: ValueSourceDefault},
+				"tolerations":         {Type: ValueSourceDefault},
+				"klusterletNamespace": {Type: ValueSourceDefault},
+				"imagePullSecret":     {Type: ValueSourceDefault},
+				"featureGates":        {Type: ValueSourceDefault},
+			},
+		},
+		{
+			name: "managed cluster annotations",
+			mode: operatorv1.InstallModeSingleton,
+			annotations: map[string]string{
+				helpers.NodeSelectorAnnotation:          `{"kubernetes.io/os":"linux"}`,
+				helpers.TolerationsAnnotation:           `[{"key":"foo","operator":"Exists","effect":"NoSchedule"}]`,
+				constants.KlusterletNamespaceAnnotation: "open-cluster-management-agent-test",
+				imageregistry.ClusterImageRegistriesAnnotation: `{"registries":[{"mirror":"quay.io/mirror",` +
+					`"source":"quay.io/open-cluster-management"}]}`,
+			},
+			expectedSources: map[string]ValueSource{
+				"images":              managedClusterAnnotationSource(imageregistry.ClusterImageRegistriesAnnotation),
+				"nodeSelector":        managedClusterAnnotationSource(helpers.NodeSelectorAnnotation),
+				"tolerations":         managedClusterAnnotationSource(helpers.TolerationsAnnotation),
+				"klusterletNamespace": managedClusterAnnotationSource(constants.KlusterletNamespaceAnnotation),
+			},
+		},
+		{
+			name: "klusterletconfig overrides the annotations",
+			mode: operatorv1.InstallModeSingleton,
+			annotations: map[string]string{
+				helpers.NodeSelectorAnnotation: `{"kubernetes.io/os":"linux"}`,
+				helpers.TolerationsAnnotation:  `[{"key":"foo","operator":"Exists","effect":"NoSchedule"}]`,
+			},
+			klusterletConfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				Spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+					Registries: []klusterletconfigv1alpha1.Registries{
+						{Source: "quay.io/open-cluster-management", Mirror: "quay.io/mirror"},
+					},
+					NodePlacement: &operatorv1.NodePlacement{
+						NodeSelector: map[string]string{"kubernetes.io/os": "linux"},
+					},
+					AppliedManifestWorkEvictionGracePeriod: "10m",
+					FeatureGates: []operatorv1.FeatureGate{
+						{Feature: "AddonManagement", Mode: operatorv1.FeatureGateModeTypeEnable},
+					},
+				},
+			},
+			expectedSources: map[string]ValueSource{
+				"images":                                 klusterletConfigSource("registries"),
+				"nodeSelector":                           klusterletConfigSource("nodePlacement"),
+				"tolerations":                            managedClusterAnnotationSource(helpers.TolerationsAnnotation),
+				"appliedManifestWorkEvictionGracePeriod": klusterletConfigSource("appliedManifestWorkEvictionGracePeriod"),
+				"featureGates":                           klusterletConfigSource("featureGates"),
+			},
+		},
+		{
+			name: "hosted mode",
+			mode: operatorv1.InstallModeHosted,
+			klusterletConfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				Spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+					Registries: []klusterletconfigv1alpha1.Registries{
+						{Source: "quay.io/open-cluster-management", Mirror: "quay.io/mirror"},
+					},
+				},
+			},
+			expectedSources: map[string]ValueSource{
+				"noOperator":          {Type: ValueSourceInstallMode, Key: string(operatorv1.InstallModeHosted)},
+				"klusterletNamespace": {Type: ValueSourceInstallMode, Key: string(operatorv1.InstallModeHosted)},
+				"images":              {Type: ValueSourceDefault},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			os.Setenv(constants.DefaultImagePullSecretEnvVarName, "")
+
+			kubeClient := kubefake.NewSimpleClientset()
+			clientHolder := &helpers.ClientHolder{
+				KubeClient:          kubeClient,
+				RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).Build(),
+				ImageRegistryClient: imageregistry.NewClient(kubeClient),
+			}
+			managedCluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: c.annotations},
+			}
+			config, err := NewManagedClusterKlusterletManifestsConfig(c.mode, managedCluster, c.klusterletConfig, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, _, err := config.Generate(context.Background(), clientHolder); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			sources := config.ValueSources()
+			for name, expected := range c.expectedSources {
+				actual, ok := sources[name]
+				if !ok {
+					t.Errorf("the source of %s is not recorded", name)
+					continue
+				}
+				if actual.Type != expected.Type || actual.Key != expected.Key {
+					t.Errorf("expected the source of %s to be %v, but got %v", name, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestNewManagedClusterKlusterletManifestsConfig(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+
+	config, err := NewManagedClusterKlusterletManifestsConfig(operatorv1.InstallModeHosted, managedCluster, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.ChartConfig().Images.ImageCredentials.CreateImageCredentials {
+		t.Errorf("the image pull secret should not be generated in the hosted mode")
+	}
+	if config.ChartConfig().PriorityClassName != constants.DefaultKlusterletPriorityClassName {
+		t.Errorf("unexpected priority class name %q", config.ChartConfig().PriorityClassName)
+	}
+
+	if _, err := NewManagedClusterKlusterletManifestsConfig("Unknown", managedCluster, nil, nil); err == nil {
+		t.Errorf("expected error, but got nil")
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+const (
+	defaultContextName  = "default-context"
+	defaultAuthInfoName = "default-auth"
+)
+
+// KubeAPIServerConfig is the config of a hub kube apiserver URL in the bootstrap kubeconfig
+type KubeAPIServerConfig struct {
+	URL      string
+	ProxyURL string
+	CA       string
+	CAData   []byte
+}
+
+// additionalContextName returns the context name of the i-th additional hub kube apiserver URL in the bootstrap
+// kubeconfig, the index starts from 1.
+func additionalContextName(i int) string {
+	return fmt.Sprintf("%s-%d", defaultContextName, i)
+}
+
+// EndpointBootstrapKubeConfigSecretName returns the name of the i-th bootstrap kubeconfig secret when the hub has
+// additional kube apiserver URLs, the index starts from 0 and the first secret contains all of the URLs.
+func EndpointBootstrapKubeConfigSecretName(i int) string {
+	return fmt.Sprintf("%s-endpoint-%d", constants.DefaultBootstrapHubKubeConfigSecretName, i)
+}
+
+// GetAdditionalKubeAPIServerURLs returns the additional hub kube apiserver URLs in the
+// hub-kube-apiserver-additional-urls annotation of the KlusterletConfig in order.
+func GetAdditionalKubeAPIServerURLs(klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig) ([]string, error) {
+	if klusterletConfig == nil {
+		return nil, nil
+	}
+
+	value := klusterletConfig.Annotations[constants.AnnotationHubKubeAPIServerAdditionalURLs]
+	urls := []string{}
+	existing := sets.New[string]()
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+
+		u, err := url.Parse(item)
+		if err != nil {
+			return nil, fmt.Errorf("the additional hub kube apiserver URL %q is invalid: %v", item, err)
+		}
+		if u.Scheme != "https" || len(u.Host) == 0 {
+			return nil, fmt.Errorf("the additional hub kube apiserver URL %q is invalid, it should be an https URL", item)
+		}
+		if existing.Has(item) {
+			continue
+		}
+		existing.Insert(item)
+		urls = append(urls, item)
+	}
+	return urls, nil
+}
+
+// GetAdditionalKubeAPIServerConfigs returns the configs of the additional hub kube apiserver URLs of the
+// KlusterletConfig in order. The additional URLs share the proxy settings and the server verification strategy
+// with the hub kube apiserver URL. They are ignored for the self managed cluster, and the URLs which are same as
+// the hub kube apiserver URL are ignored.
+func GetAdditionalKubeAPIServerConfigs(ctx context.Context, clientHolder *helpers.ClientHolder, ns string,
+	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig, kubeAPIServer string,
+	selfManaged bool) ([]KubeAPIServerConfig, error) {
+	if selfManaged {
+		return nil, nil
+	}
+
+	urls, err := GetAdditionalKubeAPIServerURLs(klusterletConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, _ := GetProxySettings(klusterletConfig)
+	configs := []KubeAPIServerConfig{}
+	for _, u := range urls {
+		if u == kubeAPIServer {
+			continue
+		}
+
+		caData, err := GetBootstrapCAData(ctx, clientHolder, u, ns, klusterletConfig)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, KubeAPIServerConfig{URL: u, ProxyURL: proxy, CAData: caData})
+	}
+	return configs, nil
+}
+
+// GetAdditionalKubeAPIServerConfigsFromKubeconfig returns the configs of the additional hub kube apiserver URLs
+// in the bootstrap kubeconfig in order.
+func GetAdditionalKubeAPIServerConfigsFromKubeconfig(kubeconfigData []byte) ([]KubeAPIServerConfig, error) {
+	config, err := clientcmd.Load(kubeconfigData)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := []KubeAPIServerConfig{}
+	for i := 1; ; i++ {
+		kubeContext, ok := config.Contexts[additionalContextName(i)]
+		if !ok {
+			break
+		}
+		cluster, ok := config.Clusters[kubeContext.Cluster]
+		if !ok {
+			return nil, fmt.Errorf("the cluster %q of the context %q is not found", kubeContext.Cluster,
+				additionalContextName(i))
+		}
+		configs = append(configs, KubeAPIServerConfig{
+			URL:      cluster.Server,
+			ProxyURL: cluster.ProxyURL,
+			CA:       cluster.CertificateAuthority,
+			CAData:   cluster.CertificateAuthorityData,
+		})
+	}
+	return configs, nil
+}
+
+// splitBootstrapKubeConfig splits the bootstrap kubeconfig which has additional hub kube apiserver URLs into a
+// list of bootstrap kubeconfigs in order. The first one is the bootstrap kubeconfig itself, its current context
+// uses the first URL, and each of the others only has an additional URL. The clusters of the split kubeconfigs
+// have the same name, so they are treated as the same hub by the agent. Nil is returned if the bootstrap
+// kubeconfig cannot be loaded or does not have additional URLs, it is used as it is.
+func splitBootstrapKubeConfig(kubeconfigData []byte) ([][]byte, error) {
+	config, err := clientcmd.Load(kubeconfigData)
+	if err != nil {
+		return nil, nil
+	}
+
+	currentContext, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, nil
+	}
+	if _, ok := config.Contexts[additionalContextName(1)]; !ok {
+		return nil, nil
+	}
+
+	kubeconfigs := [][]byte{kubeconfigData}
+	for i := 1; ; i++ {
+		kubeContext, ok := config.Contexts[additionalContextName(i)]
+		if !ok {
+			break
+		}
+		cluster, ok := config.Clusters[kubeContext.Cluster]
+		if !ok {
+			return nil, fmt.Errorf("the cluster %q of the context %q is not found", kubeContext.Cluster,
+				additionalContextName(i))
+		}
+
+		splitConfig := clientcmdapi.Config{
+			Clusters:  map[string]*clientcmdapi.Cluster{currentContext.Cluster: cluster},
+			AuthInfos: map[string]*clientcmdapi.AuthInfo{kubeContext.AuthInfo: config.AuthInfos[kubeContext.AuthInfo]},
+			Contexts: map[string]*clientcmdapi.Context{defaultContextName: {
+				Cluster:   currentContext.Cluster,
+				AuthInfo:  kubeContext.AuthInfo,
+				Namespace: kubeContext.Namespace,
+			}},
+			CurrentContext: defaultContextName,
+		}
+		data, err := runtime.Encode(clientcmdlatest.Codec, &splitConfig)
+		if err != nil {
+			return nil, err
+		}
+		kubeconfigs = append(kubeconfigs, data)
+	}
+	return kubeconfigs, nil
+}
+
+// equalKubeAPIServerConfigs checks if the two lists of kube apiserver configs are same
+func equalKubeAPIServerConfigs(configs, requiredConfigs []KubeAPIServerConfig) bool {
+	if len(configs) != len(requiredConfigs) {
+		return false
+	}
+	for i := range configs {
+		if configs[i].URL != requiredConfigs[i].URL ||
+			configs[i].ProxyURL != requiredConfigs[i].ProxyURL ||
+			configs[i].CA != requiredConfigs[i].CA ||
+			!bytes.Equal(configs[i].CAData, requiredConfigs[i].CAData) {
+			return false
+		}
+	}
+	return true
+}
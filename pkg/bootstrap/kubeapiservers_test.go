@@ -0,0 +1,125 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestGetAdditionalKubeAPIServerURLs(t *testing.T) {
+	cases := []struct {
+		name         string
+		annotations  map[string]string
+		expectedURLs []string
+		expectedErr  bool
+	}{
+		{
+			name:         "no annotation",
+			expectedURLs: []string{},
+		},
+		{
+			name: "ordered urls",
+			annotations: map[string]string{
+				constants.AnnotationHubKubeAPIServerAdditionalURLs: " https://api-2.example.com:6443, ," +
+					"https://api-3.example.com:6443,https://api-2.example.com:6443",
+			},
+			expectedURLs: []string{"https://api-2.example.com:6443", "https://api-3.example.com:6443"},
+		},
+		{
+			name: "http url",
+			annotations: map[string]string{
+				constants.AnnotationHubKubeAPIServerAdditionalURLs: "http://api-2.example.com:6443",
+			},
+			expectedErr: true,
+		},
+		{
+			name: "no host",
+			annotations: map[string]string{
+				constants.AnnotationHubKubeAPIServerAdditionalURLs: "https://",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			urls, err := GetAdditionalKubeAPIServerURLs(&klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{Annotations: c.annotations},
+			})
+			if (err != nil) != c.expectedErr {
+				t.Fatalf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if !c.expectedErr && !reflect.DeepEqual(urls, c.expectedURLs) {
+				t.Errorf("expected %v, but got %v", c.expectedURLs, urls)
+			}
+		})
+	}
+}
+
+func TestBootstrapKubeConfigWithAdditionalKubeAPIServers(t *testing.T) {
+	additionalConfigs := []KubeAPIServerConfig{
+		{URL: "https://api-2.example.com:6443", ProxyURL: "http://proxy.example.com:3128", CAData: []byte("ca-2")},
+		{URL: "https://api-3.example.com:6443", CAData: []byte("ca-3")},
+	}
+	kubeconfigData, err := CreateBootstrapKubeConfig("hub", "https://api-1.example.com:6443", "", "",
+		[]byte("ca-1"), []byte("token"), additionalConfigs...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	configs, err := GetAdditionalKubeAPIServerConfigsFromKubeconfig(kubeconfigData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !equalKubeAPIServerConfigs(configs, additionalConfigs) {
+		t.Errorf("expected %v, but got %v", additionalConfigs, configs)
+	}
+
+	kubeconfigs, err := splitBootstrapKubeConfig(kubeconfigData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(kubeconfigs) != 3 {
+		t.Fatalf("expected 3 kubeconfigs, but got %d", len(kubeconfigs))
+	}
+	if string(kubeconfigs[0]) != string(kubeconfigData) {
+		t.Errorf("the first kubeconfig should be the bootstrap kubeconfig")
+	}
+	for i, kubeconfig := range kubeconfigs[1:] {
+		config, err := clientcmd.Load(kubeconfig)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		kubeContext := config.Contexts[config.CurrentContext]
+		if kubeContext == nil || kubeContext.Cluster != "hub" {
+			t.Fatalf("unexpected current context %v", kubeContext)
+		}
+		cluster := config.Clusters["hub"]
+		if cluster.Server != additionalConfigs[i].URL || cluster.ProxyURL != additionalConfigs[i].ProxyURL ||
+			string(cluster.CertificateAuthorityData) != string(additionalConfigs[i].CAData) {
+			t.Errorf("unexpected cluster %v of the kubeconfig %d", cluster, i+1)
+		}
+		if config.AuthInfos[kubeContext.AuthInfo].Token != "token" {
+			t.Errorf("unexpected token of the kubeconfig %d", i+1)
+		}
+	}
+
+	singleKubeconfigData, err := CreateBootstrapKubeConfig("hub", "https://api-1.example.com:6443", "", "",
+		[]byte("ca-1"), []byte("token"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubeconfigs, err = splitBootstrapKubeConfig(singleKubeconfigData)
+	if err != nil || kubeconfigs != nil {
+		t.Errorf("expected the bootstrap kubeconfig is not split, but got %d kubeconfigs, %v", len(kubeconfigs), err)
+	}
+}
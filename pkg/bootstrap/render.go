@@ -11,7 +11,6 @@ import (
 	"os"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/stolostron/cluster-lifecycle-api/helpers/localcluster"
 	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
@@ -61,6 +60,8 @@ type KlusterletManifestsConfig struct {
 	chartConfig      *chart.KlusterletChartConfig
 	managedCluster   *clusterv1.ManagedCluster
 	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig
+	// sources records where the effective configurations come from, it is set by Generate
+	sources map[string]ValueSource
 }
 
 func NewKlusterletManifestsConfig(installMode operatorv1.InstallMode,
@@ -131,6 +132,43 @@ func newKlusterletChartConfig(installMode operatorv1.InstallMode,
 	return chartConfig
 }
 
+// NewManagedClusterKlusterletManifestsConfig returns the config to generate the klusterlet manifests of the import
+// secret of a managed cluster with the install mode and the merged KlusterletConfig.
+func NewManagedClusterKlusterletManifestsConfig(mode operatorv1.InstallMode, managedCluster *clusterv1.ManagedCluster,
+	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig, bootstrapKubeConfig []byte) (*KlusterletManifestsConfig, error) {
+	switch mode {
+	case operatorv1.InstallModeDefault, operatorv1.InstallModeSingleton:
+		supportPriorityClass, err := helpers.SupportPriorityClass(managedCluster)
+		if err != nil {
+			return nil, err
+		}
+		var priorityClassName string
+		if supportPriorityClass {
+			priorityClassName = constants.DefaultKlusterletPriorityClassName
+		}
+		return NewKlusterletManifestsConfig(
+			mode,
+			managedCluster.Name,
+			bootstrapKubeConfig).
+			WithManagedCluster(managedCluster).
+			WithKlusterletConfig(klusterletConfig).
+			WithPriorityClassName(priorityClassName), nil
+	case operatorv1.InstallModeHosted, operatorv1.InstallModeSingletonHosted:
+		return NewKlusterletManifestsConfig(
+			mode,
+			managedCluster.Name,
+			bootstrapKubeConfig).
+			WithManagedCluster(managedCluster).
+			WithoutImagePullSecretGenerate().
+			// the hosting cluster should support PriorityClass API and have
+			// already had the default PriorityClass
+			WithPriorityClassName(constants.DefaultKlusterletPriorityClassName).
+			WithKlusterletConfig(klusterletConfig), nil
+	default:
+		return nil, fmt.Errorf("klusterlet deploy mode %s not supported", mode)
+	}
+}
+
 // WithKlusterletClusterAnnotations sets the klusterlet cluster annotations(klusterlet.spec.registrationConfiguration.clusterAnnotations).
 // These annotations must begin with a prefix "agent.open-cluster-management.io*".
 func (c *KlusterletManifestsConfig) WithKlusterletClusterAnnotations(a map[string]string) *KlusterletManifestsConfig {
@@ -187,6 +225,14 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 	}
 
 	c.chartConfig.NoOperator = installNoOperator(installMode, c.klusterletConfig)
+	switch {
+	case installMode == operatorv1.InstallModeHosted || installMode == operatorv1.InstallModeSingletonHosted:
+		c.recordSource("noOperator", ValueSource{Type: ValueSourceInstallMode, Key: string(installMode)})
+	case c.klusterletConfig != nil && c.klusterletConfig.Spec.InstallMode != nil:
+		c.recordSource("noOperator", klusterletConfigSource("installMode"))
+	default:
+		c.recordSource("noOperator", defaultSource())
+	}
 
 	var managedClusterAnnotations map[string]string
 	if c.managedCluster != nil {
@@ -201,12 +247,16 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 	c.chartConfig.Images.Overrides.OperatorImage = klusterletAgentImages[constants.RegistrationOperatorImageEnvVarName]
 	c.chartConfig.Images.Overrides.RegistrationImage = klusterletAgentImages[constants.RegistrationImageEnvVarName]
 	c.chartConfig.Images.Overrides.WorkImage = klusterletAgentImages[constants.WorkImageEnvVarName]
+	c.recordSource("images", registriesSource(kcRegistries, managedClusterAnnotations))
 
 	// NodeSelector
 	var nodeSelector map[string]string
 	if kcNodePlacement != nil && len(kcNodePlacement.NodeSelector) != 0 {
 		nodeSelector = kcNodePlacement.NodeSelector
+		c.recordSource("nodeSelector", klusterletConfigSource("nodePlacement"))
 	} else {
+		c.recordSource("nodeSelector", annotationOrDefaultSource(managedClusterAnnotations,
+			helpers.NodeSelectorAnnotation))
 		nodeSelector, err = helpers.GetNodeSelectorFromManagedClusterAnnotations(managedClusterAnnotations)
 		if err != nil {
 			return nil, nil, fmt.Errorf("get nodeSelector for cluster %s failed: %v", clusterName, err)
@@ -222,7 +272,10 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 	var tolerations []corev1.Toleration
 	if kcNodePlacement != nil && len(kcNodePlacement.Tolerations) != 0 {
 		tolerations = kcNodePlacement.Tolerations
+		c.recordSource("tolerations", klusterletConfigSource("nodePlacement"))
 	} else {
+		c.recordSource("tolerations", annotationOrDefaultSource(managedClusterAnnotations,
+			helpers.TolerationsAnnotation))
 		tolerations, err = helpers.GetTolerationsFromManagedClusterAnnotations(managedClusterAnnotations)
 		if err != nil {
 			return nil, nil, fmt.Errorf("get tolerations for cluster %s failed: %v", clusterName, err)
@@ -236,23 +289,23 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 
 	c.chartConfig.Klusterlet.Name, c.chartConfig.Klusterlet.Namespace = getKlusterletNamespaceName(
 		c.klusterletConfig, clusterName, managedClusterAnnotations, installMode)
+	c.recordSource("klusterletNamespace", klusterletNamespaceSource(
+		c.klusterletConfig, managedClusterAnnotations, installMode))
 
 	// WorkAgentConfiguration
 	workAgentConfiguration := operatorv1.WorkAgentConfiguration{}
-	if appliedManifestWorkEvictionGracePeriod == constants.AppliedManifestWorkEvictionGracePeriodInfinite {
-		appliedManifestWorkEvictionGracePeriod = constants.AppliedManifestWorkEvictionGracePeriod100Years
-	}
-	if appliedManifestWorkEvictionGracePeriod != "" {
-		appliedManifestWorkEvictionGracePeriodTimeDuration, err := time.ParseDuration(appliedManifestWorkEvictionGracePeriod)
-		if err != nil {
-			return nil, nil, fmt.Errorf("parse appliedManifestWorkEvictionGracePeriod %s failed: %v",
-				appliedManifestWorkEvictionGracePeriod, err)
-		}
-		workAgentConfiguration.AppliedManifestWorkEvictionGracePeriod = &metav1.Duration{
-			Duration: appliedManifestWorkEvictionGracePeriodTimeDuration,
-		}
+	workAgentConfiguration.AppliedManifestWorkEvictionGracePeriod, err = parseAppliedManifestWorkEvictionGracePeriod(
+		appliedManifestWorkEvictionGracePeriod)
+	if err != nil {
+		return nil, nil, err
 	}
 	c.chartConfig.Klusterlet.WorkConfiguration = workAgentConfiguration
+	if len(appliedManifestWorkEvictionGracePeriod) > 0 {
+		c.recordSource("appliedManifestWorkEvictionGracePeriod",
+			klusterletConfigSource("appliedManifestWorkEvictionGracePeriod"))
+	} else {
+		c.recordSource("appliedManifestWorkEvictionGracePeriod", defaultSource())
+	}
 
 	// need to generate imagePullSecret
 	if c.chartConfig.Images.ImageCredentials.CreateImageCredentials {
@@ -269,9 +322,20 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 		}
 
 		c.chartConfig.Images.ImageCredentials.DockerConfigJson = string(imagePullSecret.Data[corev1.DockerConfigJsonKey])
+		switch {
+		case kcImagePullSecret.Name != "":
+			c.recordSource("imagePullSecret", klusterletConfigSource("pullSecret"))
+		default:
+			c.recordSource("imagePullSecret", annotationOrDefaultSource(managedClusterAnnotations,
+				imageregistry.ClusterImageRegistriesAnnotation))
+		}
 	}
 
 	// feature gates
+	c.recordSource("featureGates", defaultSource())
+	if c.klusterletConfig != nil && len(c.klusterletConfig.Spec.FeatureGates) > 0 {
+		c.recordSource("featureGates", klusterletConfigSource("featureGates"))
+	}
 	if c.klusterletConfig != nil {
 		for _, f := range c.klusterletConfig.Spec.FeatureGates {
 			if _, ok := apifeature.DefaultSpokeRegistrationFeatureGates[featuregate.Feature(f.Feature)]; ok {
@@ -292,6 +356,16 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 		localCluster = true
 	}
 
+	// The bootstrap kubeconfig with additional hub kube apiserver URLs is split into a kubeconfig for each URL,
+	// so the agent can connect to the hub with the next URL when the current one is unavailable.
+	var currentHubKubeConfigs [][]byte
+	if !localCluster && len(c.chartConfig.BootstrapHubKubeConfig) > 0 {
+		currentHubKubeConfigs, err = splitBootstrapKubeConfig([]byte(c.chartConfig.BootstrapHubKubeConfig))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	if !localCluster &&
 		c.klusterletConfig != nil &&
 		c.klusterletConfig.Spec.MultipleHubsConfig != nil &&
@@ -300,31 +374,9 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 			return nil, nil, fmt.Errorf("local secrets should be set")
 		}
 
-		// If MultipleHubs feature is not set in featureGates field, set it to enable.
-		var multipleHubFeatureSet bool
-		for _, f := range c.chartConfig.Klusterlet.RegistrationConfiguration.FeatureGates {
-			if f.Feature == string(apifeature.MultipleHubs) {
-				multipleHubFeatureSet = true
-			}
-		}
-
-		if !multipleHubFeatureSet {
-			c.chartConfig.Klusterlet.RegistrationConfiguration.FeatureGates = append(c.chartConfig.Klusterlet.RegistrationConfiguration.FeatureGates,
-				operatorv1.FeatureGate{
-					Feature: string(apifeature.MultipleHubs),
-					Mode:    operatorv1.FeatureGateModeTypeEnable,
-				})
-		}
+		enableMultipleHubsFeatureGate(c.chartConfig)
 		c.chartConfig.Klusterlet.RegistrationConfiguration.BootstrapKubeConfigs = *c.klusterletConfig.Spec.MultipleHubsConfig.BootstrapKubeConfigs.DeepCopy()
-
-		// Only append the current hub KubeConfigSecret if the strategy is IncludeCurrentHub
-		if c.klusterletConfig.Spec.MultipleHubsConfig != nil &&
-			c.klusterletConfig.Spec.MultipleHubsConfig.GenBootstrapKubeConfigStrategy == klusterletconfigv1alpha1.GenBootstrapKubeConfigStrategyIncludeCurrentHub {
-			c.chartConfig.Klusterlet.RegistrationConfiguration.BootstrapKubeConfigs.LocalSecrets.KubeConfigSecrets = append(
-				c.chartConfig.Klusterlet.RegistrationConfiguration.BootstrapKubeConfigs.LocalSecrets.KubeConfigSecrets, operatorv1.KubeConfigSecret{
-					Name: constants.DefaultBootstrapHubKubeConfigSecretName + "-current-hub",
-				})
-		}
+		c.recordSource("bootstrapKubeConfigs", klusterletConfigSource("multipleHubsConfig"))
 
 		bootstrapKubeConfigSecrets, err := convertKubeConfigSecrets(ctx,
 			c.klusterletConfig.Spec.MultipleHubsConfig.BootstrapKubeConfigs.LocalSecrets.KubeConfigSecrets, clientHolder.KubeClient)
@@ -335,19 +387,61 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 		// Only append the current hub KubeConfigSecret if the strategy is IncludeCurrentHub
 		if c.klusterletConfig.Spec.MultipleHubsConfig != nil &&
 			c.klusterletConfig.Spec.MultipleHubsConfig.GenBootstrapKubeConfigStrategy == klusterletconfigv1alpha1.GenBootstrapKubeConfigStrategyIncludeCurrentHub {
-			bootstrapKubeConfigSecrets = append(bootstrapKubeConfigSecrets, chart.BootStrapKubeConfig{
+			currentHubSecrets := []chart.BootStrapKubeConfig{{
 				Name:       constants.DefaultBootstrapHubKubeConfigSecretName + "-current-hub",
 				KubeConfig: c.chartConfig.BootstrapHubKubeConfig,
+			}}
+			for i := 1; i < len(currentHubKubeConfigs); i++ {
+				currentHubSecrets = append(currentHubSecrets, chart.BootStrapKubeConfig{
+					Name:       fmt.Sprintf("%s-current-hub-%d", constants.DefaultBootstrapHubKubeConfigSecretName, i),
+					KubeConfig: string(currentHubKubeConfigs[i]),
+				})
+			}
+
+			for _, secret := range currentHubSecrets {
+				c.chartConfig.Klusterlet.RegistrationConfiguration.BootstrapKubeConfigs.LocalSecrets.KubeConfigSecrets = append(
+					c.chartConfig.Klusterlet.RegistrationConfiguration.BootstrapKubeConfigs.LocalSecrets.KubeConfigSecrets, operatorv1.KubeConfigSecret{
+						Name: secret.Name,
+					})
+			}
+			bootstrapKubeConfigSecrets = append(bootstrapKubeConfigSecrets, currentHubSecrets...)
+		}
+		c.chartConfig.MultiHubBootstrapHubKubeConfigs = bootstrapKubeConfigSecrets
+	} else if len(currentHubKubeConfigs) > 1 {
+		enableMultipleHubsFeatureGate(c.chartConfig)
+
+		localSecrets := &operatorv1.LocalSecretsConfig{}
+		var bootstrapKubeConfigSecrets []chart.BootStrapKubeConfig
+		for i, kubeconfig := range currentHubKubeConfigs {
+			name := EndpointBootstrapKubeConfigSecretName(i)
+			localSecrets.KubeConfigSecrets = append(localSecrets.KubeConfigSecrets, operatorv1.KubeConfigSecret{Name: name})
+			bootstrapKubeConfigSecrets = append(bootstrapKubeConfigSecrets, chart.BootStrapKubeConfig{
+				Name:       name,
+				KubeConfig: string(kubeconfig),
 			})
 		}
+		c.chartConfig.Klusterlet.RegistrationConfiguration.BootstrapKubeConfigs = operatorv1.BootstrapKubeConfigs{
+			Type:         operatorv1.LocalSecrets,
+			LocalSecrets: localSecrets,
+		}
 		c.chartConfig.MultiHubBootstrapHubKubeConfigs = bootstrapKubeConfigSecrets
+		// the bootstrap kubeconfig has the additional hub kube apiserver URLs of the KlusterletConfig
+		c.recordSource("bootstrapKubeConfigs", klusterletConfigSource(constants.AnnotationHubKubeAPIServerAdditionalURLs))
+	} else {
+		c.recordSource("bootstrapKubeConfigs", defaultSource())
 	}
 
 	// Set MCE reserved clusterclaims
 	setClusterClaimConfiguation(c.chartConfig, c.klusterletConfig)
+	c.recordSource("clusterClaimConfiguration", defaultSource())
+	if c.klusterletConfig != nil && c.klusterletConfig.Spec.ClusterClaimConfiguration != nil {
+		c.recordSource("clusterClaimConfiguration", klusterletConfigSource("clusterClaimConfiguration"))
+	}
 
+	c.recordSource("workStatusSyncInterval", defaultSource())
 	if c.klusterletConfig != nil && c.klusterletConfig.Spec.WorkStatusSyncInterval != nil {
 		c.chartConfig.Klusterlet.WorkConfiguration.StatusSyncInterval = c.klusterletConfig.Spec.WorkStatusSyncInterval
+		c.recordSource("workStatusSyncInterval", klusterletConfigSource("workStatusSyncInterval"))
 	}
 
 	crds, objects, err := chart.RenderKlusterletChart(c.chartConfig, c.chartConfig.Klusterlet.Namespace)
@@ -370,6 +464,21 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 	return manifestsBytes, crdBytes, nil
 }
 
+// enableMultipleHubsFeatureGate enables the MultipleHubs feature gate of the registration agent if it is not set
+func enableMultipleHubsFeatureGate(cc *chart.KlusterletChartConfig) {
+	for _, f := range cc.Klusterlet.RegistrationConfiguration.FeatureGates {
+		if f.Feature == string(apifeature.MultipleHubs) {
+			return
+		}
+	}
+
+	cc.Klusterlet.RegistrationConfiguration.FeatureGates = append(cc.Klusterlet.RegistrationConfiguration.FeatureGates,
+		operatorv1.FeatureGate{
+			Feature: string(apifeature.MultipleHubs),
+			Mode:    operatorv1.FeatureGateModeTypeEnable,
+		})
+}
+
 func setClusterClaimConfiguation(cc *chart.KlusterletChartConfig, kc *klusterletconfigv1alpha1.KlusterletConfig) {
 	defaultConfiguation := &operatorv1.ClusterClaimConfiguration{
 		ReservedClusterClaimSuffixes: reservedClusterClaimSuffixes,
@@ -20,6 +20,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
 	v1 "open-cluster-management.io/api/cluster/v1"
 	operatorv1 "open-cluster-management.io/api/operator/v1"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -35,6 +36,12 @@ func init() {
 func TestKlusterletConfigGenerate(t *testing.T) {
 	var tolerationSeconds int64 = 20
 
+	multipleURLsKubeConfig, err := CreateBootstrapKubeConfig("hub", "https://api-1.example.com:6443", "", "",
+		[]byte("ca-1"), []byte("token"), KubeAPIServerConfig{URL: "https://api-2.example.com:6443", CAData: []byte("ca-2")})
+	if err != nil {
+		t.Fatalf("failed to create the bootstrap kubeconfig: %v", err)
+	}
+
 	testcases := []struct {
 		name                   string
 		defaultImagePullSecret string
@@ -712,6 +719,67 @@ func TestKlusterletConfigGenerate(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "with additional hub kube apiserver URLs",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+			},
+			config: NewKlusterletManifestsConfig(
+				operatorv1.InstallModeDefault,
+				"test",
+				multipleURLsKubeConfig,
+			),
+			validateFunc: func(t *testing.T, objs, crds []runtime.Object) {
+				var klusterlet *operatorv1.Klusterlet
+				secrets := map[string][]byte{}
+				for _, obj := range objs {
+					switch o := obj.(type) {
+					case *operatorv1.Klusterlet:
+						klusterlet = o
+					case *corev1.Secret:
+						secrets[o.Name] = o.Data["kubeconfig"]
+					}
+				}
+				if klusterlet == nil {
+					t.Fatalf("the klusterlet is not found")
+				}
+
+				multiplehubsEnabled := false
+				for _, fg := range klusterlet.Spec.RegistrationConfiguration.FeatureGates {
+					if fg.Feature == "MultipleHubs" && fg.Mode == operatorv1.FeatureGateModeTypeEnable {
+						multiplehubsEnabled = true
+					}
+				}
+				if !multiplehubsEnabled {
+					t.Errorf("the klusterlet MultipleHubs feature is not enabled")
+				}
+				bootstrapKubeConfigs := klusterlet.Spec.RegistrationConfiguration.BootstrapKubeConfigs
+				if bootstrapKubeConfigs.Type != operatorv1.LocalSecrets || bootstrapKubeConfigs.LocalSecrets == nil ||
+					len(bootstrapKubeConfigs.LocalSecrets.KubeConfigSecrets) != 2 ||
+					bootstrapKubeConfigs.LocalSecrets.KubeConfigSecrets[0].Name != "bootstrap-hub-kubeconfig-endpoint-0" ||
+					bootstrapKubeConfigs.LocalSecrets.KubeConfigSecrets[1].Name != "bootstrap-hub-kubeconfig-endpoint-1" {
+					t.Errorf("unexpected bootstrap kubeconfigs: %v", bootstrapKubeConfigs)
+				}
+
+				if _, ok := secrets[constants.DefaultBootstrapHubKubeConfigSecretName]; ok {
+					t.Errorf("the secret %s should not be rendered", constants.DefaultBootstrapHubKubeConfigSecretName)
+				}
+				if string(secrets["bootstrap-hub-kubeconfig-endpoint-0"]) != string(multipleURLsKubeConfig) {
+					t.Errorf("the first endpoint secret should have the bootstrap kubeconfig")
+				}
+				config, err := clientcmd.Load(secrets["bootstrap-hub-kubeconfig-endpoint-1"])
+				if err != nil {
+					t.Fatalf("failed to load the second endpoint kubeconfig: %v", err)
+				}
+				if server := config.Clusters["hub"].Server; server != "https://api-2.example.com:6443" {
+					t.Errorf("unexpected server of the second endpoint kubeconfig: %s", server)
+				}
+			},
+		},
 		{
 			name: "with mutliplehubs enabled but local-cluster",
 			clientObjs: []runtimeclient.Object{
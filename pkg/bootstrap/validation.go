@@ -0,0 +1,109 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/kubernetes"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+)
+
+// registryRegexp matches a registry with an optional repository path, e.g. quay.io:443/open-cluster-management,
+// it follows the domain and path components of the docker image reference.
+var registryRegexp = regexp.MustCompile(
+	`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)*(:[0-9]+)?` +
+		`(/[a-z0-9]+(([._]|__|[-]+)[a-z0-9]+)*)*$`)
+
+// ValidateKlusterletConfigSpec validates the configurations of the KlusterletConfig spec which are used to generate
+// the klusterlet manifests, it returns all of the invalid configurations in an aggregated error.
+func ValidateKlusterletConfigSpec(spec klusterletconfigv1alpha1.KlusterletConfigSpec) error {
+	errs := []error{}
+	for _, registry := range spec.Registries {
+		if err := validateRegistry(registry.Mirror); err != nil {
+			errs = append(errs, fmt.Errorf("invalid registries mirror: %v", err))
+		}
+		if len(registry.Source) == 0 {
+			continue
+		}
+		if err := validateRegistry(registry.Source); err != nil {
+			errs = append(errs, fmt.Errorf("invalid registries source: %v", err))
+		}
+	}
+
+	if spec.NodePlacement != nil {
+		if err := helpers.ValidateNodeSelector(spec.NodePlacement.NodeSelector); err != nil {
+			errs = append(errs, fmt.Errorf("invalid nodeSelector: %v", err))
+		}
+		if err := helpers.ValidateTolerations(spec.NodePlacement.Tolerations); err != nil {
+			errs = append(errs, fmt.Errorf("invalid tolerations: %v", err))
+		}
+	}
+
+	if _, err := parseAppliedManifestWorkEvictionGracePeriod(spec.AppliedManifestWorkEvictionGracePeriod); err != nil {
+		errs = append(errs, err)
+	}
+
+	if spec.MultipleHubsConfig != nil &&
+		spec.MultipleHubsConfig.BootstrapKubeConfigs.Type == operatorv1.LocalSecrets &&
+		spec.MultipleHubsConfig.BootstrapKubeConfigs.LocalSecrets == nil {
+		errs = append(errs, fmt.Errorf("local secrets should be set"))
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// ValidateKlusterletConfig validates the KlusterletConfig spec and the resources it references, i.e. the local
+// secrets of the bootstrap kubeconfigs should exist in the pod namespace and have the kubeconfig.
+func ValidateKlusterletConfig(ctx context.Context, kubeClient kubernetes.Interface,
+	kc *klusterletconfigv1alpha1.KlusterletConfig) error {
+	errs := []error{}
+	if err := ValidateKlusterletConfigSpec(kc.Spec); err != nil {
+		errs = append(errs, err)
+	}
+
+	if kc.Spec.MultipleHubsConfig != nil &&
+		kc.Spec.MultipleHubsConfig.BootstrapKubeConfigs.Type == operatorv1.LocalSecrets &&
+		kc.Spec.MultipleHubsConfig.BootstrapKubeConfigs.LocalSecrets != nil {
+		for _, secret := range kc.Spec.MultipleHubsConfig.BootstrapKubeConfigs.LocalSecrets.KubeConfigSecrets {
+			if _, err := convertKubeConfigSecrets(ctx, []operatorv1.KubeConfigSecret{secret}, kubeClient); err != nil {
+				errs = append(errs, fmt.Errorf("invalid local secret %s: %v", secret.Name, err))
+			}
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// parseAppliedManifestWorkEvictionGracePeriod parses the appliedManifestWorkEvictionGracePeriod, the INFINITE is
+// 100 years, it returns nil if the value is empty.
+func parseAppliedManifestWorkEvictionGracePeriod(value string) (*metav1.Duration, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if value == constants.AppliedManifestWorkEvictionGracePeriodInfinite {
+		value = constants.AppliedManifestWorkEvictionGracePeriod100Years
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("parse appliedManifestWorkEvictionGracePeriod %s failed: %v", value, err)
+	}
+	return &metav1.Duration{Duration: duration}, nil
+}
+
+func validateRegistry(registry string) error {
+	if !registryRegexp.MatchString(strings.TrimSuffix(registry, "/")) {
+		return fmt.Errorf("%q is not a valid registry", registry)
+	}
+	return nil
+}
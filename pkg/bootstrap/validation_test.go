@@ -0,0 +1,122 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestValidateKlusterletConfig(t *testing.T) {
+	t.Setenv(constants.PodNamespaceEnvVarName, "multicluster-engine")
+
+	cases := []struct {
+		name           string
+		spec           klusterletconfigv1alpha1.KlusterletConfigSpec
+		expectedErrors []string
+	}{
+		{
+			name: "valid",
+			spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+				Registries: []klusterletconfigv1alpha1.Registries{
+					{Source: "quay.io/open-cluster-management/", Mirror: "registry.example.com:5000/ocm"},
+				},
+				NodePlacement: &operatorv1.NodePlacement{
+					NodeSelector: map[string]string{"kubernetes.io/os": "linux"},
+					Tolerations:  []corev1.Toleration{{Key: "foo", Operator: corev1.TolerationOpExists}},
+				},
+				AppliedManifestWorkEvictionGracePeriod: constants.AppliedManifestWorkEvictionGracePeriodInfinite,
+				MultipleHubsConfig: &klusterletconfigv1alpha1.MultipleHubsConfig{
+					BootstrapKubeConfigs: operatorv1.BootstrapKubeConfigs{
+						Type: operatorv1.LocalSecrets,
+						LocalSecrets: &operatorv1.LocalSecretsConfig{
+							KubeConfigSecrets: []operatorv1.KubeConfigSecret{{Name: "hub1"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid",
+			spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+				Registries: []klusterletconfigv1alpha1.Registries{
+					{Source: "https://quay.io", Mirror: "registry example com"},
+				},
+				NodePlacement: &operatorv1.NodePlacement{
+					Tolerations: []corev1.Toleration{{Key: "foo", Operator: "In"}},
+				},
+				AppliedManifestWorkEvictionGracePeriod: "1 day",
+				MultipleHubsConfig: &klusterletconfigv1alpha1.MultipleHubsConfig{
+					BootstrapKubeConfigs: operatorv1.BootstrapKubeConfigs{
+						Type: operatorv1.LocalSecrets,
+						LocalSecrets: &operatorv1.LocalSecretsConfig{
+							KubeConfigSecrets: []operatorv1.KubeConfigSecret{{Name: "hub1"}, {Name: "hub2"}, {Name: "hub3"}},
+						},
+					},
+				},
+			},
+			expectedErrors: []string{
+				"invalid registries mirror",
+				"invalid registries source",
+				"invalid tolerations",
+				"parse appliedManifestWorkEvictionGracePeriod 1 day failed",
+				"invalid local secret hub2: kubeconfig key not found in secret hub2",
+				"invalid local secret hub3",
+			},
+		},
+		{
+			name: "local secrets are not set",
+			spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+				MultipleHubsConfig: &klusterletconfigv1alpha1.MultipleHubsConfig{
+					BootstrapKubeConfigs: operatorv1.BootstrapKubeConfigs{
+						Type: operatorv1.LocalSecrets,
+					},
+				},
+			},
+			expectedErrors: []string{"local secrets should be set"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "hub1", Namespace: "multicluster-engine"},
+					Data:       map[string][]byte{"kubeconfig": []byte("kubeconfig")},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "hub2", Namespace: "multicluster-engine"},
+				},
+			)
+
+			err := ValidateKlusterletConfig(context.TODO(), kubeClient, &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec:       c.spec,
+			})
+			if len(c.expectedErrors) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors, but got nil")
+			}
+			for _, expected := range c.expectedErrors {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected error %q, but got %v", expected, err)
+				}
+			}
+		})
+	}
+}
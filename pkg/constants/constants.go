@@ -24,6 +24,13 @@ const (
 
 	// LabelAutoImportRestore is the label key of auto import secret used for backup restore case
 	LabelAutoImportRestore = "cluster.open-cluster-management.io/restore-auto-import-secret"
+
+	// AnnotationAutoImportAttempts is the annotation key of auto import secret used to record the number of the
+	// failed auto import attempts with the secret, so the retry budget is kept after the import controller restarts
+	AnnotationAutoImportAttempts = "import.open-cluster-management.io/auto-import-attempts"
+	// AnnotationAutoImportAttemptsSecretHash is the annotation key of auto import secret used to record the hash of
+	// the secret data which the failed attempts are counted against, the attempts are reset once the data is changed
+	AnnotationAutoImportAttemptsSecretHash = "import.open-cluster-management.io/auto-import-attempts-secret-hash"
 )
 
 const (
@@ -38,6 +45,94 @@ const (
 	// DefaultAutoImportStrategy is the default value used by the import-controller when no customized
 	// AutoImportStrategy is specified in the import-controller-config ConfigMap.
 	DefaultAutoImportStrategy = "ImportOnly"
+
+	// AnnotationAutoImportStrategy is the annotation key of managed cluster or KlusterletConfig used to override
+	// the AutoImportStrategy in the import-controller-config ConfigMap. The annotation on the managed cluster
+	// takes precedence over the one on the KlusterletConfig.
+	AnnotationAutoImportStrategy = "import.open-cluster-management.io/auto-import-strategy"
+
+	// AutoImportRefuseInsecureKey is the data key in the import-controller-config ConfigMap used to refuse
+	// the token based auto import when the certificate of the managed cluster kube apiserver cannot be
+	// verified. By default, the certificate is not verified if the auto-import-secret does not provide
+	// the ca.crt or the ca_fingerprint.
+	AutoImportRefuseInsecureKey = "autoImportRefuseInsecure"
+
+	// ClusterTakeoverPolicyKey is the data key in the import-controller-config ConfigMap used to specify how to
+	// import a managed cluster whose klusterlet is registered to another hub.
+	ClusterTakeoverPolicyKey = "clusterTakeoverPolicy"
+
+	// ClusterTakeoverPolicyRefuse refuses to import the managed cluster, the import fails.
+	ClusterTakeoverPolicyRefuse = "Refuse"
+	// ClusterTakeoverPolicyWarn imports the managed cluster and records a warning event.
+	ClusterTakeoverPolicyWarn = "Warn"
+	// ClusterTakeoverPolicyTakeover imports the managed cluster and records a normal event.
+	ClusterTakeoverPolicyTakeover = "Takeover"
+
+	// DefaultClusterTakeoverPolicy is the default value used by the import-controller when no customized
+	// cluster takeover policy is specified in the import-controller-config ConfigMap.
+	DefaultClusterTakeoverPolicy = ClusterTakeoverPolicyWarn
+
+	// AutoImportSyncWindowsKey is the data key in the import-controller-config ConfigMap used to specify the
+	// maintenance windows in which the importing resources are allowed to be synced to the imported managed
+	// clusters. The windows are separated by semicolons, each window is a cron expression with five fields
+	// (minute, hour, day of month, month and day of week, in UTC) followed by a duration, e.g. "0 2 * * 6 4h".
+	// The sync is not restricted if no window is specified.
+	AutoImportSyncWindowsKey = "autoImportSyncWindows"
+
+	// AnnotationAutoImportSyncWindows is the annotation key of managed cluster used to override the
+	// autoImportSyncWindows in the import-controller-config ConfigMap, the sync of the managed cluster is not
+	// restricted if the annotation value is empty.
+	AnnotationAutoImportSyncWindows = "import.open-cluster-management.io/auto-import-sync-windows"
+
+	// AutoImportMaxAttemptsKey is the data key in the import-controller-config ConfigMap used to specify the
+	// maximum number of the failed attempts to import a managed cluster with an auto-import-secret. Once the
+	// attempts are exhausted, the import is stopped until the auto-import-secret is changed.
+	AutoImportMaxAttemptsKey = "autoImportMaxAttempts"
+
+	// AutoImportBackoffBaseKey is the data key in the import-controller-config ConfigMap used to specify the
+	// delay before retrying the first failed auto import, the delay is doubled for each failed attempt.
+	AutoImportBackoffBaseKey = "autoImportBackoffBase"
+
+	// AutoImportBackoffMaxKey is the data key in the import-controller-config ConfigMap used to specify the
+	// maximum delay between the retries of the failed auto import.
+	AutoImportBackoffMaxKey = "autoImportBackoffMax"
+
+	DefaultAutoImportMaxAttempts = 10
+	DefaultAutoImportBackoffBase = 10 * time.Second
+	DefaultAutoImportBackoffMax  = 10 * time.Minute
+
+	// CSRApprovalPolicyKey is the data key in the import-controller-config ConfigMap used to specify the rules in
+	// YAML to approve or deny the CSRs of the managed clusters. The CSRs which are not matched by any rule are
+	// handled by the built-in approval conditions.
+	CSRApprovalPolicyKey = "csrApprovalPolicy"
+
+	// CARotationOverlapKey is the data key in the import-controller-config ConfigMap used to specify the overlap
+	// period of the CA rotation. When the CA bundle of the hub kube apiserver is changed, the certificates which
+	// are removed from the bundle are kept in the bootstrap kubeconfigs with the new ones in the overlap period.
+	// The CA rotation mode is disabled if it is not specified.
+	CARotationOverlapKey = "caRotationOverlap"
+
+	// CAExpiryWarningThresholdKey is the data key in the import-controller-config ConfigMap used to specify how
+	// long before all of the certificates in the CA bundle of the bootstrap kubeconfig expire to warn about it.
+	CAExpiryWarningThresholdKey = "caExpiryWarningThreshold"
+
+	DefaultCAExpiryWarningThreshold = 30 * 24 * time.Hour
+
+	// BootstrapTokenLifetimeKey is the data key in the import-controller-config ConfigMap used to specify the
+	// lifetime of the tokens in the bootstrap kubeconfigs, it is at least 10 minutes.
+	BootstrapTokenLifetimeKey = "bootstrapTokenLifetime"
+
+	// BootstrapTokenRefreshRatioKey is the data key in the import-controller-config ConfigMap used to specify the
+	// ratio of the token lifetime, the token in the bootstrap kubeconfig is refreshed when the ratio of its
+	// lifetime remains. It is greater than 0 and less than 1.
+	BootstrapTokenRefreshRatioKey = "bootstrapTokenRefreshRatio"
+
+	// BootstrapTokenEphemeralKey is the data key in the import-controller-config ConfigMap used to enable the
+	// ephemeral mode of the bootstrap tokens, the token is revoked once the managed cluster is imported.
+	BootstrapTokenEphemeralKey = "bootstrapTokenEphemeral"
+
+	MinSecretTokenLifetime         = 10 * time.Minute
+	DefaultSecretTokenRefreshRatio = 0.2
 )
 
 /* #nosec */
@@ -82,7 +177,33 @@ const (
 	ImportSecretTokenExpiration        = "expiration"
 	DefaultSecretTokenExpirationSecond = 360 * 24 * 60 * 60 // 360 days
 	ImportSecretTokenCreation          = "creation"
-	DefaultSecretTokenRefreshThreshold = 360 * 24 * time.Hour / 5 // 72 days
+
+	// ImportSecretTokenRevocation is the data key of the import secret to record when the token in the bootstrap
+	// kubeconfig is revoked in the ephemeral mode, a new token is requested once the cluster is imported again.
+	ImportSecretTokenRevocation = "revocation"
+
+	// ImportSecretCARotationStartedAt is the data key of the import secret to record when the CA rotation of the
+	// bootstrap kubeconfig is started, it only exists in the overlap period of the CA rotation.
+	ImportSecretCARotationStartedAt = "ca-rotation-started-at"
+
+	// AnnotationBootstrapTokenCreation and AnnotationBootstrapTokenExpiration are the annotation keys of the import
+	// secret, the values are the creation and expiration time of the token in the bootstrap kubeconfig in RFC3339.
+	// They are not set if the token does not expire.
+	AnnotationBootstrapTokenCreation   = "import.open-cluster-management.io/bootstrap-token-creation"
+	AnnotationBootstrapTokenExpiration = "import.open-cluster-management.io/bootstrap-token-expiration"
+
+	// AnnotationBootstrapTokenRevocation is the annotation key of the import secret, the value is the time in
+	// RFC3339 when the token in the bootstrap kubeconfig is revoked in the ephemeral mode.
+	AnnotationBootstrapTokenRevocation = "import.open-cluster-management.io/bootstrap-token-revocation"
+
+	// AnnotationBootstrapCAExpiration is the annotation key of the import secret, the value is the time in RFC3339
+	// when all of the certificates in the CA bundles of the bootstrap kubeconfig expire.
+	AnnotationBootstrapCAExpiration = "import.open-cluster-management.io/bootstrap-ca-expiration"
+
+	// AnnotationBootstrapNextRotation is the annotation key of the import secret, the value is the time in RFC3339
+	// when the bootstrap kubeconfig is planned to be regenerated, i.e. the token is refreshed or the removed
+	// certificates are dropped after the overlap period of the CA rotation.
+	AnnotationBootstrapNextRotation = "import.open-cluster-management.io/bootstrap-next-rotation"
 )
 
 // NOSONAR-END
@@ -138,6 +259,64 @@ const (
 	ConditionReasonManagedClusterForceDetaching = "ManagedClusterForceDetaching"
 )
 
+const (
+	// AnnotationImportPreflight is the annotation key of managed cluster used to run the import preflight checks
+	// with the auto-import-secret instead of importing the managed cluster. The managed cluster will be imported
+	// after this annotation is removed.
+	AnnotationImportPreflight = "import.open-cluster-management.io/preflight"
+
+	// ConditionManagedClusterImportPreflight is the condition type of managed cluster to indicate whether the
+	// import preflight checks are passed
+	ConditionManagedClusterImportPreflight = "ManagedClusterImportPreflight"
+
+	ConditionReasonManagedClusterImportPreflightSucceeded = "ManagedClusterImportPreflightSucceeded"
+	ConditionReasonManagedClusterImportPreflightFailed    = "ManagedClusterImportPreflightFailed"
+
+	EventReasonManagedClusterImportPreflightSucceeded = "PreflightSucceeded"
+	EventReasonManagedClusterImportPreflightFailed    = "PreflightFailed"
+
+	// ConditionManagedClusterImportSyncPending is the condition type of managed cluster to indicate whether the
+	// sync of the importing resources is deferred to the next maintenance window
+	ConditionManagedClusterImportSyncPending = "ManagedClusterImportSyncPending"
+
+	ConditionReasonManagedClusterImportSyncPending    = "ManagedClusterImportSyncPending"
+	ConditionReasonManagedClusterImportSyncWindowOpen = "ManagedClusterImportSyncWindowOpen"
+
+	// ConditionManagedClusterBootstrapCAExpiring is the condition type of managed cluster to indicate whether all
+	// of the certificates in the CA bundle of its bootstrap kubeconfig are about to expire
+	ConditionManagedClusterBootstrapCAExpiring = "ManagedClusterBootstrapCAExpiring"
+
+	ConditionReasonManagedClusterBootstrapCAExpiring = "ManagedClusterBootstrapCAExpiring"
+	ConditionReasonManagedClusterBootstrapCAValid    = "ManagedClusterBootstrapCAValid"
+
+	EventReasonManagedClusterBootstrapCAExpiring = "BootstrapCAExpiring"
+	EventReasonManagedClusterBootstrapCARenewed  = "BootstrapCARenewed"
+
+	// ConditionManagedClusterBootstrapCredentialsValid is the condition type of managed cluster to report the
+	// expiration of the token and the CA bundle in its bootstrap kubeconfig, and when they are rotated next time
+	ConditionManagedClusterBootstrapCredentialsValid = "ManagedClusterBootstrapCredentialsValid"
+
+	ConditionReasonManagedClusterBootstrapCredentialsValid   = "ManagedClusterBootstrapCredentialsValid"
+	ConditionReasonManagedClusterBootstrapCredentialsExpired = "ManagedClusterBootstrapCredentialsExpired"
+
+	// HubKubeConfigSecretName is the name of the secret in the klusterlet agent namespace that contains the
+	// kubeconfig used by the agent to connect to its hub
+	HubKubeConfigSecretName = "hub-kubeconfig-secret" // #nosec G101
+)
+
+const (
+	// ImportHistoryConfigMapName is the name of the ConfigMap in the managed cluster namespace used to record the
+	// import attempts of the managed cluster
+	ImportHistoryConfigMapName = "import-history"
+
+	// ImportHistoryKey is the data key of the import history ConfigMap, the value is a JSON list of the import
+	// attempts, the latest attempt is the last one
+	ImportHistoryKey = "history"
+
+	// ImportHistoryLimit is the maximum number of the import attempts kept in the import history ConfigMap
+	ImportHistoryLimit = 20
+)
+
 const (
 	EventReasonManagedClusterImportFailed = "Failed"
 	EventReasonManagedClusterImported     = "Imported"
@@ -146,6 +325,13 @@ const (
 
 	EventReasonManagedClusterDetaching      = "Detaching"
 	EventReasonManagedClusterForceDetaching = "ForceDetaching"
+
+	EventReasonManagedClusterRegisteredToAnotherHub = "RegisteredToAnotherHub"
+	EventActionTakeover                             = "Takeover"
+	EventActionTakeoverRefused                      = "TakeoverRefused"
+
+	EventReasonManagedClusterCSRDenied = "CSRDenied"
+	EventActionDenyCSR                 = "DenyCSR"
 )
 
 /* #nosec */
@@ -157,6 +343,17 @@ const (
 	AutoImportSecretKubeServerKey string            = "server"
 	AutoImportSecretKubeTokenKey  string            = "token"
 
+	// AutoImportSecretCAKey is the key of the auto-import-secret used to provide the CA bundle to verify the
+	// certificate of the managed cluster kube apiserver for the token based auto-import-secrets.
+	AutoImportSecretCAKey string = "ca.crt"
+	// AutoImportSecretCAFingerprintKey is the key of the auto-import-secret used to pin the SHA-256 fingerprint
+	// of a certificate in the certificate chain served by the managed cluster kube apiserver, the pinned
+	// certificate and its issuers are used as the CA bundle to verify the kube apiserver.
+	AutoImportSecretCAFingerprintKey string = "ca_fingerprint"
+	// AutoImportSecretRetryKey is the key of the auto-import-secret used to override the number of the retries
+	// of a failed auto import, 0 means the import is tried only once.
+	AutoImportSecretRetryKey string = "autoImportRetry"
+
 	AutoImportSecretRosaConfig                corev1.SecretType = "auto-import/rosa"
 	AutoImportSecretRosaConfigAPIURLKey       string            = "api_url"
 	AutoImportSecretRosaConfigAPITokenKey     string            = "api_token"
@@ -164,13 +361,23 @@ const (
 	AutoImportSecretRosaConfigClusterIDKey    string            = "cluster_id"
 	AutoImportSecretRosaConfigClientIDKey     string            = "client_id"
 	AutoImportSecretRosaConfigClientSecretKey string            = "client_secret"
-	AutoImportSecretRosaConfigRetryTimesKey   string            = "retry_times"
+	AutoImportSecretRosaConfigRetryTimesKey   string            = "retry_times" // Deprecated: use autoImportRetry
 	AutoImportSecretRosaConfigAuthMethodKey   string            = "auth_method"
 	// The definitions of the auth methods follow the same approach as in discovery:
 	// https://github.com/stolostron/discovery/blob/13cb209687bf963b58232eb96b25cf0d20d111ec/controllers/discoveryconfig_controller.go#L251
 	// TODO: @xuezhaojun, in long term, the offline-token should be removed, and only use service-account, see more details in Jira 10404.
 	AutoImportSecretRosaConfigAuthMethodOfflineToken   string = "offline-token"
 	AutoImportSecretRosaConfigAuthMethodServiceAccount string = "service-account"
+
+	// AutoImportSecretOIDCConfig is the auto-import-secret type for the clusters whose api server accepts the
+	// tokens issued by an OIDC provider, e.g. EKS, GKE and AKS. The import controller requests a short-lived
+	// token with the client credentials grant from the token endpoint and uses it to import the cluster.
+	AutoImportSecretOIDCConfig                corev1.SecretType = "auto-import/oidc"
+	AutoImportSecretOIDCConfigTokenURLKey     string            = "token_url"
+	AutoImportSecretOIDCConfigClientIDKey     string            = "client_id"
+	AutoImportSecretOIDCConfigClientSecretKey string            = "client_secret"
+	AutoImportSecretOIDCConfigScopeKey        string            = "scope"
+	AutoImportSecretOIDCConfigAudienceKey     string            = "audience"
 )
 
 const (
@@ -187,6 +394,37 @@ const (
 
 const (
 	DefaultBootstrapHubKubeConfigSecretName = "bootstrap-hub-kubeconfig" // #nosec G101
+
+	// AnnotationHubKubeAPIServerAdditionalURLs is the annotation key of KlusterletConfig used to specify an
+	// ordered, comma separated list of the additional hub kube apiserver URLs. The agent connects to the hub
+	// with the next URL when the hub cannot be reached with the current one.
+	AnnotationHubKubeAPIServerAdditionalURLs = "import.open-cluster-management.io/hub-kube-apiserver-additional-urls"
+
+	// AnnotationBootstrapTokenLifetime, AnnotationBootstrapTokenRefreshRatio and AnnotationBootstrapTokenEphemeral
+	// are the annotation keys of KlusterletConfig used to override the bootstrapTokenLifetime,
+	// bootstrapTokenRefreshRatio and bootstrapTokenEphemeral of the import-controller-config ConfigMap for the
+	// managed clusters which use the KlusterletConfig.
+	AnnotationBootstrapTokenLifetime     = "import.open-cluster-management.io/bootstrap-token-lifetime"
+	AnnotationBootstrapTokenRefreshRatio = "import.open-cluster-management.io/bootstrap-token-refresh-ratio"
+	AnnotationBootstrapTokenEphemeral    = "import.open-cluster-management.io/bootstrap-token-ephemeral"
+
+	// AnnotationKlusterletConfigObservedGeneration and AnnotationKlusterletConfigValidationErrors are the annotation
+	// keys of KlusterletConfig which report its status, because the status of KlusterletConfig has no fields.
+	AnnotationKlusterletConfigObservedGeneration = "import.open-cluster-management.io/observed-generation"
+	AnnotationKlusterletConfigValidationErrors   = "import.open-cluster-management.io/validation-errors"
+
+	// AnnotationKlusterletConfigManagedClusterSelector and AnnotationKlusterletConfigManagedClusterSet are the
+	// annotation keys of KlusterletConfig to select the managed clusters which use the KlusterletConfig without the
+	// klusterlet-config annotation. The selector is a label selector, e.g. "env=prod,region in (us-east,us-west)",
+	// and the managed cluster set selects the managed clusters in the set. Both of them are required to match if
+	// both are set.
+	AnnotationKlusterletConfigManagedClusterSelector = "import.open-cluster-management.io/managed-cluster-selector"
+	AnnotationKlusterletConfigManagedClusterSet      = "import.open-cluster-management.io/managed-cluster-set"
+
+	// AnnotationKlusterletConfigPriority is the annotation key of KlusterletConfig to specify the priority of the
+	// KlusterletConfig selected by the managed cluster selector, the KlusterletConfig with higher priority
+	// overrides the one with lower priority. The default priority is 0.
+	AnnotationKlusterletConfigPriority = "import.open-cluster-management.io/priority"
 )
 
 const (
@@ -196,3 +434,26 @@ const (
 	// If a managed cluster is from the agent-registration, the username of the CSR will be this
 	AgentRegistrationBootstrapUser = "system:serviceaccount:multicluster-engine:agent-registration-bootstrap"
 )
+
+/* #nosec */
+const (
+	// RegistrationTokenSecretType is the type of the secrets which record the agent registration tokens. A
+	// registration token is bound to its secret, so the token is revoked once the secret is deleted.
+	RegistrationTokenSecretType corev1.SecretType = "import.open-cluster-management.io/registration-token"
+
+	// LabelRegistrationTokenID is the label key of the registration token secret, the value is the ID (JTI) of
+	// the token.
+	LabelRegistrationTokenID = "import.open-cluster-management.io/registration-token-id"
+
+	// AnnotationRegistrationTokenCluster is the annotation key of the registration token secret, the value is
+	// the name of the cluster which the token is issued for.
+	AnnotationRegistrationTokenCluster = "import.open-cluster-management.io/registration-token-cluster"
+
+	// AnnotationRegistrationTokenExpiration is the annotation key of the registration token secret, the value is
+	// the expiration time of the token in RFC3339.
+	AnnotationRegistrationTokenExpiration = "import.open-cluster-management.io/registration-token-expiration"
+
+	// AnnotationRegistrationTokenUsedBy is the annotation key of the registration token secret, the value is the
+	// name of the CSR which is approved with the token. A token is only used once.
+	AnnotationRegistrationTokenUsedBy = "import.open-cluster-management.io/registration-token-used-by"
+)
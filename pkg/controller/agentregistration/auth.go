@@ -0,0 +1,306 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package agentregistration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+const (
+	// authCacheSize is the maximum number of the cached TokenReview and SubjectAccessReview results
+	authCacheSize = 4096
+	// authAllowedCacheTTL is the TTL of the cached results of the authenticated and authorized tokens
+	authAllowedCacheTTL = 30 * time.Second
+	// authDeniedCacheTTL is the TTL of the cached results of the unauthenticated or unauthorized tokens
+	authDeniedCacheTTL = 10 * time.Second
+
+	// rateLimiterCacheSize is the maximum number of the users whose rate limiters are kept
+	rateLimiterCacheSize = 4096
+	// rateLimiterTTL is the time after which the rate limiter of a user is recreated
+	rateLimiterTTL = 10 * time.Minute
+
+	// DefaultRateLimitQPS is the default number of the requests per second allowed for a user
+	DefaultRateLimitQPS = 10
+	// DefaultRateLimitBurst is the default number of the requests allowed in a burst for a user
+	DefaultRateLimitBurst = 50
+)
+
+// authResult is the result of the TokenReview and SubjectAccessReview of a token
+type authResult struct {
+	user          authenticationv1.UserInfo
+	authenticated bool
+	allowed       bool
+	// message is the reason of the denial
+	message string
+}
+
+// authenticator authenticates and authorizes the requests of the agent registration server with the TokenReview and
+// the SubjectAccessReview, the results are cached for a short TTL so a burst of requests with the same token does not
+// overwhelm the kube apiserver. The authenticated requests are rate limited by user and audited.
+type authenticator struct {
+	clientHolder *helpers.ClientHolder
+	results      *cache.LRUExpireCache
+
+	limitersLock sync.Mutex
+	limiters     *cache.LRUExpireCache
+	qps          rate.Limit
+	burst        int
+}
+
+func newAuthenticator(clientHolder *helpers.ClientHolder, qps float64, burst int) *authenticator {
+	return &authenticator{
+		clientHolder: clientHolder,
+		results:      cache.NewLRUExpireCache(authCacheSize),
+		limiters:     cache.NewLRUExpireCache(rateLimiterCacheSize),
+		qps:          rate.Limit(qps),
+		burst:        burst,
+	}
+}
+
+// middleware returns a handler which serves the request with the next handler when the request is authorized and
+// not throttled, and writes an audit log of the request.
+func (a *authenticator) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		record := &auditRecord{}
+		sw := &statusRecordingWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			klog.InfoS("Agent registration request",
+				"user", record.user.Username,
+				"groups", record.user.Groups,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"clusters", record.clusters,
+				"klusterletConfigs", record.klusterletConfigs,
+				"status", sw.status,
+				"remoteAddr", r.RemoteAddr,
+				"latency", time.Since(start))
+		}()
+
+		// Get the Authorization header value
+		authHeader := r.Header.Get("Authorization")
+
+		// Check if the header value starts with "Bearer "
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			http.Error(sw, "Invalid Authorization header", http.StatusUnauthorized)
+			return
+		}
+
+		// Extract the token from the header value
+		token := strings.TrimPrefix(authHeader, "Bearer ")
+
+		result, err := a.authenticate(r.Context(), token)
+		if err != nil {
+			http.Error(sw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		record.user = result.user
+		if !result.authenticated || !result.allowed {
+			http.Error(sw, result.message, http.StatusUnauthorized)
+			return
+		}
+
+		if !a.limiter(result.user.Username).Allow() {
+			sw.Header().Set("Retry-After", "1")
+			http.Error(sw, fmt.Sprintf("too many requests from the user %s", result.user.Username),
+				http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), auditRecordKey{}, record)))
+	})
+}
+
+// authenticate returns the cached result of the token if it exists, otherwise it reviews the token with the
+// TokenReview and the SubjectAccessReview and caches the result. The errors are not cached.
+func (a *authenticator) authenticate(ctx context.Context, token string) (*authResult, error) {
+	// only keep the hash of the token in memory
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:])
+	if cached, ok := a.results.Get(key); ok {
+		return cached.(*authResult), nil
+	}
+
+	result, err := a.review(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := authDeniedCacheTTL
+	if result.authenticated && result.allowed {
+		ttl = authAllowedCacheTTL
+	}
+	a.results.Add(key, result, ttl)
+	return result, nil
+}
+
+func (a *authenticator) review(ctx context.Context, token string) (*authResult, error) {
+	// Authentication
+	trresult, err := a.clientHolder.KubeClient.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token: token,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("create TR failed %v", err.Error())
+	}
+	if !trresult.Status.Authenticated {
+		return &authResult{
+			message: fmt.Sprintf("authentication failed, response:%v, error:%v", trresult.Status, trresult.Status.Error),
+		}, nil
+	}
+
+	// Authorization
+	userInfo := trresult.Status.User
+	sarrequest := newSubjectAccessReview(userInfo)
+	sarrequest.Spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{
+		Path: "/agent-registration/*",
+		Verb: "get",
+	}
+	sarresult, err := a.clientHolder.KubeClient.AuthorizationV1().SubjectAccessReviews().Create(ctx, sarrequest, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("create SAR failed %v, user: %v", err.Error(), userInfo)
+	}
+	if !sarresult.Status.Allowed {
+		return &authResult{
+			user:          userInfo,
+			authenticated: true,
+			message:       fmt.Sprintf("authorization failed, response:%v, user:%v", sarresult.Status, userInfo),
+		}, nil
+	}
+
+	return &authResult{user: userInfo, authenticated: true, allowed: true}, nil
+}
+
+// limiter returns the rate limiter of the user
+func (a *authenticator) limiter(username string) *rate.Limiter {
+	a.limitersLock.Lock()
+	defer a.limitersLock.Unlock()
+
+	if limiter, ok := a.limiters.Get(username); ok {
+		return limiter.(*rate.Limiter)
+	}
+
+	limiter := rate.NewLimiter(a.qps, a.burst)
+	a.limiters.Add(username, limiter, rateLimiterTTL)
+	return limiter
+}
+
+// newSubjectAccessReview returns a SubjectAccessReview of the user without the attributes
+func newSubjectAccessReview(userInfo authenticationv1.UserInfo) *authorizationv1.SubjectAccessReview {
+	extra := make(map[string]authorizationv1.ExtraValue)
+	for k, v := range userInfo.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	return &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   userInfo.Username,
+			Groups: userInfo.Groups,
+			UID:    userInfo.UID,
+			Extra:  extra,
+		},
+	}
+}
+
+// authorizeManagedCluster checks that the user of the request is allowed to get the managed cluster with a
+// SubjectAccessReview, and that the managed cluster exists. The user is authorized before the managed cluster is
+// looked up, so an unauthorized user cannot find out which managed clusters exist. If the check fails, the error
+// response is written and false is returned.
+func authorizeManagedCluster(w http.ResponseWriter, r *http.Request, clientHolder *helpers.ClientHolder,
+	clusterName string) bool {
+	record, ok := r.Context().Value(auditRecordKey{}).(*auditRecord)
+	if !ok {
+		http.Error(w, "the user of the request is unknown", http.StatusUnauthorized)
+		return false
+	}
+
+	sarrequest := newSubjectAccessReview(record.user)
+	sarrequest.Spec.ResourceAttributes = &authorizationv1.ResourceAttributes{
+		Group:    clusterv1.GroupName,
+		Resource: "managedclusters",
+		Name:     clusterName,
+		Verb:     "get",
+	}
+	sarresult, err := clientHolder.KubeClient.AuthorizationV1().SubjectAccessReviews().Create(
+		r.Context(), sarrequest, metav1.CreateOptions{})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("create SAR failed %v", err), http.StatusInternalServerError)
+		return false
+	}
+	if !sarresult.Status.Allowed {
+		http.Error(w, fmt.Sprintf("the user %s is not allowed to get the managed cluster %s",
+			record.user.Username, clusterName), http.StatusForbidden)
+		return false
+	}
+
+	err = clientHolder.RuntimeClient.Get(r.Context(), types.NamespacedName{Name: clusterName}, &clusterv1.ManagedCluster{})
+	if errors.IsNotFound(err) {
+		http.Error(w, fmt.Sprintf("the managed cluster %s is not found", clusterName), http.StatusNotFound)
+		return false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// auditRecordKey is the context key of the audit record of a request
+type auditRecordKey struct{}
+
+// auditRecord is the audit record of an agent registration request, the handlers add the clusters and the
+// KlusterletConfigs which are requested into it.
+type auditRecord struct {
+	user              authenticationv1.UserInfo
+	clusters          []string
+	klusterletConfigs []string
+}
+
+// auditCluster records that the manifests of the cluster are requested with the KlusterletConfig
+func auditCluster(r *http.Request, clusterName, klusterletConfigName string) {
+	record, ok := r.Context().Value(auditRecordKey{}).(*auditRecord)
+	if !ok {
+		return
+	}
+	record.clusters = append(record.clusters, clusterName)
+	record.klusterletConfigs = append(record.klusterletConfigs, klusterletConfigName)
+}
+
+// statusRecordingWriter records the status code of the response
+type statusRecordingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusRecordingWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecordingWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
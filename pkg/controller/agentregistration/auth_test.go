@@ -0,0 +1,225 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package agentregistration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+// newTestAuthenticator returns an authenticator whose kube client authenticates the token "valid" as the user
+// "alice", and authorizes the user "alice" only, the user "alice" is not allowed to get the managed cluster
+// "forbidden". The numbers of the reviews are counted.
+func newTestAuthenticator(burst int) (*authenticator, *int, *int) {
+	tokenReviews, subjectAccessReviews := 0, 0
+	kubeClient := kubefake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "tokenreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		tokenReviews++
+		tr := action.(clienttesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		switch tr.Spec.Token {
+		case "valid":
+			tr.Status = authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "alice"},
+			}
+		case "forbidden":
+			tr.Status = authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "bob"},
+			}
+		}
+		return true, tr, nil
+	})
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		subjectAccessReviews++
+		sar := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		sar.Status.Allowed = sar.Spec.User == "alice"
+		if attrs := sar.Spec.ResourceAttributes; attrs != nil {
+			sar.Status.Allowed = sar.Status.Allowed && attrs.Group == clusterv1.GroupName &&
+				attrs.Resource == "managedclusters" && attrs.Verb == "get" && attrs.Name != "forbidden"
+		}
+		return true, sar, nil
+	})
+
+	return newAuthenticator(&helpers.ClientHolder{KubeClient: kubeClient}, 1, burst), &tokenReviews, &subjectAccessReviews
+}
+
+func serveTestRequest(handler http.Handler, token string) int {
+	req := httptest.NewRequest(http.MethodGet, "/agent-registration/manifests/cluster1", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAuthenticatorMiddleware(t *testing.T) {
+	cases := []struct {
+		name                         string
+		tokens                       []string
+		burst                        int
+		expectedStatuses             []int
+		expectedTokenReviews         int
+		expectedSubjectAccessReviews int
+	}{
+		{
+			name:             "no token",
+			tokens:           []string{""},
+			burst:            10,
+			expectedStatuses: []int{http.StatusUnauthorized},
+		},
+		{
+			name:                 "unauthenticated token is cached",
+			tokens:               []string{"invalid", "invalid"},
+			burst:                10,
+			expectedStatuses:     []int{http.StatusUnauthorized, http.StatusUnauthorized},
+			expectedTokenReviews: 1,
+		},
+		{
+			name:                         "unauthorized token is cached",
+			tokens:                       []string{"forbidden", "forbidden"},
+			burst:                        10,
+			expectedStatuses:             []int{http.StatusUnauthorized, http.StatusUnauthorized},
+			expectedTokenReviews:         1,
+			expectedSubjectAccessReviews: 1,
+		},
+		{
+			name:                         "authorized token is cached",
+			tokens:                       []string{"valid", "valid", "valid"},
+			burst:                        10,
+			expectedStatuses:             []int{http.StatusOK, http.StatusOK, http.StatusOK},
+			expectedTokenReviews:         1,
+			expectedSubjectAccessReviews: 1,
+		},
+		{
+			name:                         "requests are rate limited",
+			tokens:                       []string{"valid", "valid", "valid"},
+			burst:                        2,
+			expectedStatuses:             []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+			expectedTokenReviews:         1,
+			expectedSubjectAccessReviews: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			auth, tokenReviews, subjectAccessReviews := newTestAuthenticator(c.burst)
+			handler := auth.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			statuses := []int{}
+			for _, token := range c.tokens {
+				statuses = append(statuses, serveTestRequest(handler, token))
+			}
+
+			if !reflect.DeepEqual(statuses, c.expectedStatuses) {
+				t.Errorf("expected statuses %v, but got %v", c.expectedStatuses, statuses)
+			}
+			if *tokenReviews != c.expectedTokenReviews {
+				t.Errorf("expected %d token reviews, but got %d", c.expectedTokenReviews, *tokenReviews)
+			}
+			if *subjectAccessReviews != c.expectedSubjectAccessReviews {
+				t.Errorf("expected %d subject access reviews, but got %d", c.expectedSubjectAccessReviews,
+					*subjectAccessReviews)
+			}
+		})
+	}
+}
+
+func TestAuditCluster(t *testing.T) {
+	auth, _, _ := newTestAuthenticator(10)
+	var record *auditRecord
+	handler := auth.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auditCluster(r, "cluster1", "default")
+		auditCluster(r, "cluster2", "")
+		record = r.Context().Value(auditRecordKey{}).(*auditRecord)
+	}))
+
+	if status := serveTestRequest(handler, "valid"); status != http.StatusOK {
+		t.Fatalf("expected status %d, but got %d", http.StatusOK, status)
+	}
+	if record.user.Username != "alice" {
+		t.Errorf("expected the user alice, but got %q", record.user.Username)
+	}
+	if !reflect.DeepEqual(record.clusters, []string{"cluster1", "cluster2"}) {
+		t.Errorf("unexpected clusters %v", record.clusters)
+	}
+	if !reflect.DeepEqual(record.klusterletConfigs, []string{"default", ""}) {
+		t.Errorf("unexpected klusterletconfigs %v", record.klusterletConfigs)
+	}
+}
+
+func TestAuthorizeManagedCluster(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clusterv1.Install(scheme); err != nil {
+		t.Fatalf("failed to install the scheme: %v", err)
+	}
+
+	cases := []struct {
+		name           string
+		clusterName    string
+		withoutAuth    bool
+		expectedStatus int
+	}{
+		{
+			name:           "import history of the cluster",
+			clusterName:    "cluster1",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "no user",
+			clusterName:    "cluster1",
+			withoutAuth:    true,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "not allowed",
+			clusterName:    "forbidden",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "cluster not found",
+			clusterName:    "cluster2",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			auth, _, _ := newTestAuthenticator(10)
+			auth.clientHolder.RuntimeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}},
+				&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "forbidden"}},
+			).Build()
+			handler := importHistoryHandler(auth.clientHolder)
+
+			req := httptest.NewRequest(http.MethodGet, "/agent-registration/import-history/"+c.clusterName, nil)
+			req.Header.Set("Authorization", "Bearer valid")
+			rec := httptest.NewRecorder()
+			if c.withoutAuth {
+				handler.ServeHTTP(rec, req)
+			} else {
+				auth.middleware(handler).ServeHTTP(rec, req)
+			}
+
+			if rec.Code != c.expectedStatus {
+				t.Errorf("expected status %d, but got %d: %s", c.expectedStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package agentregistration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	listerklusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
+	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// bulkManifestsMaxClusters is the maximum number of the clusters in a bulk manifests request
+	bulkManifestsMaxClusters = 500
+	// bulkManifestsMaxRequestBytes is the maximum size of the body of a bulk manifests request
+	bulkManifestsMaxRequestBytes = 1 << 20
+
+	bulkManifestsFormatTar = "tar"
+	bulkManifestsFormatZip = "zip"
+)
+
+// BulkManifestsRequest is the request body of the bulk manifests endpoint
+type BulkManifestsRequest struct {
+	// Clusters are the clusters whose klusterlet manifests are generated
+	Clusters []BulkManifestsCluster `json:"clusters"`
+	// KlusterletConfig is the default KlusterletConfig of the clusters, it is optional
+	KlusterletConfig string `json:"klusterletconfig,omitempty"`
+}
+
+// BulkManifestsCluster is a cluster in the bulk manifests request
+type BulkManifestsCluster struct {
+	// Name is the name of the cluster
+	Name string `json:"name"`
+	// KlusterletConfig is the KlusterletConfig of the cluster, it overrides the default KlusterletConfig of
+	// the request
+	KlusterletConfig string `json:"klusterletconfig,omitempty"`
+}
+
+// bulkManifestsHandler generates the klusterlet manifests and CRDs for a list of clusters and returns them as
+// a gzipped tar or a zip archive, the files of a cluster are put in the directory named with the cluster name.
+func bulkManifestsHandler(ctx context.Context, clientHolder *helpers.ClientHolder,
+	klusterletconfigLister listerklusterletconfigv1alpha1.KlusterletConfigLister) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, fmt.Sprintf("method %s is not allowed", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = bulkManifestsFormatTar
+		}
+		if format != bulkManifestsFormatTar && format != bulkManifestsFormatZip {
+			http.Error(w, fmt.Sprintf("unsupported format %q, it should be %s or %s",
+				format, bulkManifestsFormatTar, bulkManifestsFormatZip), http.StatusBadRequest)
+			return
+		}
+
+		request := &BulkManifestsRequest{}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, bulkManifestsMaxRequestBytes)).Decode(request); err != nil {
+			http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if err := validateBulkManifestsRequest(request, r.URL.Query().Get("duration")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, cluster := range request.Clusters {
+			auditCluster(r, cluster.Name, klusterletConfigOfCluster(request, cluster))
+		}
+
+		files := []bootstrap.ArchiveFile{}
+		// the issued tokens are revoked if the archive is not returned
+		tokenSecretNames := []string{}
+		for _, cluster := range request.Clusters {
+			klusterletconfigName := klusterletConfigOfCluster(request, cluster)
+
+			// each cluster has its own registration token, which is only allowed to register the cluster once
+			token, tokenSecretName, err := issueRegistrationToken(ctx, clientHolder, cluster.Name,
+				r.URL.Query().Get("duration"))
+			if err != nil {
+				revokeRegistrationTokens(ctx, clientHolder, tokenSecretNames...)
+				http.Error(w, err.Error(), httpStatusOfRegistrationTokenError(err))
+				return
+			}
+			tokenSecretNames = append(tokenSecretNames, tokenSecretName)
+
+			config, err := newKlusterletManifestsConfig(r.Context(), clientHolder, klusterletconfigLister,
+				cluster.Name, klusterletconfigName, token)
+			if err != nil {
+				revokeRegistrationTokens(ctx, clientHolder, tokenSecretNames...)
+				http.Error(w, fmt.Sprintf("failed to generate the manifests of the cluster %s: %v", cluster.Name, err),
+					http.StatusInternalServerError)
+				return
+			}
+			content, crdContent, err := config.Generate(r.Context(), clientHolder)
+			if err != nil {
+				revokeRegistrationTokens(ctx, clientHolder, tokenSecretNames...)
+				http.Error(w, fmt.Sprintf("failed to generate the manifests of the cluster %s: %v", cluster.Name, err),
+					http.StatusInternalServerError)
+				return
+			}
+
+			files = append(files,
+				bootstrap.ArchiveFile{Name: path.Join(cluster.Name, "crds.yaml"), Content: crdContent},
+				bootstrap.ArchiveFile{Name: path.Join(cluster.Name, "import.yaml"), Content: content},
+			)
+		}
+
+		var archive []byte
+		var err error
+		contentType, fileName := "application/gzip", "manifests.tar.gz"
+		if format == bulkManifestsFormatZip {
+			contentType, fileName = "application/zip", "manifests.zip"
+			archive, err = bootstrap.ZipArchive(files)
+		} else {
+			archive, err = bootstrap.TarGzArchive(files)
+		}
+		if err != nil {
+			revokeRegistrationTokens(ctx, clientHolder, tokenSecretNames...)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(archive); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// klusterletConfigOfCluster returns the KlusterletConfig of the cluster, it is the default KlusterletConfig of the
+// request if the cluster does not specify one
+func klusterletConfigOfCluster(request *BulkManifestsRequest, cluster BulkManifestsCluster) string {
+	if cluster.KlusterletConfig != "" {
+		return cluster.KlusterletConfig
+	}
+	return request.KlusterletConfig
+}
+
+// validateBulkManifestsRequest validates the request and the duration of the registration tokens before any token
+// is issued
+func validateBulkManifestsRequest(request *BulkManifestsRequest, durationStr string) error {
+	if durationStr != "" {
+		if _, err := time.ParseDuration(durationStr); err != nil {
+			return fmt.Errorf("invalid duration %q: %v", durationStr, err)
+		}
+	}
+	if len(request.Clusters) == 0 {
+		return fmt.Errorf("at least one cluster is required")
+	}
+	if len(request.Clusters) > bulkManifestsMaxClusters {
+		return fmt.Errorf("too many clusters, the maximum is %d", bulkManifestsMaxClusters)
+	}
+
+	names := sets.New[string]()
+	for _, cluster := range request.Clusters {
+		// the cluster name is used as the directory name in the archive
+		if errs := validation.IsDNS1123Label(cluster.Name); len(errs) > 0 {
+			return fmt.Errorf("invalid cluster name %q: %v", cluster.Name, errs)
+		}
+		if names.Has(cluster.Name) {
+			return fmt.Errorf("duplicated cluster name %q", cluster.Name)
+		}
+		names.Insert(cluster.Name)
+	}
+	return nil
+}
@@ -0,0 +1,351 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package agentregistration
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	ocinfrav1 "github.com/openshift/api/config/v1"
+	listerklusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	authv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+)
+
+func TestValidateBulkManifestsRequest(t *testing.T) {
+	tooManyClusters := []BulkManifestsCluster{}
+	for i := 0; i <= bulkManifestsMaxClusters; i++ {
+		tooManyClusters = append(tooManyClusters, BulkManifestsCluster{Name: fmt.Sprintf("cluster%d", i)})
+	}
+
+	cases := []struct {
+		name          string
+		request       *BulkManifestsRequest
+		duration      string
+		expectedError string
+	}{
+		{
+			name:          "no clusters",
+			request:       &BulkManifestsRequest{},
+			expectedError: "at least one cluster is required",
+		},
+		{
+			name:          "too many clusters",
+			request:       &BulkManifestsRequest{Clusters: tooManyClusters},
+			expectedError: "too many clusters",
+		},
+		{
+			name: "duplicated clusters",
+			request: &BulkManifestsRequest{Clusters: []BulkManifestsCluster{
+				{Name: "cluster1"}, {Name: "cluster2"}, {Name: "cluster1"},
+			}},
+			expectedError: "duplicated cluster name \"cluster1\"",
+		},
+		{
+			name: "invalid cluster name",
+			request: &BulkManifestsRequest{Clusters: []BulkManifestsCluster{
+				{Name: "../cluster1"},
+			}},
+			expectedError: "invalid cluster name",
+		},
+		{
+			name: "invalid duration",
+			request: &BulkManifestsRequest{Clusters: []BulkManifestsCluster{
+				{Name: "cluster1"},
+			}},
+			duration:      "abc",
+			expectedError: "invalid duration \"abc\"",
+		},
+		{
+			name: "valid request",
+			request: &BulkManifestsRequest{Clusters: []BulkManifestsCluster{
+				{Name: "cluster1"}, {Name: "cluster2", KlusterletConfig: "test"},
+			}},
+			duration: "2h",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateBulkManifestsRequest(c.request, c.duration)
+			if len(c.expectedError) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.expectedError) {
+				t.Errorf("expected error %q, but got %v", c.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestKlusterletConfigOfCluster(t *testing.T) {
+	cases := []struct {
+		name     string
+		request  *BulkManifestsRequest
+		cluster  BulkManifestsCluster
+		expected string
+	}{
+		{
+			name:    "no klusterletconfig",
+			request: &BulkManifestsRequest{},
+			cluster: BulkManifestsCluster{Name: "cluster1"},
+		},
+		{
+			name:     "the default klusterletconfig of the request",
+			request:  &BulkManifestsRequest{KlusterletConfig: "default"},
+			cluster:  BulkManifestsCluster{Name: "cluster1"},
+			expected: "default",
+		},
+		{
+			name:     "the klusterletconfig of the cluster overrides the default",
+			request:  &BulkManifestsRequest{KlusterletConfig: "default"},
+			cluster:  BulkManifestsCluster{Name: "cluster1", KlusterletConfig: "test"},
+			expected: "test",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := klusterletConfigOfCluster(c.request, c.cluster); actual != c.expected {
+				t.Errorf("expected klusterletconfig %q, but got %q", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBulkManifestsHandler(t *testing.T) {
+	os.Setenv(constants.RegistrationOperatorImageEnvVarName, "quay.io/open-cluster-management/registration-operator:latest")
+	os.Setenv(constants.WorkImageEnvVarName, "quay.io/open-cluster-management/work:latest")
+	os.Setenv(constants.RegistrationImageEnvVarName, "quay.io/open-cluster-management/registration:latest")
+	os.Setenv(constants.DefaultImagePullSecretEnvVarName, "")
+	os.Setenv(constants.PodNamespaceEnvVarName, "open-cluster-management")
+
+	scheme := runtime.NewScheme()
+	for _, install := range []func(*runtime.Scheme) error{corev1.AddToScheme, ocinfrav1.Install, clusterv1.Install} {
+		if err := install(scheme); err != nil {
+			t.Fatalf("failed to install the scheme: %v", err)
+		}
+	}
+
+	klusterletconfigs := []*klusterletconfigv1alpha1.KlusterletConfig{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: constants.GlobalKlusterletConfigName},
+			Spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+				HubKubeAPIServerConfig: &klusterletconfigv1alpha1.KubeAPIServerConfig{
+					URL:                        "https://api.hub.example.com:6443",
+					ServerVerificationStrategy: klusterletconfigv1alpha1.ServerVerificationStrategyUseSystemTruststore,
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "broken"},
+			Spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+				HubKubeAPIServerConfig: &klusterletconfigv1alpha1.KubeAPIServerConfig{
+					URL:                        "https://api.hub.example.com:6443",
+					ServerVerificationStrategy: "Unknown",
+				},
+			},
+		},
+	}
+	kcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, kc := range klusterletconfigs {
+		if err := kcIndexer.Add(kc); err != nil {
+			t.Fatalf("failed to add klusterletconfig: %v", err)
+		}
+	}
+	kcLister := listerklusterletconfigv1alpha1.NewKlusterletConfigLister(kcIndexer)
+
+	twoClusters := `{"clusters":[{"name":"cluster1"},{"name":"cluster2"}]}`
+	cases := []struct {
+		name              string
+		method            string
+		query             string
+		body              string
+		expectedStatus    int
+		expectedFileName  string
+		expectedEntries   []string
+		expectedTokenLeft int
+	}{
+		{
+			name:           "method not allowed",
+			method:         http.MethodGet,
+			body:           twoClusters,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:           "unsupported format",
+			method:         http.MethodPost,
+			query:          "format=rar",
+			body:           twoClusters,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid request body",
+			method:         http.MethodPost,
+			body:           "clusters",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid duration",
+			method:         http.MethodPost,
+			query:          "duration=abc",
+			body:           twoClusters,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:             "tar archive",
+			method:           http.MethodPost,
+			body:             twoClusters,
+			expectedStatus:   http.StatusOK,
+			expectedFileName: "manifests.tar.gz",
+			expectedEntries: []string{
+				"cluster1/crds.yaml", "cluster1/import.yaml", "cluster2/crds.yaml", "cluster2/import.yaml",
+			},
+			expectedTokenLeft: 2,
+		},
+		{
+			name:             "zip archive",
+			method:           http.MethodPost,
+			query:            "format=zip&duration=2h",
+			body:             twoClusters,
+			expectedStatus:   http.StatusOK,
+			expectedFileName: "manifests.zip",
+			expectedEntries: []string{
+				"cluster1/crds.yaml", "cluster1/import.yaml", "cluster2/crds.yaml", "cluster2/import.yaml",
+			},
+			expectedTokenLeft: 2,
+		},
+		{
+			name:           "the tokens are revoked when the manifests are failed to generate",
+			method:         http.MethodPost,
+			body:           `{"clusters":[{"name":"cluster1"},{"name":"cluster2","klusterletconfig":"broken"}]}`,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			secretCount := 0
+			kubeClient.PrependReactor("create", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				// the fake client does not generate the name
+				secret := action.(clienttesting.CreateAction).GetObject().(*corev1.Secret)
+				if secret.Name == "" {
+					secretCount++
+					secret.Name = fmt.Sprintf("%s%d", secret.GenerateName, secretCount)
+				}
+				return false, nil, nil
+			})
+			kubeClient.PrependReactor("create", "serviceaccounts", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				return true, &authv1.TokenRequest{
+					Status: authv1.TokenRequestStatus{
+						Token:               "token",
+						ExpirationTimestamp: metav1.NewTime(time.Now().Add(time.Hour)),
+					},
+				}, nil
+			})
+			clientHolder := &helpers.ClientHolder{
+				KubeClient:          kubeClient,
+				RuntimeClient:       fake.NewClientBuilder().WithScheme(scheme).Build(),
+				ImageRegistryClient: imageregistry.NewClient(kubeClient),
+			}
+
+			target := "/agent-registration/bulk-manifests"
+			if len(c.query) > 0 {
+				target = target + "?" + c.query
+			}
+			recorder := httptest.NewRecorder()
+			bulkManifestsHandler(context.TODO(), clientHolder, kcLister).ServeHTTP(recorder,
+				httptest.NewRequest(c.method, target, strings.NewReader(c.body)))
+
+			if recorder.Code != c.expectedStatus {
+				t.Fatalf("expected status %d, but got %d: %s", c.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+
+			secrets, err := kubeClient.CoreV1().Secrets("open-cluster-management").List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(secrets.Items) != c.expectedTokenLeft {
+				t.Errorf("expected %d registration token secrets, but got %d", c.expectedTokenLeft, len(secrets.Items))
+			}
+
+			if c.expectedStatus != http.StatusOK {
+				return
+			}
+			if disposition := recorder.Header().Get("Content-Disposition"); !strings.Contains(disposition, c.expectedFileName) {
+				t.Errorf("expected the file %s, but got %q", c.expectedFileName, disposition)
+			}
+			entries := archiveEntries(t, c.expectedFileName, recorder.Body.Bytes())
+			if !reflect.DeepEqual(entries, c.expectedEntries) {
+				t.Errorf("expected entries %v, but got %v", c.expectedEntries, entries)
+			}
+		})
+	}
+}
+
+// archiveEntries returns the sorted names of the non-empty files in the archive
+func archiveEntries(t *testing.T, fileName string, data []byte) []string {
+	entries := []string{}
+	if strings.HasSuffix(fileName, ".zip") {
+		reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("failed to read the zip archive: %v", err)
+		}
+		for _, file := range reader.File {
+			if file.UncompressedSize64 == 0 {
+				t.Errorf("the file %s is empty", file.Name)
+			}
+			entries = append(entries, file.Name)
+		}
+	} else {
+		gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("failed to read the gzip archive: %v", err)
+		}
+		tarReader := tar.NewReader(gzipReader)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("failed to read the tar archive: %v", err)
+			}
+			if header.Size == 0 {
+				t.Errorf("the file %s is empty", header.Name)
+			}
+			entries = append(entries, header.Name)
+		}
+	}
+	sort.Strings(entries)
+	return entries
+}
@@ -0,0 +1,170 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package agentregistration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	listerklusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"open-cluster-management.io/ocm/pkg/operator/helpers/chart"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+// redactedValue replaces the credentials in the chart values of the explain response
+const redactedValue = "<redacted>"
+
+// ExplainResponse is the response of the explain endpoint, it describes how the klusterlet manifests of a managed
+// cluster are generated.
+type ExplainResponse struct {
+	// ClusterName is the name of the managed cluster
+	ClusterName string `json:"clusterName"`
+	// InstallMode is the install mode of the klusterlet
+	InstallMode operatorv1.InstallMode `json:"installMode"`
+	// KlusterletConfigs are the names of the KlusterletConfigs which the managed cluster uses in the merging order
+	KlusterletConfigs []string `json:"klusterletConfigs"`
+	// MergedKlusterletConfig is the KlusterletConfig merged from the KlusterletConfigs
+	MergedKlusterletConfig *klusterletconfigv1alpha1.KlusterletConfig `json:"mergedKlusterletConfig,omitempty"`
+	// KlusterletConfigSources are the names of the KlusterletConfigs which each spec field and annotation of the
+	// merged KlusterletConfig is from
+	KlusterletConfigSources map[string][]string `json:"klusterletConfigSources,omitempty"`
+	// Sources are the sources of the effective configurations of the klusterlet manifests
+	Sources map[string]bootstrap.ValueSource `json:"sources,omitempty"`
+	// ChartValues are the values of the klusterlet chart, the credentials are redacted
+	ChartValues *chart.KlusterletChartConfig `json:"chartValues,omitempty"`
+	// Error is the error which fails to generate the klusterlet manifests
+	Error string `json:"error,omitempty"`
+}
+
+// explainHandler returns the merged KlusterletConfig of a managed cluster, the source of each effective configuration
+// and the values of the klusterlet chart. The user is required to be allowed to get the managed cluster.
+func explainHandler(clientHolder *helpers.ClientHolder,
+	klusterletconfigLister listerklusterletconfigv1alpha1.KlusterletConfigLister) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urlparams := strings.Split(r.URL.Path, "/")
+		clusterName := urlparams[len(urlparams)-1]
+		if len(clusterName) == 0 {
+			http.Error(w, "the managed cluster name is required", http.StatusBadRequest)
+			return
+		}
+		auditCluster(r, clusterName, "")
+		if !authorizeManagedCluster(w, r, clientHolder, clusterName) {
+			return
+		}
+
+		response, err := explainManagedCluster(r.Context(), clientHolder, klusterletconfigLister, clusterName)
+		if errors.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("the managed cluster %s is not found", clusterName), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			http.Error(w, "Failed to encode the explanation", http.StatusInternalServerError)
+		}
+	})
+}
+
+// explainManagedCluster explains the klusterlet manifests of the managed cluster. The manifests are generated in the
+// same way as the import secret without the bootstrap kubeconfig, a failure of the generation is reported in the
+// response, so the invalid configurations can be found.
+func explainManagedCluster(ctx context.Context, clientHolder *helpers.ClientHolder,
+	klusterletconfigLister listerklusterletconfigv1alpha1.KlusterletConfigLister,
+	clusterName string) (*ExplainResponse, error) {
+	managedCluster := &clusterv1.ManagedCluster{}
+	if err := clientHolder.RuntimeClient.Get(ctx, types.NamespacedName{Name: clusterName}, managedCluster); err != nil {
+		return nil, err
+	}
+
+	klusterletconfigs, err := helpers.GetKlusterletConfigsOfManagedCluster(managedCluster, klusterletconfigLister)
+	if err != nil {
+		return nil, err
+	}
+	mergedKlusterletConfig, err := helpers.MergeKlusterletConfigs(klusterletconfigs...)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := helpers.DetermineKlusterletMode(managedCluster)
+	response := &ExplainResponse{
+		ClusterName:             clusterName,
+		InstallMode:             mode,
+		KlusterletConfigs:       []string{},
+		MergedKlusterletConfig:  mergedKlusterletConfig,
+		KlusterletConfigSources: helpers.GetKlusterletConfigSources(klusterletconfigs...),
+	}
+	for _, kc := range klusterletconfigs {
+		response.KlusterletConfigs = append(response.KlusterletConfigs, kc.Name)
+	}
+
+	if err := helpers.ValidateKlusterletMode(mode); err != nil {
+		response.Error = err.Error()
+		return response, nil
+	}
+	config, err := bootstrap.NewManagedClusterKlusterletManifestsConfig(mode, managedCluster, mergedKlusterletConfig,
+		nil)
+	if err != nil {
+		response.Error = err.Error()
+		return response, nil
+	}
+	// the sources are recorded until the generation fails
+	_, _, generateErr := config.Generate(ctx, clientHolder)
+
+	response.Sources = map[string]bootstrap.ValueSource{}
+	for name, source := range config.ValueSources() {
+		if source.Type == bootstrap.ValueSourceKlusterletConfig {
+			source.KlusterletConfigs = response.KlusterletConfigSources[source.Key]
+		}
+		response.Sources[name] = source
+	}
+	response.Sources["installMode"] = bootstrap.ValueSource{Type: bootstrap.ValueSourceDefault}
+	if _, ok := managedCluster.Annotations[constants.KlusterletDeployModeAnnotation]; ok {
+		response.Sources["installMode"] = bootstrap.ValueSource{
+			Type: bootstrap.ValueSourceManagedClusterAnnotation,
+			Key:  constants.KlusterletDeployModeAnnotation,
+		}
+	}
+
+	if generateErr != nil {
+		response.Error = generateErr.Error()
+		return response, nil
+	}
+	response.ChartValues = redactChartConfig(config.ChartConfig())
+	return response, nil
+}
+
+// redactChartConfig returns a copy of the chart values without the image pull secret and the kubeconfigs
+func redactChartConfig(config *chart.KlusterletChartConfig) *chart.KlusterletChartConfig {
+	redacted := *config
+	if len(redacted.Images.ImageCredentials.DockerConfigJson) > 0 {
+		redacted.Images.ImageCredentials.DockerConfigJson = redactedValue
+	}
+	if len(redacted.BootstrapHubKubeConfig) > 0 {
+		redacted.BootstrapHubKubeConfig = redactedValue
+	}
+	if len(redacted.ExternalManagedKubeConfig) > 0 {
+		redacted.ExternalManagedKubeConfig = redactedValue
+	}
+	redacted.MultiHubBootstrapHubKubeConfigs = nil
+	for _, kubeconfig := range config.MultiHubBootstrapHubKubeConfigs {
+		redacted.MultiHubBootstrapHubKubeConfigs = append(redacted.MultiHubBootstrapHubKubeConfigs,
+			chart.BootStrapKubeConfig{Name: kubeconfig.Name, KubeConfig: redactedValue})
+	}
+	return &redacted
+}
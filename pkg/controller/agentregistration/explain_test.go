@@ -0,0 +1,270 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package agentregistration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	listerklusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
+	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"open-cluster-management.io/ocm/pkg/operator/helpers/chart"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+)
+
+func TestExplainManagedCluster(t *testing.T) {
+	os.Setenv(constants.RegistrationOperatorImageEnvVarName, "quay.io/open-cluster-management/registration-operator:latest")
+	os.Setenv(constants.WorkImageEnvVarName, "quay.io/open-cluster-management/work:latest")
+	os.Setenv(constants.RegistrationImageEnvVarName, "quay.io/open-cluster-management/registration:latest")
+	os.Setenv(constants.DefaultImagePullSecretEnvVarName, "")
+
+	scheme := runtime.NewScheme()
+	if err := clusterv1.Install(scheme); err != nil {
+		t.Fatalf("failed to install the scheme: %v", err)
+	}
+
+	klusterletconfigs := []*klusterletconfigv1alpha1.KlusterletConfig{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: constants.GlobalKlusterletConfigName},
+			Spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+				NodePlacement: &operatorv1.NodePlacement{
+					NodeSelector: map[string]string{"kubernetes.io/os": "linux"},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "dev",
+				Annotations: map[string]string{
+					constants.AnnotationKlusterletConfigManagedClusterSelector: "env=dev",
+				},
+			},
+			Spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+				Registries: []klusterletconfigv1alpha1.Registries{
+					{Source: "quay.io/open-cluster-management", Mirror: "quay.io/dev"},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+				Registries: []klusterletconfigv1alpha1.Registries{
+					{Source: "quay.io/open-cluster-management", Mirror: "quay.io/test"},
+				},
+			},
+		},
+	}
+	kcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, kc := range klusterletconfigs {
+		if err := kcIndexer.Add(kc); err != nil {
+			t.Fatalf("failed to add klusterletconfig: %v", err)
+		}
+	}
+	kcLister := listerklusterletconfigv1alpha1.NewKlusterletConfigLister(kcIndexer)
+
+	cases := []struct {
+		name        string
+		clusterName string
+		clusters    []client.Object
+		validate    func(t *testing.T, response *ExplainResponse, err error)
+	}{
+		{
+			name:        "managed cluster not found",
+			clusterName: "cluster1",
+			validate: func(t *testing.T, response *ExplainResponse, err error) {
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected not found error, but got %v", err)
+				}
+			},
+		},
+		{
+			name:        "explain the managed cluster",
+			clusterName: "cluster1",
+			clusters: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "cluster1",
+						Labels: map[string]string{"env": "dev"},
+						Annotations: map[string]string{
+							apiconstants.AnnotationKlusterletConfig: "test",
+							helpers.TolerationsAnnotation:           `[{"key":"foo","operator":"Exists"}]`,
+						},
+					},
+				},
+			},
+			validate: func(t *testing.T, response *ExplainResponse, err error) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if response.Error != "" {
+					t.Fatalf("unexpected error in the response: %s", response.Error)
+				}
+				if !reflect.DeepEqual(response.KlusterletConfigs, []string{"global", "dev", "test"}) {
+					t.Errorf("unexpected klusterletconfigs %v", response.KlusterletConfigs)
+				}
+				if response.InstallMode != operatorv1.InstallModeSingleton {
+					t.Errorf("unexpected install mode %s", response.InstallMode)
+				}
+
+				expectedSources := map[string]bootstrap.ValueSource{
+					"installMode": {Type: bootstrap.ValueSourceDefault},
+					"nodeSelector": {Type: bootstrap.ValueSourceKlusterletConfig, Key: "nodePlacement",
+						KlusterletConfigs: []string{"global"}},
+					"images": {Type: bootstrap.ValueSourceKlusterletConfig, Key: "registries",
+						KlusterletConfigs: []string{"test"}},
+					"tolerations": {Type: bootstrap.ValueSourceManagedClusterAnnotation,
+						Key: helpers.TolerationsAnnotation},
+				}
+				for name, expected := range expectedSources {
+					if !reflect.DeepEqual(response.Sources[name], expected) {
+						t.Errorf("expected the source of %s to be %v, but got %v", name, expected, response.Sources[name])
+					}
+				}
+
+				if response.ChartValues == nil {
+					t.Fatalf("the chart values are not returned")
+				}
+				if response.ChartValues.Images.Overrides.WorkImage != "quay.io/test/work:latest" {
+					t.Errorf("unexpected work image %s", response.ChartValues.Images.Overrides.WorkImage)
+				}
+				if response.ChartValues.Images.ImageCredentials.DockerConfigJson != redactedValue {
+					t.Errorf("the image pull secret is not redacted")
+				}
+			},
+		},
+		{
+			name:        "invalid annotation",
+			clusterName: "cluster2",
+			clusters: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster2",
+						Annotations: map[string]string{
+							imageregistry.ClusterImageRegistriesAnnotation: "invalid",
+						},
+					},
+				},
+			},
+			validate: func(t *testing.T, response *ExplainResponse, err error) {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if response.Error == "" {
+					t.Errorf("expected error in the response")
+				}
+				if response.ChartValues != nil {
+					t.Errorf("the chart values should not be returned")
+				}
+				if !reflect.DeepEqual(response.KlusterletConfigs, []string{"global"}) {
+					t.Errorf("unexpected klusterletconfigs %v", response.KlusterletConfigs)
+				}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			clientHolder := &helpers.ClientHolder{
+				KubeClient:          kubeClient,
+				RuntimeClient:       fake.NewClientBuilder().WithScheme(scheme).WithObjects(c.clusters...).Build(),
+				ImageRegistryClient: imageregistry.NewClient(kubeClient),
+			}
+			response, err := explainManagedCluster(context.Background(), clientHolder, kcLister, c.clusterName)
+			c.validate(t, response, err)
+		})
+	}
+}
+
+func TestExplainHandler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clusterv1.Install(scheme); err != nil {
+		t.Fatalf("failed to install the scheme: %v", err)
+	}
+	kcLister := listerklusterletconfigv1alpha1.NewKlusterletConfigLister(
+		cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}))
+
+	cases := []struct {
+		name           string
+		clusterName    string
+		expectedStatus int
+	}{
+		{
+			name:           "explain the cluster",
+			clusterName:    "cluster1",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "not allowed",
+			clusterName:    "forbidden",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "cluster not found",
+			clusterName:    "cluster2",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			auth, _, _ := newTestAuthenticator(10)
+			auth.clientHolder.RuntimeClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}},
+				&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "forbidden"}},
+			).Build()
+			auth.clientHolder.ImageRegistryClient = imageregistry.NewClient(auth.clientHolder.KubeClient)
+
+			req := httptest.NewRequest(http.MethodGet, "/agent-registration/explain/"+c.clusterName, nil)
+			req.Header.Set("Authorization", "Bearer valid")
+			rec := httptest.NewRecorder()
+			auth.middleware(explainHandler(auth.clientHolder, kcLister)).ServeHTTP(rec, req)
+
+			if rec.Code != c.expectedStatus {
+				t.Errorf("expected status %d, but got %d: %s", c.expectedStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRedactChartConfig(t *testing.T) {
+	config := &chart.KlusterletChartConfig{
+		BootstrapHubKubeConfig: "kubeconfig",
+		MultiHubBootstrapHubKubeConfigs: []chart.BootStrapKubeConfig{
+			{Name: "hub1", KubeConfig: "kubeconfig1"},
+		},
+	}
+	config.Images.ImageCredentials.DockerConfigJson = "{}"
+
+	redacted := redactChartConfig(config)
+	if redacted.BootstrapHubKubeConfig != redactedValue ||
+		redacted.Images.ImageCredentials.DockerConfigJson != redactedValue ||
+		redacted.MultiHubBootstrapHubKubeConfigs[0].KubeConfig != redactedValue ||
+		redacted.MultiHubBootstrapHubKubeConfigs[0].Name != "hub1" {
+		t.Errorf("the credentials are not redacted: %v", redacted)
+	}
+	if len(redacted.ExternalManagedKubeConfig) != 0 {
+		t.Errorf("the empty value should not be redacted")
+	}
+	if config.BootstrapHubKubeConfig != "kubeconfig" || config.MultiHubBootstrapHubKubeConfigs[0].KubeConfig != "kubeconfig1" {
+		t.Errorf("the original chart values should not be modified")
+	}
+}
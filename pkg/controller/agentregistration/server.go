@@ -10,29 +10,35 @@ import (
 	"strings"
 	"time"
 
-	authenticationv1 "k8s.io/api/authentication/v1"
-	authorizationv1 "k8s.io/api/authorization/v1"
-
 	listerklusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
 	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
 	operatorv1 "open-cluster-management.io/api/operator/v1"
 
 	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
 )
 
+// registrationTokenCleanupInterval is the interval to delete the expired and consumed registration token secrets
+const registrationTokenCleanupInterval = 10 * time.Minute
+
+// RunAgentRegistrationServer runs the agent registration server on the port, the authenticated requests of a user
+// are limited to qps requests per second with the burst.
 func RunAgentRegistrationServer(ctx context.Context, port int, clientHolder *helpers.ClientHolder,
-	klusterletconfigLister listerklusterletconfigv1alpha1.KlusterletConfigLister) error {
+	klusterletconfigLister listerklusterletconfigv1alpha1.KlusterletConfigLister, qps float64, burst int) error {
 	mux := http.NewServeMux()
+	authMiddleware := newAuthenticator(clientHolder, qps, burst).middleware
 
-	mux.Handle("/agent-registration", authMiddleware(clientHolder, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/agent-registration", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		response := map[string]interface{}{
 			"paths": []string{
 				"/crds/v1",
 				"/manifests",
+				"/bulk-manifests",
+				"/import-history",
+				"/explain",
 			},
 			"serverInfo": map[string]string{
 				"serverTime": time.Now().UTC().Format(time.RFC3339),
@@ -45,7 +51,7 @@ func RunAgentRegistrationServer(ctx context.Context, port int, clientHolder *hel
 		}
 	})))
 
-	mux.Handle("/agent-registration/crds/v1", authMiddleware(clientHolder, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/agent-registration/crds/v1", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		config := bootstrap.NewKlusterletManifestsConfig(
 			operatorv1.InstallModeDefault,
 			"dummy",
@@ -60,85 +66,62 @@ func RunAgentRegistrationServer(ctx context.Context, port int, clientHolder *hel
 		}
 	})))
 
-	// example URl: https://<route address>/agent-registration/manifests/cluster1?klusterletconfig=default&duration=4h
-	mux.Handle("/agent-registration/manifests/", authMiddleware(clientHolder, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var err error
+	// example URl: https://<route address>/agent-registration/manifests/cluster1?klusterletconfig=default&duration=4h&format=helm
+	mux.Handle("/agent-registration/manifests/", authMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		urlparams := strings.Split(r.URL.Path, "/")
 		clusterID := urlparams[len(urlparams)-1]
 
 		klusterletconfigName := r.URL.Query().Get("klusterletconfig")
 		durationStr := r.URL.Query().Get("duration")
-
-		// Get the merged KlusterletConfig, it merges the user assigned KlusterletConfig with the global KlusterletConfig.
-		mergedKlusterletConfig, err := helpers.GetMergedKlusterletConfigWithGlobal(klusterletconfigName, klusterletconfigLister)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = manifestsFormatYAML
+		}
+		if format != manifestsFormatYAML && format != manifestsFormatHelm && format != manifestsFormatKustomize {
+			http.Error(w, fmt.Sprintf("unsupported format %q, it should be %s, %s or %s", format,
+				manifestsFormatYAML, manifestsFormatHelm, manifestsFormatKustomize), http.StatusBadRequest)
 			return
 		}
 
-		// In the agent-registration case, the bootstrap sa is not created in the managed cluster namespace, because managed cluster is not created yet.
-		// Instead, it's in the pod namespace with the name "agent-registration-bootstrap".
-		ns := os.Getenv(constants.PodNamespaceEnvVarName)
-
-		var token []byte
-		if durationStr == "" {
-			token, _, _, err = bootstrap.GetBootstrapToken(ctx, clientHolder.KubeClient, AgentRegistrationDefaultBootstrapSAName, ns,
-				constants.DefaultSecretTokenExpirationSecond)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-		} else {
-			duration, err := time.ParseDuration(durationStr)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
-
-			token, _, _, err = bootstrap.RequestSAToken(ctx, clientHolder.KubeClient, AgentRegistrationDefaultBootstrapSAName, ns, int64(duration.Seconds()))
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
-		}
-
-		// get the latest kube apiserver configuration
-		kubeAPIServer, proxyURL, ca, caData, err := bootstrap.GetKubeAPIServerConfig(
-			ctx, clientHolder, ns, mergedKlusterletConfig, false)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if len(clusterID) == 0 {
+			http.Error(w, "the cluster name is required", http.StatusBadRequest)
 			return
 		}
-		ctxClusterName, err := bootstrap.GetKubeconfigClusterName(ctx, clientHolder.RuntimeClient)
+		auditCluster(r, clusterID, klusterletconfigName)
+
+		// the token is only allowed to register the cluster once
+		token, tokenSecretName, err := issueRegistrationToken(ctx, clientHolder, clusterID, durationStr)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), httpStatusOfRegistrationTokenError(err))
 			return
 		}
 
-		bootstrapkubeconfig, err := bootstrap.CreateBootstrapKubeConfig(ctxClusterName, kubeAPIServer, proxyURL, ca, caData, token)
+		config, err := newKlusterletManifestsConfig(r.Context(), clientHolder, klusterletconfigLister,
+			clusterID, klusterletconfigName, token)
 		if err != nil {
+			revokeRegistrationTokens(ctx, clientHolder, tokenSecretName)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		klusterletClusterAnnotations := map[string]string{
-			"agent.open-cluster-management.io/create-with-default-klusterletaddonconfig": "true",
+		var content []byte
+		switch format {
+		case manifestsFormatYAML:
+			content, _, err = config.Generate(r.Context(), clientHolder)
+		case manifestsFormatHelm:
+			content, err = config.GenerateHelmChart(r.Context(), clientHolder)
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "klusterlet-"+clusterID+".tgz"))
+		case manifestsFormatKustomize:
+			content, err = config.GenerateKustomizeBase(r.Context(), clientHolder)
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", clusterID+".tar.gz"))
 		}
-		if klusterletconfigName != "" {
-			// This annotation will finanlly be added on the managedcluster which created by the agent side.
-			// Then the reconciliation of importconfig-controller will render manifests with the same KlusterletConfig
-			klusterletClusterAnnotations[apiconstants.AnnotationKlusterletConfig] = klusterletconfigName
-		}
-
-		content, _, err := bootstrap.NewKlusterletManifestsConfig(
-			operatorv1.InstallModeDefault,
-			clusterID,
-			bootstrapkubeconfig).
-			WithKlusterletClusterAnnotations(klusterletClusterAnnotations).
-			WithKlusterletConfig(mergedKlusterletConfig).
-			Generate(r.Context(), clientHolder)
 		if err != nil {
+			revokeRegistrationTokens(ctx, clientHolder, tokenSecretName)
+			w.Header().Del("Content-Disposition")
 			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
 		_, err = w.Write(content)
@@ -147,6 +130,18 @@ func RunAgentRegistrationServer(ctx context.Context, port int, clientHolder *hel
 		}
 	})))
 
+	// example URl: https://<route address>/agent-registration/bulk-manifests?format=zip&duration=4h
+	mux.Handle("/agent-registration/bulk-manifests", authMiddleware(bulkManifestsHandler(ctx, clientHolder,
+		klusterletconfigLister)))
+
+	// example URl: https://<route address>/agent-registration/import-history/cluster1
+	mux.Handle("/agent-registration/import-history/", authMiddleware(importHistoryHandler(clientHolder)))
+
+	// example URl: https://<route address>/agent-registration/explain/cluster1
+	mux.Handle("/agent-registration/explain/", authMiddleware(explainHandler(clientHolder, klusterletconfigLister)))
+
+	go cleanupRegistrationTokens(ctx, clientHolder)
+
 	server := &http.Server{
 		ReadHeaderTimeout: 10 * time.Second,
 		Addr:              fmt.Sprintf(":%d", port),
@@ -158,67 +153,153 @@ func RunAgentRegistrationServer(ctx context.Context, port int, clientHolder *hel
 	return server.ListenAndServeTLS("/server/tls.crt", "/server/tls.key")
 }
 
-func authMiddleware(clientHolder *helpers.ClientHolder, next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get the Authorization header value
-		authHeader := r.Header.Get("Authorization")
+// invalidDurationError is returned when the requested duration of the registration token is invalid
+type invalidDurationError struct {
+	err error
+}
 
-		// Check if the header value starts with "Bearer "
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
-			return
-		}
+func (e *invalidDurationError) Error() string {
+	return e.err.Error()
+}
 
-		// Extract the token from the header value
-		token := strings.TrimPrefix(authHeader, "Bearer ")
+func httpStatusOfRegistrationTokenError(err error) int {
+	if _, ok := err.(*invalidDurationError); ok {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
 
-		// Authentication
-		trresult, err := clientHolder.KubeClient.AuthenticationV1().TokenReviews().Create(r.Context(), &authenticationv1.TokenReview{
-			Spec: authenticationv1.TokenReviewSpec{
-				Token: token,
-			},
-		}, metav1.CreateOptions{})
+// issueRegistrationToken issues a one-time registration token of the agent registration bootstrap sa for the
+// cluster, the token is requested with the duration if it is specified. The token and the name of its registration
+// token secret are returned.
+func issueRegistrationToken(ctx context.Context, clientHolder *helpers.ClientHolder, clusterName string,
+	durationStr string) ([]byte, string, error) {
+	// In the agent-registration case, the bootstrap sa is not created in the managed cluster namespace, because managed cluster is not created yet.
+	// Instead, it's in the pod namespace with the name "agent-registration-bootstrap".
+	ns := os.Getenv(constants.PodNamespaceEnvVarName)
+
+	expirationSeconds := int64(constants.DefaultSecretTokenExpirationSecond)
+	if durationStr != "" {
+		duration, err := time.ParseDuration(durationStr)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("create TR failed %v", err.Error()), http.StatusInternalServerError)
-			return
+			return nil, "", &invalidDurationError{err: err}
 		}
-		if !trresult.Status.Authenticated {
-			http.Error(w, fmt.Sprintf("authentication failed, response:%v, error:%v", trresult.Status, trresult.Status.Error), http.StatusUnauthorized)
-			return
+		expirationSeconds = int64(duration.Seconds())
+	}
+
+	return helpers.IssueRegistrationToken(ctx, clientHolder.KubeClient, AgentRegistrationDefaultBootstrapSAName, ns,
+		clusterName, expirationSeconds)
+}
+
+// revokeRegistrationTokens revokes the registration tokens whose manifests are not returned to the user
+func revokeRegistrationTokens(ctx context.Context, clientHolder *helpers.ClientHolder, tokenSecretNames ...string) {
+	ns := os.Getenv(constants.PodNamespaceEnvVarName)
+	for _, name := range tokenSecretNames {
+		if err := helpers.RevokeRegistrationToken(ctx, clientHolder.KubeClient, ns, name); err != nil {
+			klog.Warningf("failed to revoke the registration token %s/%s: %v", ns, name, err)
 		}
+	}
+}
 
-		// Authorization
-		userInfo := trresult.Status.User
-		extra := make(map[string]authorizationv1.ExtraValue)
-		for k, v := range userInfo.Extra {
-			extra[k] = authorizationv1.ExtraValue(v)
-		}
-		sarrequest := &authorizationv1.SubjectAccessReview{
-			Spec: authorizationv1.SubjectAccessReviewSpec{
-				User:   userInfo.Username,
-				Groups: userInfo.Groups,
-				UID:    userInfo.UID,
-				Extra:  extra,
-				NonResourceAttributes: &authorizationv1.NonResourceAttributes{
-					Path: "/agent-registration/*",
-					Verb: "get",
-				},
-			},
+// cleanupRegistrationTokens deletes the expired and consumed registration token secrets periodically
+func cleanupRegistrationTokens(ctx context.Context, clientHolder *helpers.ClientHolder) {
+	ns := os.Getenv(constants.PodNamespaceEnvVarName)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := helpers.CleanupRegistrationTokens(ctx, clientHolder.KubeClient, ns, time.Now()); err != nil {
+			klog.Warningf("failed to clean up the registration tokens: %v", err)
 		}
-		sarresult, err := clientHolder.KubeClient.AuthorizationV1().SubjectAccessReviews().Create(r.Context(), sarrequest, metav1.CreateOptions{})
-		if err != nil {
-			http.Error(w, fmt.Sprintf("create SAR failed %v, user: %v", err.Error(), userInfo), http.StatusInternalServerError)
+	}, registrationTokenCleanupInterval)
+}
+
+// newKlusterletManifestsConfig returns the config to generate the klusterlet manifests of a cluster with the
+// bootstrap token and the KlusterletConfig, the KlusterletConfig is merged with the global KlusterletConfig.
+func newKlusterletManifestsConfig(ctx context.Context, clientHolder *helpers.ClientHolder,
+	klusterletconfigLister listerklusterletconfigv1alpha1.KlusterletConfigLister,
+	clusterID, klusterletconfigName string, token []byte) (*bootstrap.KlusterletManifestsConfig, error) {
+	// Get the merged KlusterletConfig, it merges the user assigned KlusterletConfig with the global KlusterletConfig.
+	mergedKlusterletConfig, err := helpers.GetMergedKlusterletConfigWithGlobal(klusterletconfigName, klusterletconfigLister)
+	if err != nil {
+		return nil, err
+	}
+
+	// get the latest kube apiserver configuration
+	kubeAPIServer, proxyURL, ca, caData, err := bootstrap.GetKubeAPIServerConfig(
+		ctx, clientHolder, os.Getenv(constants.PodNamespaceEnvVarName), mergedKlusterletConfig, false)
+	if err != nil {
+		return nil, err
+	}
+	additionalConfigs, err := bootstrap.GetAdditionalKubeAPIServerConfigs(ctx, clientHolder,
+		os.Getenv(constants.PodNamespaceEnvVarName), mergedKlusterletConfig, kubeAPIServer, false)
+	if err != nil {
+		return nil, err
+	}
+	ctxClusterName, err := bootstrap.GetKubeconfigClusterName(ctx, clientHolder.RuntimeClient)
+	if err != nil {
+		return nil, err
+	}
+
+	bootstrapkubeconfig, err := bootstrap.CreateBootstrapKubeConfig(ctxClusterName, kubeAPIServer, proxyURL, ca, caData, token,
+		additionalConfigs...)
+	if err != nil {
+		return nil, err
+	}
+
+	klusterletClusterAnnotations := map[string]string{
+		"agent.open-cluster-management.io/create-with-default-klusterletaddonconfig": "true",
+	}
+	if klusterletconfigName != "" {
+		// This annotation will finanlly be added on the managedcluster which created by the agent side.
+		// Then the reconciliation of importconfig-controller will render manifests with the same KlusterletConfig
+		klusterletClusterAnnotations[apiconstants.AnnotationKlusterletConfig] = klusterletconfigName
+	}
+
+	return bootstrap.NewKlusterletManifestsConfig(
+		operatorv1.InstallModeDefault,
+		clusterID,
+		bootstrapkubeconfig).
+		WithKlusterletClusterAnnotations(klusterletClusterAnnotations).
+		WithKlusterletConfig(mergedKlusterletConfig), nil
+}
+
+const (
+	AgentRegistrationDefaultBootstrapSAName = "agent-registration-bootstrap"
+)
+
+// the output formats of the manifests endpoint
+const (
+	manifestsFormatYAML      = "yaml"
+	manifestsFormatHelm      = "helm"
+	manifestsFormatKustomize = "kustomize"
+)
+
+// importHistoryHandler returns the import history of a managed cluster, the user is required to be allowed to get
+// the managed cluster.
+func importHistoryHandler(clientHolder *helpers.ClientHolder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urlparams := strings.Split(r.URL.Path, "/")
+		clusterName := urlparams[len(urlparams)-1]
+		if len(clusterName) == 0 {
+			http.Error(w, "the managed cluster name is required", http.StatusBadRequest)
+			return
+		}
+		auditCluster(r, clusterName, "")
+		if !authorizeManagedCluster(w, r, clientHolder, clusterName) {
 			return
 		}
-		if !sarresult.Status.Allowed {
-			http.Error(w, fmt.Sprintf("authorization failed, response:%v, user:%v", sarresult.Status, userInfo), http.StatusUnauthorized)
+
+		history, err := helpers.GetImportHistory(r.Context(), clientHolder.KubeClient, clusterName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"clusterName": clusterName,
+			"history":     history,
+		}); err != nil {
+			http.Error(w, "Failed to encode import history", http.StatusInternalServerError)
+		}
 	})
 }
-
-const (
-	AgentRegistrationDefaultBootstrapSAName = "agent-registration-bootstrap"
-)
@@ -25,6 +25,7 @@ import (
 	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/metrics"
 	"github.com/stolostron/managedcluster-import-controller/pkg/source"
 )
 
@@ -40,6 +41,9 @@ type ReconcileAutoImport struct {
 	importHelper             *helpers.ImportHelper
 	rosaKubeConfigGetters    map[string]*helpers.RosaKubeConfigGetter
 	autoImportStrategyGetter helpers.AutoImportStrategyGetterFunc
+	importSyncWindowsGetter  helpers.ImportSyncWindowsGetterFunc
+	insecureRefusedGetter    helpers.InsecureAutoImportRefusedGetterFunc
+	retryPolicyGetter        helpers.AutoImportRetryPolicyGetterFunc
 }
 
 func NewReconcileAutoImport(
@@ -49,16 +53,24 @@ func NewReconcileAutoImport(
 	recorder events.Recorder,
 	mcRecorder kevents.EventRecorder,
 	autoImportStrategyGetter helpers.AutoImportStrategyGetterFunc,
+	insecureRefusedGetter helpers.InsecureAutoImportRefusedGetterFunc,
+	clusterTakeoverPolicyGetter helpers.ClusterTakeoverPolicyGetterFunc,
+	importSyncWindowsGetter helpers.ImportSyncWindowsGetterFunc,
+	retryPolicyGetter helpers.AutoImportRetryPolicyGetterFunc,
 ) *ReconcileAutoImport {
 	return &ReconcileAutoImport{
-		client:                   client,
-		kubeClient:               kubeClient,
-		informerHolder:           informerHolder,
-		recorder:                 recorder,
-		mcRecorder:               mcRecorder,
-		importHelper:             helpers.NewImportHelper(informerHolder, recorder, log),
+		client:         client,
+		kubeClient:     kubeClient,
+		informerHolder: informerHolder,
+		recorder:       recorder,
+		mcRecorder:     mcRecorder,
+		importHelper: helpers.NewImportHelper(informerHolder, recorder, log).
+			WithClusterTakeoverPolicy(clusterTakeoverPolicyGetter, mcRecorder),
 		rosaKubeConfigGetters:    make(map[string]*helpers.RosaKubeConfigGetter),
 		autoImportStrategyGetter: autoImportStrategyGetter,
+		importSyncWindowsGetter:  importSyncWindowsGetter,
+		insecureRefusedGetter:    insecureRefusedGetter,
+		retryPolicyGetter:        retryPolicyGetter,
 	}
 }
 
@@ -103,7 +115,7 @@ func (r *ReconcileAutoImport) Reconcile(ctx context.Context, request reconcile.R
 	}
 
 	immediateImport := helpers.IsImmediateImport(managedCluster.Annotations)
-	autoImportStrategy, err := r.autoImportStrategyGetter()
+	autoImportStrategy, err := r.autoImportStrategyGetter(managedCluster)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -128,34 +140,88 @@ func (r *ReconcileAutoImport) Reconcile(ctx context.Context, request reconcile.R
 		return reconcile.Result{}, err
 	}
 
+	insecureRefused, err := r.insecureRefusedGetter()
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if helpers.IsImportPreflight(managedCluster.Annotations) {
+		// only run the preflight checks, the managed cluster will not be imported until the annotation is removed
+		return r.preflight(managedCluster, autoImportSecret, insecureRefused)
+	}
+
 	backupRestore := false
 	if v, ok := autoImportSecret.Labels[constants.LabelAutoImportRestore]; ok && strings.EqualFold(v, "true") {
 		backupRestore = true
 	}
 
-	generateClientHolderFunc, err := r.getGenerateClientHolderFuncFromAutoImportSecret(managedClusterName, autoImportSecret)
+	if !backupRestore && !immediateImport && importSucceeded {
+		// the importing resources are only synced to the imported managed cluster in the maintenance windows
+		requeueAfter, deferred, err := helpers.DeferImportSync(r.client, managedCluster, r.importSyncWindowsGetter)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if deferred {
+			reqLogger.Info("Auto import sync is deferred to the next maintenance window",
+				"managedCluster", managedCluster.Name, "requeueAfter", requeueAfter)
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
+		}
+	}
+
+	retryPolicy, err := r.retryPolicyGetter(autoImportSecret)
 	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if attempts := helpers.GetAutoImportAttempts(autoImportSecret); attempts >= retryPolicy.MaxAttempts {
+		// the retry budget is exhausted, do not retry until the auto import secret is changed
+		reqLogger.V(5).Info("Auto import is stopped after the failed attempts",
+			"managedCluster", managedCluster.Name, "attempts", attempts)
+		return reconcile.Result{}, nil
+	}
+
+	generateClientHolderFunc, err := r.getGenerateClientHolderFuncFromAutoImportSecret(
+		managedClusterName, autoImportSecret, insecureRefused)
+	if err != nil {
+		metrics.RecordAutoImport(string(autoImportSecret.Type), metrics.AutoImportResultFailed)
+		condition := helpers.NewManagedClusterImportSucceededCondition(
+			metav1.ConditionFalse,
+			constants.ConditionReasonManagedClusterImportFailed,
+			fmt.Sprintf("AutoImportSecretInvalid %s/%s; %s",
+				autoImportSecret.Namespace, autoImportSecret.Name, err),
+		)
+		result, rErr := r.recordFailedAttempt(ctx, managedClusterName, autoImportSecret, retryPolicy, &condition)
+		if rErr != nil {
+			return reconcile.Result{}, rErr
+		}
 		if err := helpers.UpdateManagedClusterImportCondition(
 			r.client,
 			managedCluster,
-			helpers.NewManagedClusterImportSucceededCondition(
-				metav1.ConditionFalse,
-				constants.ConditionReasonManagedClusterImportFailed,
-				fmt.Sprintf("AutoImportSecretInvalid %s/%s; %s",
-					autoImportSecret.Namespace, autoImportSecret.Name, err),
-			),
+			condition,
 			r.mcRecorder,
+			helpers.ImportAttemptSource{
+				Controller: helpers.ImportSourceAutoImport,
+				SecretType: autoImportSecret.Type,
+			},
 		); err != nil {
 			return reconcile.Result{}, err
 		}
-		// return error if the auto import secret invalid
 		reqLogger.Info("Auto import secret invalid", "managedCluster", managedCluster.Name, "error", err)
-		return reconcile.Result{}, err
+		return result, nil
 	}
 
 	r.importHelper = r.importHelper.WithGenerateClientHolderFunc(generateClientHolderFunc)
 	result, condition, modified, iErr := r.importHelper.Import(
 		backupRestore, managedCluster, autoImportSecret)
+	if iErr != nil && condition.Reason == constants.ConditionReasonManagedClusterImportFailed {
+		// the import is failed, retry it with backoff instead of the default rate limiting of the controller,
+		// including waiting for the kubeconfig of the rosa cluster
+		reqLogger.Info("Auto import failed", "managedCluster", managedCluster.Name, "error", iErr)
+		result, err = r.recordFailedAttempt(ctx, managedClusterName, autoImportSecret, retryPolicy, &condition)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		iErr = nil
+	}
 	// if resources are applied but NOT modified, will not update the condition, keep the original condition.
 	// This check is to prevent the current controller and import status controller from modifying the
 	// ManagedClusterImportSucceeded condition of the managed cluster in a loop
@@ -165,6 +231,10 @@ func (r *ReconcileAutoImport) Reconcile(ctx context.Context, request reconcile.R
 			managedCluster,
 			condition,
 			r.mcRecorder,
+			helpers.ImportAttemptSource{
+				Controller: helpers.ImportSourceAutoImport,
+				SecretType: autoImportSecret.Type,
+			},
 		); err != nil {
 			return reconcile.Result{}, err
 		}
@@ -172,8 +242,14 @@ func (r *ReconcileAutoImport) Reconcile(ctx context.Context, request reconcile.R
 
 	reqLogger.V(5).Info("Import result", "importError", iErr, "condition", condition,
 		"result", result, "modified", modified)
+	metrics.RecordAutoImport(string(autoImportSecret.Type), autoImportResult(condition))
 
 	if helpers.ImportingResourcesApplied(&condition) {
+		// the auto import secret could be kept after the import, forget its failed attempts
+		if err := helpers.ResetAutoImportAttempts(ctx, r.kubeClient, autoImportSecret); err != nil {
+			return reconcile.Result{}, err
+		}
+
 		// clean up the import user when current cluster is rosa
 		if getter, ok := r.rosaKubeConfigGetters[managedClusterName]; ok {
 			if err := getter.Cleanup(); err != nil {
@@ -199,37 +275,133 @@ func (r *ReconcileAutoImport) Reconcile(ctx context.Context, request reconcile.R
 	return result, iErr
 }
 
+// recordFailedAttempt records a failed import attempt of the managed cluster on the auto import secret, appends the
+// attempts to the message of the import condition and returns the result to retry the import with backoff. The
+// import is not retried once the maximum attempts are reached.
+func (r *ReconcileAutoImport) recordFailedAttempt(ctx context.Context, clusterName string,
+	autoImportSecret *corev1.Secret, retryPolicy helpers.AutoImportRetryPolicy,
+	condition *metav1.Condition) (reconcile.Result, error) {
+	attempts, err := helpers.RecordAutoImportAttempt(ctx, r.kubeClient, autoImportSecret)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if attempts >= retryPolicy.MaxAttempts {
+		condition.Message = fmt.Sprintf("%s (attempt %d/%d), stop retrying until the %s/%s is changed",
+			condition.Message, attempts, retryPolicy.MaxAttempts, autoImportSecret.Namespace, autoImportSecret.Name)
+
+		// the import of the rosa cluster is stopped, clean up its import user
+		if getter, ok := r.rosaKubeConfigGetters[clusterName]; ok {
+			if err := getter.Cleanup(); err != nil {
+				log.Info("Failed to clean up the import user of the rosa cluster", "managedCluster", clusterName,
+					"error", err.Error())
+			}
+			delete(r.rosaKubeConfigGetters, clusterName)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	condition.Message = fmt.Sprintf("%s (attempt %d/%d)", condition.Message, attempts, retryPolicy.MaxAttempts)
+	return reconcile.Result{RequeueAfter: retryPolicy.Backoff(attempts)}, nil
+}
+
+// preflight runs the import preflight checks with the auto import secret and updates the import preflight
+// condition of the managed cluster
+func (r *ReconcileAutoImport) preflight(managedCluster *clusterv1.ManagedCluster, autoImportSecret *corev1.Secret,
+	insecureRefused bool) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Name", managedCluster.Name)
+
+	generateClientHolderFunc, err := r.getGenerateClientHolderFuncFromAutoImportSecret(
+		managedCluster.Name, autoImportSecret, insecureRefused)
+	if err != nil {
+		reqLogger.Info("Auto import secret invalid", "managedCluster", managedCluster.Name, "error", err)
+		return reconcile.Result{}, helpers.UpdateManagedClusterImportPreflightCondition(
+			r.client,
+			managedCluster,
+			helpers.NewManagedClusterImportPreflightCondition([]string{
+				fmt.Sprintf("AutoImportSecretInvalid %s/%s; %s", autoImportSecret.Namespace, autoImportSecret.Name, err),
+			}),
+			r.mcRecorder,
+		)
+	}
+
+	r.importHelper = r.importHelper.WithGenerateClientHolderFunc(generateClientHolderFunc)
+	result, condition, err := r.importHelper.Preflight(managedCluster, autoImportSecret)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if condition == nil {
+		return result, nil
+	}
+
+	reqLogger.V(5).Info("Preflight result", "condition", condition, "result", result)
+	if err := helpers.UpdateManagedClusterImportPreflightCondition(
+		r.client,
+		managedCluster,
+		*condition,
+		r.mcRecorder,
+	); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	return result, nil
+}
+
 func (r *ReconcileAutoImport) getGenerateClientHolderFuncFromAutoImportSecret(
-	clusterName string, secret *corev1.Secret) (helpers.GenerateClientHolderFunc, error) {
+	clusterName string, secret *corev1.Secret, insecureRefused bool) (helpers.GenerateClientHolderFunc, error) {
+	generateClientFromKubeTokenSecret := func(secret *corev1.Secret) (
+		reconcile.Result, *helpers.ClientHolder, meta.RESTMapper, error) {
+		return helpers.GenerateImportClientFromKubeTokenSecret(secret, insecureRefused)
+	}
+	generateClientFromKubeConfigSecret := func(secret *corev1.Secret) (
+		reconcile.Result, *helpers.ClientHolder, meta.RESTMapper, error) {
+		return helpers.GenerateImportClientFromKubeConfigSecret(secret, insecureRefused)
+	}
+
 	switch secret.Type {
 	case corev1.SecretTypeOpaque:
 		// for compatibility, we parse the secret felids to determine which generator should be used
 		if _, hasKubeConfig := secret.Data[constants.AutoImportSecretKubeConfigKey]; hasKubeConfig {
-			return helpers.GenerateImportClientFromKubeConfigSecret, nil
+			return generateClientFromKubeConfigSecret, nil
 		}
 
 		_, hasKubeAPIToken := secret.Data[constants.AutoImportSecretKubeTokenKey]
 		_, hasKubeAPIServer := secret.Data[constants.AutoImportSecretKubeServerKey]
 		if hasKubeAPIToken && hasKubeAPIServer {
-			return helpers.GenerateImportClientFromKubeTokenSecret, nil
+			return generateClientFromKubeTokenSecret, nil
 		}
 
 		return nil, fmt.Errorf("kubeconfig or token/server pair is missing")
 	case constants.AutoImportSecretKubeConfig:
-		return helpers.GenerateImportClientFromKubeConfigSecret, nil
+		return generateClientFromKubeConfigSecret, nil
 	case constants.AutoImportSecretKubeToken:
-		return helpers.GenerateImportClientFromKubeTokenSecret, nil
+		return generateClientFromKubeTokenSecret, nil
 	case constants.AutoImportSecretRosaConfig:
 		getter, ok := r.rosaKubeConfigGetters[clusterName]
 		if !ok {
 			getter = helpers.NewRosaKubeConfigGetter()
 			r.rosaKubeConfigGetters[clusterName] = getter
 		}
+		getter.SetInsecureRefused(insecureRefused)
 
 		return func(secret *corev1.Secret) (reconcile.Result, *helpers.ClientHolder, meta.RESTMapper, error) {
 			return helpers.GenerateImportClientFromRosaCluster(getter, secret)
 		}, nil
+	case constants.AutoImportSecretOIDCConfig:
+		return func(secret *corev1.Secret) (reconcile.Result, *helpers.ClientHolder, meta.RESTMapper, error) {
+			return helpers.GenerateImportClientFromOIDCSecret(secret, insecureRefused)
+		}, nil
 	default:
 		return nil, fmt.Errorf("unsupported secret type %s", secret.Type)
 	}
 }
+
+// autoImportResult returns the auto import metrics result by the import condition
+func autoImportResult(condition metav1.Condition) string {
+	if helpers.ImportingResourcesApplied(&condition) {
+		return metrics.AutoImportResultSucceeded
+	}
+	if condition.Reason == constants.ConditionReasonManagedClusterImportFailed {
+		return metrics.AutoImportResultFailed
+	}
+	return metrics.AutoImportResultRequeued
+}
@@ -5,6 +5,7 @@ package autoimport
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -93,9 +94,12 @@ func TestReconcile(t *testing.T) {
 		works                   []runtime.Object
 		secrets                 []runtime.Object
 		autoImportStrategy      string
+		importSyncWindows       []helpers.ImportSyncWindow
 		expectedErr             bool
 		expectedConditionStatus metav1.ConditionStatus
 		expectedConditionReason string
+		expectedRequeueAfter    bool
+		expectedSyncPending     bool
 	}{
 		{
 			name:        "no cluster",
@@ -180,7 +184,7 @@ func TestReconcile(t *testing.T) {
 				},
 			},
 			autoImportStrategy:      apiconstants.AutoImportStrategyImportOnly,
-			expectedErr:             true,
+			expectedErr:             false,
 			expectedConditionStatus: metav1.ConditionFalse,
 			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
 		},
@@ -223,7 +227,7 @@ func TestReconcile(t *testing.T) {
 					Data: map[string][]byte{},
 				},
 			},
-			expectedErr:             true,
+			expectedErr:             false,
 			expectedConditionStatus: metav1.ConditionFalse,
 			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
 		},
@@ -352,7 +356,7 @@ func TestReconcile(t *testing.T) {
 					Type: constants.AutoImportSecretKubeConfig,
 				},
 			},
-			expectedErr:             true,
+			expectedErr:             false,
 			expectedConditionStatus: metav1.ConditionFalse,
 			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
 		},
@@ -395,7 +399,7 @@ func TestReconcile(t *testing.T) {
 					Type: constants.AutoImportSecretKubeToken,
 				},
 			},
-			expectedErr:             true,
+			expectedErr:             false,
 			expectedConditionStatus: metav1.ConditionFalse,
 			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
 		},
@@ -438,7 +442,7 @@ func TestReconcile(t *testing.T) {
 					Type: corev1.SecretTypeOpaque,
 				},
 			},
-			expectedErr:             true,
+			expectedErr:             false,
 			expectedConditionStatus: metav1.ConditionFalse,
 			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
 		},
@@ -478,7 +482,7 @@ func TestReconcile(t *testing.T) {
 					Type: constants.AutoImportSecretRosaConfig,
 				},
 			},
-			expectedErr:             true,
+			expectedErr:             false,
 			expectedConditionStatus: metav1.ConditionFalse,
 			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
 		},
@@ -633,6 +637,194 @@ func TestReconcile(t *testing.T) {
 			expectedConditionStatus: metav1.ConditionFalse,
 			expectedConditionReason: constants.ConditionReasonManagedClusterImporting,
 		},
+		{
+			name: "defer the sync out of the maintenance windows",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder(managedClusterName).
+					WithImportedCondition(true).
+					Build(),
+			},
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet-crds",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+			},
+			secrets: []runtime.Object{
+				testinghelpers.GetImportSecret(managedClusterName),
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "auto-import-secret",
+						Namespace: managedClusterName,
+					},
+					Data: map[string][]byte{
+						"kubeconfig": testinghelpers.BuildKubeconfig(config),
+					},
+					Type: constants.AutoImportSecretKubeConfig,
+				},
+			},
+			autoImportStrategy:      apiconstants.AutoImportStrategyImportAndSync,
+			importSyncWindows:       closedImportSyncWindows(t),
+			expectedErr:             false,
+			expectedConditionStatus: metav1.ConditionTrue,
+			expectedConditionReason: constants.ConditionReasonManagedClusterImported,
+			expectedRequeueAfter:    true,
+			expectedSyncPending:     true,
+		},
+		{
+			name: "sync in the maintenance windows",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder(managedClusterName).
+					WithImportedCondition(true).
+					Build(),
+			},
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet-crds",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+			},
+			secrets: []runtime.Object{
+				testinghelpers.GetImportSecret(managedClusterName),
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "auto-import-secret",
+						Namespace: managedClusterName,
+					},
+					Data: map[string][]byte{
+						"kubeconfig": testinghelpers.BuildKubeconfig(config),
+					},
+					Type: constants.AutoImportSecretKubeConfig,
+				},
+			},
+			autoImportStrategy:      apiconstants.AutoImportStrategyImportAndSync,
+			importSyncWindows:       openImportSyncWindows(t),
+			expectedErr:             false,
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
+		},
+		{
+			name: "immediate-import annotation bypasses the maintenance windows",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder(managedClusterName).
+					WithAnnotations(apiconstants.AnnotationImmediateImport, "").
+					WithImportedCondition(true).
+					Build(),
+			},
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet-crds",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+			},
+			secrets: []runtime.Object{
+				testinghelpers.GetImportSecret(managedClusterName),
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "auto-import-secret",
+						Namespace: managedClusterName,
+					},
+					Data: map[string][]byte{
+						"kubeconfig": testinghelpers.BuildKubeconfig(config),
+					},
+					Type: constants.AutoImportSecretKubeConfig,
+				},
+			},
+			autoImportStrategy:      apiconstants.AutoImportStrategyImportAndSync,
+			importSyncWindows:       closedImportSyncWindows(t),
+			expectedErr:             false,
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
+		},
+		{
+			name: "backup restore bypasses the maintenance windows",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder(managedClusterName).
+					WithImportedCondition(true).
+					Build(),
+			},
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet-crds",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+			},
+			secrets: []runtime.Object{
+				testinghelpers.GetImportSecret(managedClusterName),
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "auto-import-secret",
+						Namespace: managedClusterName,
+						Labels: map[string]string{
+							constants.LabelAutoImportRestore: "true",
+						},
+					},
+					Data: map[string][]byte{
+						"kubeconfig": testinghelpers.BuildKubeconfig(config),
+					},
+					Type: constants.AutoImportSecretKubeConfig,
+				},
+			},
+			autoImportStrategy: apiconstants.AutoImportStrategyImportAndSync,
+			importSyncWindows:  closedImportSyncWindows(t),
+			expectedErr:        false,
+		},
 	}
 
 	for _, c := range cases {
@@ -662,22 +854,53 @@ func TestReconcile(t *testing.T) {
 				},
 				eventstesting.NewTestingEventRecorder(t),
 				helpers.NewManagedClusterEventRecorder(ctx, kubeClient),
-				func() (strategy string, err error) {
+				func(_ *clusterv1.ManagedCluster) (strategy string, err error) {
 					if len(c.autoImportStrategy) > 0 {
 						return c.autoImportStrategy, nil
 					}
 					return constants.DefaultAutoImportStrategy, nil
 				},
+				func() (bool, error) {
+					return false, nil
+				},
+				func() (string, error) {
+					return constants.DefaultClusterTakeoverPolicy, nil
+				},
+				func(_ *clusterv1.ManagedCluster) ([]helpers.ImportSyncWindow, error) {
+					return c.importSyncWindows, nil
+				},
+				func(_ *corev1.Secret) (helpers.AutoImportRetryPolicy, error) {
+					return helpers.AutoImportRetryPolicy{
+						MaxAttempts: constants.DefaultAutoImportMaxAttempts,
+						BackoffBase: constants.DefaultAutoImportBackoffBase,
+						BackoffMax:  constants.DefaultAutoImportBackoffMax,
+					}, nil
+				},
 			)
 
 			req := reconcile.Request{NamespacedName: types.NamespacedName{Name: managedClusterName}}
-			_, err := r.Reconcile(ctx, req)
+			result, err := r.Reconcile(ctx, req)
 			if c.expectedErr && err == nil {
 				t.Errorf("name: %v, expected error, but failed", c.name)
 			}
 			if !c.expectedErr && err != nil {
 				t.Errorf("name: %v, unexpected error: %v", c.name, err)
 			}
+			if c.expectedRequeueAfter && result.RequeueAfter <= 0 {
+				t.Errorf("name: %v, expected requeue after, but got %v", c.name, result)
+			}
+
+			if len(c.importSyncWindows) > 0 {
+				managedCluster := &clusterv1.ManagedCluster{}
+				if err := r.client.Get(ctx, types.NamespacedName{Name: managedClusterName}, managedCluster); err != nil {
+					t.Errorf("name %v : get managed cluster error: %v", c.name, err)
+				}
+				syncPending := meta.IsStatusConditionTrue(managedCluster.Status.Conditions,
+					constants.ConditionManagedClusterImportSyncPending)
+				if syncPending != c.expectedSyncPending {
+					t.Errorf("name %v : expect sync pending %v, got %v", c.name, c.expectedSyncPending, syncPending)
+				}
+			}
 
 			if c.expectedConditionReason != "" {
 
@@ -703,3 +926,22 @@ func TestReconcile(t *testing.T) {
 		})
 	}
 }
+
+// closedImportSyncWindows returns a daily window which opens in an hour, so it is closed in the test
+func closedImportSyncWindows(t *testing.T) []helpers.ImportSyncWindow {
+	start := time.Now().UTC().Add(time.Hour)
+	windows, err := helpers.ParseImportSyncWindows(fmt.Sprintf("%d %d * * * 1m", start.Minute(), start.Hour()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return windows
+}
+
+// openImportSyncWindows returns a window which opens every minute, so it is always open in the test
+func openImportSyncWindows(t *testing.T) []helpers.ImportSyncWindow {
+	windows, err := helpers.ParseImportSyncWindows("* * * * * 1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return windows
+}
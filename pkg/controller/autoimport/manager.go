@@ -92,6 +92,13 @@ func Add(ctx context.Context,
 							return true
 						}
 
+						// handle the change of the preflight annotation, run the preflight checks once it is added
+						// and import the managed cluster once it is removed
+						if helpers.IsImportPreflight(e.ObjectOld.GetAnnotations()) !=
+							helpers.IsImportPreflight(e.ObjectNew.GetAnnotations()) {
+							return true
+						}
+
 						// handle the removal of the disable-auto-import annotation
 						_, oldAutoImportDisabled := e.ObjectOld.GetAnnotations()[apiconstants.DisableAutoImportAnnotation]
 						_, newAutoImportDisabled := e.ObjectNew.GetAnnotations()[apiconstants.DisableAutoImportAnnotation]
@@ -141,7 +148,12 @@ func Add(ctx context.Context,
 			informerHolder,
 			helpers.NewEventRecorder(clientHolder.KubeClient, ControllerName),
 			mcRecorder,
-			helpers.AutoImportStrategyGetter(componentNamespace, informerHolder.ControllerConfigLister, log),
+			helpers.AutoImportStrategyGetter(componentNamespace, informerHolder.ControllerConfigLister,
+				informerHolder.KlusterletConfigLister, log),
+			helpers.InsecureAutoImportRefusedGetter(componentNamespace, informerHolder.ControllerConfigLister, log),
+			helpers.ClusterTakeoverPolicyGetter(componentNamespace, informerHolder.ControllerConfigLister, log),
+			helpers.ImportSyncWindowsGetter(componentNamespace, informerHolder.ControllerConfigLister, log),
+			helpers.AutoImportRetryPolicyGetter(componentNamespace, informerHolder.ControllerConfigLister, log),
 		))
 
 	return err
@@ -14,6 +14,7 @@ import (
 	hivev1 "github.com/openshift/hive/apis/hive/v1"
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/openshift/library-go/pkg/operator/resource/resourcemerge"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -39,6 +40,7 @@ type ReconcileClusterDeployment struct {
 	mcRecorder               kevents.EventRecorder
 	importHelper             *helpers.ImportHelper
 	autoImportStrategyGetter helpers.AutoImportStrategyGetterFunc
+	importSyncWindowsGetter  helpers.ImportSyncWindowsGetterFunc
 }
 
 func NewReconcileClusterDeployment(
@@ -48,7 +50,19 @@ func NewReconcileClusterDeployment(
 	recorder events.Recorder,
 	mcRecorder kevents.EventRecorder,
 	autoImportStrategyGetter helpers.AutoImportStrategyGetterFunc,
+	clusterTakeoverPolicyGetter helpers.ClusterTakeoverPolicyGetterFunc,
+	importSyncWindowsGetter helpers.ImportSyncWindowsGetterFunc,
+	insecureRefusedGetter helpers.InsecureAutoImportRefusedGetterFunc,
 ) *ReconcileClusterDeployment {
+	// the admin kubeconfig of the clusterdeployment is refused if it is insecure and the insecure import is refused
+	generateClientFromKubeConfigSecret := func(secret *corev1.Secret) (
+		reconcile.Result, *helpers.ClientHolder, meta.RESTMapper, error) {
+		insecureRefused, err := insecureRefusedGetter()
+		if err != nil {
+			return reconcile.Result{}, nil, nil, err
+		}
+		return helpers.GenerateImportClientFromKubeConfigSecret(secret, insecureRefused)
+	}
 
 	return &ReconcileClusterDeployment{
 		client:         client,
@@ -57,8 +71,10 @@ func NewReconcileClusterDeployment(
 		recorder:       recorder,
 		mcRecorder:     mcRecorder,
 		importHelper: helpers.NewImportHelper(informerHolder, recorder, log).
-			WithGenerateClientHolderFunc(helpers.GenerateImportClientFromKubeConfigSecret),
+			WithGenerateClientHolderFunc(generateClientFromKubeConfigSecret).
+			WithClusterTakeoverPolicy(clusterTakeoverPolicyGetter, mcRecorder),
 		autoImportStrategyGetter: autoImportStrategyGetter,
+		importSyncWindowsGetter:  importSyncWindowsGetter,
 	}
 }
 
@@ -118,7 +134,7 @@ func (r *ReconcileClusterDeployment) Reconcile(
 	}
 
 	immediateImport := helpers.IsImmediateImport(managedCluster.Annotations)
-	autoImportStrategy, err := r.autoImportStrategyGetter()
+	autoImportStrategy, err := r.autoImportStrategyGetter(managedCluster)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -172,6 +188,19 @@ func (r *ReconcileClusterDeployment) Reconcile(
 		return reconcile.Result{}, err
 	}
 
+	if !immediateImport && importSucceeded {
+		// the importing resources are only synced to the imported managed cluster in the maintenance windows
+		requeueAfter, deferred, err := helpers.DeferImportSync(r.client, managedCluster, r.importSyncWindowsGetter)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if deferred {
+			reqLogger.Info("Auto import sync is deferred to the next maintenance window",
+				"managedCluster", managedCluster.Name, "requeueAfter", requeueAfter)
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
+		}
+	}
+
 	result, condition, modified, iErr := r.importHelper.Import(false, managedCluster, hiveSecret)
 	// if resources are applied but NOT modified, will not update the condition, keep the original condition.
 	// This check is to prevent the current controller and import status controller from modifying the
@@ -182,6 +211,10 @@ func (r *ReconcileClusterDeployment) Reconcile(
 			managedCluster,
 			condition,
 			r.mcRecorder,
+			helpers.ImportAttemptSource{
+				Controller: helpers.ImportSourceClusterDeployment,
+				SecretType: hiveSecret.Type,
+			},
 		); err != nil {
 			return reconcile.Result{}, err
 		}
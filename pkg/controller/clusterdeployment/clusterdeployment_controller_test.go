@@ -5,6 +5,7 @@ package clusterdeployment
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -63,8 +64,11 @@ func TestReconcile(t *testing.T) {
 		works                   []runtime.Object
 		secrets                 []runtime.Object
 		autoImportStrategy      string
+		importSyncWindows       []helpers.ImportSyncWindow
 		expectedErr             bool
 		expectedConditionReason string
+		expectedRequeueAfter    bool
+		expectedSyncPending     bool
 	}{
 		{
 			name:    "no clusterdeployment",
@@ -343,6 +347,183 @@ func TestReconcile(t *testing.T) {
 			expectedErr:             true,
 			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
 		},
+		{
+			name: "defer the sync out of the maintenance windows",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder("test").
+					WithImportedCondition(true).Build(),
+				&hivev1.ClusterDeployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "test",
+					},
+					Spec: hivev1.ClusterDeploymentSpec{
+						Installed: true,
+						ClusterMetadata: &hivev1.ClusterMetadata{
+							AdminKubeconfigSecretRef: corev1.LocalObjectReference{
+								Name: "test",
+							},
+						},
+					},
+				},
+			},
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet-crds",
+						Namespace: "test",
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet",
+						Namespace: "test",
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+			},
+			secrets: []runtime.Object{
+				testinghelpers.GetImportSecret("test"),
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token":  []byte(config.BearerToken),
+						"server": []byte(config.Host),
+					},
+				},
+			},
+			autoImportStrategy:      apiconstants.AutoImportStrategyImportAndSync,
+			importSyncWindows:       closedImportSyncWindows(t),
+			expectedErr:             false,
+			expectedConditionReason: constants.ConditionReasonManagedClusterImported,
+			expectedRequeueAfter:    true,
+			expectedSyncPending:     true,
+		},
+		{
+			name: "sync in the maintenance windows",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder("test").
+					WithImportedCondition(true).Build(),
+				&hivev1.ClusterDeployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "test",
+					},
+					Spec: hivev1.ClusterDeploymentSpec{
+						Installed: true,
+						ClusterMetadata: &hivev1.ClusterMetadata{
+							AdminKubeconfigSecretRef: corev1.LocalObjectReference{
+								Name: "test",
+							},
+						},
+					},
+				},
+			},
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet-crds",
+						Namespace: "test",
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet",
+						Namespace: "test",
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+			},
+			secrets: []runtime.Object{
+				testinghelpers.GetImportSecret("test"),
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token":  []byte(config.BearerToken),
+						"server": []byte(config.Host),
+					},
+				},
+			},
+			autoImportStrategy:      apiconstants.AutoImportStrategyImportAndSync,
+			importSyncWindows:       openImportSyncWindows(t),
+			expectedErr:             true,
+			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
+		},
+		{
+			name: "immediate-import annotation bypasses the maintenance windows",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder("test").
+					WithAnnotations(apiconstants.AnnotationImmediateImport, "").
+					WithImportedCondition(true).Build(),
+				&hivev1.ClusterDeployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "test",
+					},
+					Spec: hivev1.ClusterDeploymentSpec{
+						Installed: true,
+						ClusterMetadata: &hivev1.ClusterMetadata{
+							AdminKubeconfigSecretRef: corev1.LocalObjectReference{
+								Name: "test",
+							},
+						},
+					},
+				},
+			},
+			works: []runtime.Object{
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet-crds",
+						Namespace: "test",
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+				&workv1.ManifestWork{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-klusterlet",
+						Namespace: "test",
+						Labels: map[string]string{
+							constants.KlusterletWorksLabel: "true",
+						},
+					},
+				},
+			},
+			secrets: []runtime.Object{
+				testinghelpers.GetImportSecret("test"),
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token":  []byte(config.BearerToken),
+						"server": []byte(config.Host),
+					},
+				},
+			},
+			autoImportStrategy:      apiconstants.AutoImportStrategyImportAndSync,
+			importSyncWindows:       closedImportSyncWindows(t),
+			expectedErr:             true,
+			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
+		},
 	}
 
 	for _, c := range cases {
@@ -372,21 +553,45 @@ func TestReconcile(t *testing.T) {
 				},
 				eventstesting.NewTestingEventRecorder(t),
 				helpers.NewManagedClusterEventRecorder(ctx, kubeClient),
-				func() (strategy string, err error) {
+				func(_ *clusterv1.ManagedCluster) (strategy string, err error) {
 					if len(c.autoImportStrategy) > 0 {
 						return c.autoImportStrategy, nil
 					}
 					return constants.DefaultAutoImportStrategy, nil
 				},
+				func() (string, error) {
+					return constants.DefaultClusterTakeoverPolicy, nil
+				},
+				func(_ *clusterv1.ManagedCluster) ([]helpers.ImportSyncWindow, error) {
+					return c.importSyncWindows, nil
+				},
+				func() (bool, error) {
+					return false, nil
+				},
 			)
 
-			_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}})
+			result, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}})
 			if c.expectedErr && err == nil {
 				t.Errorf("name: %v, expected error, but failed", c.name)
 			}
 			if !c.expectedErr && err != nil {
 				t.Errorf("name: %v, unexpected error: %v", c.name, err)
 			}
+			if c.expectedRequeueAfter && result.RequeueAfter <= 0 {
+				t.Errorf("name: %v, expected requeue after, but got %v", c.name, result)
+			}
+
+			if len(c.importSyncWindows) > 0 {
+				managedCluster := &clusterv1.ManagedCluster{}
+				if err := r.client.Get(context.TODO(), types.NamespacedName{Name: "test"}, managedCluster); err != nil {
+					t.Errorf("name %v : get managed cluster error: %v", c.name, err)
+				}
+				syncPending := meta.IsStatusConditionTrue(managedCluster.Status.Conditions,
+					constants.ConditionManagedClusterImportSyncPending)
+				if syncPending != c.expectedSyncPending {
+					t.Errorf("name %v : expect sync pending %v, got %v", c.name, c.expectedSyncPending, syncPending)
+				}
+			}
 
 			if c.expectedConditionReason != "" {
 				managedCluster := &clusterv1.ManagedCluster{}
@@ -407,3 +612,22 @@ func TestReconcile(t *testing.T) {
 		})
 	}
 }
+
+// closedImportSyncWindows returns a daily window which opens in an hour, so it is closed in the test
+func closedImportSyncWindows(t *testing.T) []helpers.ImportSyncWindow {
+	start := time.Now().UTC().Add(time.Hour)
+	windows, err := helpers.ParseImportSyncWindows(fmt.Sprintf("%d %d * * * 1m", start.Minute(), start.Hour()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return windows
+}
+
+// openImportSyncWindows returns a window which opens every minute, so it is always open in the test
+func openImportSyncWindows(t *testing.T) []helpers.ImportSyncWindow {
+	windows, err := helpers.ParseImportSyncWindows("* * * * * 1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return windows
+}
@@ -147,7 +147,11 @@ func Add(ctx context.Context,
 			informerHolder,
 			helpers.NewEventRecorder(clientHolder.KubeClient, ControllerName),
 			mcRecorder,
-			helpers.AutoImportStrategyGetter(componentNamespace, informerHolder.ControllerConfigLister, log),
+			helpers.AutoImportStrategyGetter(componentNamespace, informerHolder.ControllerConfigLister,
+				informerHolder.KlusterletConfigLister, log),
+			helpers.ClusterTakeoverPolicyGetter(componentNamespace, informerHolder.ControllerConfigLister, log),
+			helpers.ImportSyncWindowsGetter(componentNamespace, informerHolder.ControllerConfigLister, log),
+			helpers.InsecureAutoImportRefusedGetter(componentNamespace, informerHolder.ControllerConfigLister, log),
 		))
 
 	return err
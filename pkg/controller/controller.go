@@ -12,6 +12,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/stolostron/managedcluster-import-controller/pkg/controller/agentregistration"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/autoimport"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/clusterdeployment"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/clusternamespacedeletion"
@@ -50,6 +51,14 @@ func AddToManager(ctx context.Context,
 		})
 	}
 
+	agentRegistrationUser := ""
+	if features.DefaultMutableFeatureGate.Enabled(features.AgentRegistration) {
+		// If agent registration is enabled, a csr from the agent registration bootstrap user is approved when it is
+		// created with an unused registration token issued for its cluster, otherwise it is denied.
+		agentRegistrationUser = fmt.Sprintf("system:serviceaccount:%s:%s", componentNamespace,
+			agentregistration.AgentRegistrationDefaultBootstrapSAName)
+	}
+
 	AddToManagerFuncs := []struct {
 		ControllerName string
 		Add            func() error
@@ -57,7 +66,8 @@ func AddToManager(ctx context.Context,
 		{
 			csr.ControllerName,
 			func() error {
-				return csr.Add(ctx, manager, clientHolder, extraCSRApprovalConditions)
+				return csr.Add(ctx, manager, clientHolder, informerHolder, componentNamespace, mcRecorder,
+					agentRegistrationUser, extraCSRApprovalConditions)
 			},
 		},
 		{
@@ -66,7 +76,7 @@ func AddToManager(ctx context.Context,
 		},
 		{
 			importconfig.ControllerName,
-			func() error { return importconfig.Add(ctx, manager, clientHolder, informerHolder) },
+			func() error { return importconfig.Add(ctx, manager, clientHolder, informerHolder, mcRecorder) },
 		},
 		{
 			manifestwork.ControllerName,
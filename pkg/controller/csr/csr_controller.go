@@ -5,9 +5,14 @@ package csr
 
 import (
 	"context"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/openshift/library-go/pkg/operator/events"
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 
 	certificatesv1 "k8s.io/api/certificates/v1"
@@ -15,6 +20,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	kevents "k8s.io/client-go/tools/events"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -23,6 +29,18 @@ import (
 
 const (
 	userNameSignature = "system:serviceaccount:%s:%s"
+
+	// subjectPrefix is the prefix of the subject common name and organization of the CSR from a managed cluster
+	subjectPrefix = "system:open-cluster-management:"
+)
+
+// the reasons of the denied bootstrap CSRs
+const (
+	csrDeniedReasonUsernameMismatch         = "BootstrapUsernameMismatch"
+	csrDeniedReasonInvalidSubject           = "InvalidSubject"
+	csrDeniedReasonManagedClusterNotFound   = "ManagedClusterNotFound"
+	csrDeniedReasonManagedClusterDeleting   = "ManagedClusterDeleting"
+	csrDeniedReasonRegistrationTokenInvalid = "RegistrationTokenInvalid"
 )
 
 var log = logf.Log.WithName("controller_csr")
@@ -52,6 +70,89 @@ func isValidUnapprovedBootstrapCSR(csr *certificatesv1.CertificateSigningRequest
 		getApprovalType(csr) == ""
 }
 
+// isBootstrapSAUsername checks if the CSR is requested by the bootstrap service account of a managed cluster,
+// the bootstrap service account is in the namespace of the managed cluster.
+func isBootstrapSAUsername(username string) bool {
+	parts := strings.Split(username, ":")
+	return len(parts) == 4 && fmt.Sprintf(userNameSignature, parts[2], parts[3]) == username &&
+		strings.HasSuffix(parts[3], "-"+helpers.BootstrapSASuffix)
+}
+
+// validateBootstrapCSR validates the CSR which is requested by the bootstrap service account of a managed cluster or
+// by the agent registration bootstrap user, the reason and message are returned if the CSR is invalid and should be
+// denied. The CSRs which are not requested by a bootstrap service account are not validated.
+func (r *ReconcileCSR) validateBootstrapCSR(ctx context.Context,
+	csr *certificatesv1.CertificateSigningRequest) (string, string, error) {
+	if r.isAgentRegistrationCSR(csr) {
+		// the registration token of the CSR is consumed, so the CSR is approved with it only once
+		message, err := helpers.ConsumeRegistrationToken(ctx, r.clientHolder.KubeClient, r.registrationTokenNamespace,
+			csr)
+		if err != nil {
+			return "", "", err
+		}
+		if len(message) > 0 {
+			return csrDeniedReasonRegistrationTokenInvalid, fmt.Sprintf(
+				"The registration token of the CSR is invalid: %s", message), nil
+		}
+		return "", "", nil
+	}
+
+	if !isBootstrapSAUsername(csr.Spec.Username) {
+		return "", "", nil
+	}
+
+	clusterName := helpers.GetClusterName(csr)
+	if !validUsername(csr, clusterName) {
+		return csrDeniedReasonUsernameMismatch, fmt.Sprintf(
+			"The requesting user %s is not the bootstrap service account of the managed cluster %s",
+			csr.Spec.Username, clusterName), nil
+	}
+
+	if err := validateSubject(csr, clusterName); err != nil {
+		return csrDeniedReasonInvalidSubject, fmt.Sprintf("The subject of the CSR is invalid: %v", err), nil
+	}
+
+	cluster := clusterv1.ManagedCluster{}
+	err := r.clientHolder.RuntimeClient.Get(ctx, types.NamespacedName{Name: clusterName}, &cluster)
+	if errors.IsNotFound(err) {
+		return csrDeniedReasonManagedClusterNotFound, fmt.Sprintf(
+			"The managed cluster %s is not found", clusterName), nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if !cluster.DeletionTimestamp.IsZero() {
+		return csrDeniedReasonManagedClusterDeleting, fmt.Sprintf(
+			"The managed cluster %s is being deleted", clusterName), nil
+	}
+	return "", "", nil
+}
+
+// validateSubject checks if the subject of the CSR is requested by the registration agent of the managed cluster,
+// the common name is system:open-cluster-management:<cluster name>:<agent name> and the organizations contains
+// system:open-cluster-management:<cluster name>.
+func validateSubject(csr *certificatesv1.CertificateSigningRequest, clusterName string) error {
+	block, _ := pem.Decode(csr.Spec.Request)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return fmt.Errorf("the request is not a PEM encoded certificate request")
+	}
+	request, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return err
+	}
+
+	clusterSubject := subjectPrefix + clusterName
+	agentName, ok := strings.CutPrefix(request.Subject.CommonName, clusterSubject+":")
+	if !ok || len(agentName) == 0 {
+		return fmt.Errorf("the common name %q is not in the format %s:<agent name>",
+			request.Subject.CommonName, clusterSubject)
+	}
+	if !slices.Contains(request.Subject.Organization, clusterSubject) {
+		return fmt.Errorf("the organizations %v do not contain %s", request.Subject.Organization, clusterSubject)
+	}
+	return nil
+}
+
 // approveExistingManagedClusterCSR checks if the CSR is from an existing managed cluster
 func approveExistingManagedClusterCSR(ctx context.Context, csr *certificatesv1.CertificateSigningRequest,
 	clientHolder *helpers.ClientHolder) (bool, error) {
@@ -72,9 +173,21 @@ func approveExistingManagedClusterCSR(ctx context.Context, csr *certificatesv1.C
 
 // ReconcileCSR reconciles the managed cluster CSR object
 type ReconcileCSR struct {
-	clientHolder       *helpers.ClientHolder
-	recorder           events.Recorder
-	approvalConditions []func(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (bool, error)
+	clientHolder         *helpers.ClientHolder
+	recorder             events.Recorder
+	mcRecorder           kevents.EventRecorder
+	approvalPolicyGetter helpers.CSRApprovalPolicyGetterFunc
+	approvalConditions   []func(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (bool, error)
+
+	// agentRegistrationUser is the bootstrap user of the agent registration, it is empty if the agent registration
+	// is disabled. Its CSRs are approved with the registration tokens in the registrationTokenNamespace.
+	agentRegistrationUser      string
+	registrationTokenNamespace string
+}
+
+// isAgentRegistrationCSR checks if the CSR is requested by the agent registration bootstrap user
+func (r *ReconcileCSR) isAgentRegistrationCSR(csr *certificatesv1.CertificateSigningRequest) bool {
+	return len(r.agentRegistrationUser) > 0 && csr.Spec.Username == r.agentRegistrationUser
 }
 
 // blank assignment to verify that ReconcileCSR implements reconcile.Reconciler
@@ -88,9 +201,7 @@ var _ reconcile.Reconciler = &ReconcileCSR{}
 func (r *ReconcileCSR) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	reqLogger := log.WithValues("Request.Name", request.Name)
 
-	csrReq := r.clientHolder.KubeClient.CertificatesV1().CertificateSigningRequests()
-
-	csr, err := csrReq.Get(ctx, request.Name, metav1.GetOptions{})
+	csr, err := r.clientHolder.KubeClient.CertificatesV1().CertificateSigningRequests().Get(ctx, request.Name, metav1.GetOptions{})
 	if errors.IsNotFound(err) {
 		return reconcile.Result{}, nil
 	}
@@ -101,6 +212,39 @@ func (r *ReconcileCSR) Reconcile(ctx context.Context, request reconcile.Request)
 		return reconcile.Result{}, nil
 	}
 
+	// The approval policy takes precedence over the approval conditions
+	if r.approvalPolicyGetter != nil {
+		policy, err := r.approvalPolicyGetter()
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if rule := policy.Match(csr); rule != nil {
+			reqLogger.V(5).Info("CSR is matched by the approval policy", "rule", rule.Name, "action", rule.Action)
+			if rule.Action == helpers.CSRApprovalActionDeny {
+				return reconcile.Result{}, r.deny(ctx, csr, "DeniedByPolicyRule:"+rule.Name,
+					fmt.Sprintf("The CSR is denied by the rule %q of the CSR approval policy", rule.Name))
+			}
+			return reconcile.Result{}, r.approve(ctx, csr, "AutoApprovedByPolicyRule:"+rule.Name,
+				fmt.Sprintf("The CSR is approved by the rule %q of the CSR approval policy", rule.Name))
+		}
+	}
+
+	// Deny the invalid CSRs of the bootstrap service accounts, so the agents fail fast instead of waiting forever
+	reason, message, err := r.validateBootstrapCSR(ctx, csr)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if len(reason) > 0 {
+		reqLogger.Info("Deny the invalid bootstrap CSR", "reason", reason, "message", message)
+		return reconcile.Result{}, r.deny(ctx, csr, reason, message)
+	}
+
+	// The CSR of the agent registration bootstrap user is valid once its registration token is consumed
+	if r.isAgentRegistrationCSR(csr) {
+		return reconcile.Result{}, r.approve(ctx, csr, "AutoApprovedWithRegistrationToken",
+			"The CSR is approved with the registration token issued for the managed cluster")
+	}
+
 	// Check if any approval condition matches
 	shouldApprove := false
 	for _, condition := range r.approvalConditions {
@@ -120,20 +264,56 @@ func (r *ReconcileCSR) Reconcile(ctx context.Context, request reconcile.Request)
 
 	reqLogger.V(5).Info("Reconciling CSR")
 
+	return reconcile.Result{}, r.approve(ctx, csr, "AutoApprovedByCSRController",
+		"The managedcluster-import-controller auto approval automatically approved this CSR")
+}
+
+func (r *ReconcileCSR) approve(ctx context.Context, csr *certificatesv1.CertificateSigningRequest,
+	reason, message string) error {
 	csr = csr.DeepCopy()
 	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
 		Type:           certificatesv1.CertificateApproved,
 		Status:         corev1.ConditionTrue,
-		Reason:         "AutoApprovedByCSRController",
-		Message:        "The managedcluster-import-controller auto approval automatically approved this CSR",
+		Reason:         reason,
+		Message:        message,
 		LastUpdateTime: metav1.Now(),
 	})
-	if _, err := csrReq.UpdateApproval(ctx, csr.Name, csr, metav1.UpdateOptions{}); err != nil {
-		return reconcile.Result{}, err
+	if _, err := r.clientHolder.KubeClient.CertificatesV1().CertificateSigningRequests().UpdateApproval(
+		ctx, csr.Name, csr, metav1.UpdateOptions{}); err != nil {
+		return err
 	}
 
 	r.recorder.Eventf("ManagedClusterCSRAutoApproved", "managed cluster csr %q is auto approved by import controller", csr.Name)
-	return reconcile.Result{}, nil
+	return nil
+}
+
+func (r *ReconcileCSR) deny(ctx context.Context, csr *certificatesv1.CertificateSigningRequest,
+	reason, message string) error {
+	csr = csr.DeepCopy()
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:           certificatesv1.CertificateDenied,
+		Status:         corev1.ConditionTrue,
+		Reason:         reason,
+		Message:        message,
+		LastUpdateTime: metav1.Now(),
+	})
+	if _, err := r.clientHolder.KubeClient.CertificatesV1().CertificateSigningRequests().UpdateApproval(
+		ctx, csr.Name, csr, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+
+	r.recorder.Eventf("ManagedClusterCSRDenied", "managed cluster csr %q is denied by import controller: %s",
+		csr.Name, message)
+
+	clusterName := helpers.GetClusterName(csr)
+	if r.mcRecorder != nil && len(clusterName) > 0 {
+		// the managed cluster may not exist, the event is recorded with its name
+		mc := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: clusterName, Namespace: clusterName}}
+		r.mcRecorder.Eventf(mc, nil, corev1.EventTypeWarning,
+			constants.EventReasonManagedClusterCSRDenied, constants.EventActionDenyCSR,
+			"The CSR %s of the %s is denied, reason: %s, %s", csr.Name, clusterName, reason, message)
+	}
+	return nil
 }
 
 // check whether a CSR is in terminal state
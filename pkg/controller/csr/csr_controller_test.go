@@ -5,9 +5,17 @@ package csr
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/openshift/library-go/pkg/operator/events/eventstesting"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
@@ -15,12 +23,14 @@ import (
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 
 	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	fakeclientset "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	kevents "k8s.io/client-go/tools/events"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -32,6 +42,23 @@ const (
 	clusterName      = "mycluster"
 )
 
+func newTestCertificateRequest(t *testing.T, clusterName string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:   subjectPrefix + clusterName + ":agent",
+			Organization: []string{subjectPrefix + clusterName, "system:open-cluster-management:managed-clusters"},
+		},
+	}, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate request: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
 func TestReconcileCSR_Reconcile(t *testing.T) {
 
 	testCSR := &certificatesv1.CertificateSigningRequest{
@@ -43,6 +70,7 @@ func TestReconcileCSR_Reconcile(t *testing.T) {
 		},
 		Spec: certificatesv1.CertificateSigningRequestSpec{
 			Username: fmt.Sprintf(userNameSignature, clusterName, helpers.GetBootstrapSAName(clusterName)),
+			Request:  newTestCertificateRequest(t, clusterName),
 		},
 	}
 
@@ -54,7 +82,7 @@ func TestReconcileCSR_Reconcile(t *testing.T) {
 			},
 		},
 		Spec: certificatesv1.CertificateSigningRequestSpec{
-			Username: fmt.Sprintf(userNameSignature, "specialCluster", helpers.GetBootstrapSAName("specialCluster")),
+			Username: fmt.Sprintf(userNameSignature, "open-cluster-management", "flightctl-agent-registration"),
 		},
 	}
 
@@ -188,8 +216,8 @@ func TestReconcileCSR_Reconcile(t *testing.T) {
 						t.Error("CSR not approved")
 					}
 				case "testCSRClusterNotFound":
-					if len(csr.Status.Conditions) != 0 {
-						t.Error("CSR should not have been approved")
+					if len(csr.Status.Conditions) != 1 || csr.Status.Conditions[0].Type != certificatesv1.CertificateDenied {
+						t.Error("CSR should have been denied")
 					}
 				default:
 					t.Error("Case not tested")
@@ -402,3 +430,358 @@ func Test_isValidUnapprovedBootstrapCSR(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcileCSR_ApprovalPolicy(t *testing.T) {
+	policy, err := helpers.ParseCSRApprovalPolicy(`
+rules:
+- name: deny-prod
+  action: Deny
+  clusterNamePattern: prod-.*
+- name: approve-dev
+  action: Approve
+  clusterNamePattern: dev-.*
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cases := []struct {
+		name              string
+		clusterName       string
+		expectedCondition certificatesv1.RequestConditionType
+		expectedReason    string
+	}{
+		{
+			name:              "denied by policy",
+			clusterName:       "prod-1",
+			expectedCondition: certificatesv1.CertificateDenied,
+			expectedReason:    "DeniedByPolicyRule:deny-prod",
+		},
+		{
+			name:              "approved by policy",
+			clusterName:       "dev-1",
+			expectedCondition: certificatesv1.CertificateApproved,
+			expectedReason:    "AutoApprovedByPolicyRule:approve-dev",
+		},
+		{
+			name:              "approved by approval conditions",
+			clusterName:       "cluster1",
+			expectedCondition: certificatesv1.CertificateApproved,
+			expectedReason:    "AutoApprovedByCSRController",
+		},
+		{
+			name:        "not matched",
+			clusterName: "cluster2",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			csr := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: csrNameReconcile,
+					Labels: map[string]string{
+						constants.CSRClusterNameLabel: c.clusterName,
+					},
+				},
+			}
+			kubeClient := fakeclientset.NewSimpleClientset(csr)
+			r := &ReconcileCSR{
+				clientHolder: &helpers.ClientHolder{KubeClient: kubeClient},
+				recorder:     eventstesting.NewTestingEventRecorder(t),
+				approvalPolicyGetter: func() (*helpers.CSRApprovalPolicy, error) {
+					return policy, nil
+				},
+				approvalConditions: []func(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (bool, error){
+					func(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (bool, error) {
+						return helpers.GetClusterName(csr) == "cluster1", nil
+					},
+				},
+			}
+
+			if _, err := r.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: csrNameReconcile},
+			}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			csr, err := kubeClient.CertificatesV1().CertificateSigningRequests().Get(
+				context.TODO(), csrNameReconcile, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(c.expectedCondition) == 0 {
+				if len(csr.Status.Conditions) != 0 {
+					t.Errorf("expected no condition, but got %v", csr.Status.Conditions)
+				}
+				return
+			}
+			if len(csr.Status.Conditions) != 1 {
+				t.Fatalf("expected one condition, but got %v", csr.Status.Conditions)
+			}
+			if csr.Status.Conditions[0].Type != c.expectedCondition || csr.Status.Conditions[0].Reason != c.expectedReason {
+				t.Errorf("expected condition %s with reason %s, but got %v", c.expectedCondition, c.expectedReason,
+					csr.Status.Conditions[0])
+			}
+		})
+	}
+}
+
+func TestReconcileCSR_DenyInvalidBootstrapCSR(t *testing.T) {
+	testscheme := scheme.Scheme
+	testscheme.AddKnownTypes(clusterv1.SchemeGroupVersion, &clusterv1.ManagedCluster{})
+
+	bootstrapUser := fmt.Sprintf(userNameSignature, clusterName, helpers.GetBootstrapSAName(clusterName))
+	now := metav1.Now()
+	cases := []struct {
+		name           string
+		username       string
+		request        []byte
+		clusters       []client.Object
+		expectedReason string
+	}{
+		{
+			name:     "valid csr",
+			username: bootstrapUser,
+			request:  newTestCertificateRequest(t, clusterName),
+			clusters: []client.Object{&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: clusterName}}},
+		},
+		{
+			name:     "not a bootstrap user",
+			username: "system:serviceaccount:open-cluster-management:agent-registration-bootstrap",
+		},
+		{
+			name:           "bootstrap user of another cluster",
+			username:       fmt.Sprintf(userNameSignature, "other", helpers.GetBootstrapSAName("other")),
+			request:        newTestCertificateRequest(t, clusterName),
+			clusters:       []client.Object{&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: clusterName}}},
+			expectedReason: csrDeniedReasonUsernameMismatch,
+		},
+		{
+			name:           "invalid request",
+			username:       bootstrapUser,
+			request:        []byte("invalid"),
+			clusters:       []client.Object{&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: clusterName}}},
+			expectedReason: csrDeniedReasonInvalidSubject,
+		},
+		{
+			name:           "subject of another cluster",
+			username:       bootstrapUser,
+			request:        newTestCertificateRequest(t, "other"),
+			clusters:       []client.Object{&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: clusterName}}},
+			expectedReason: csrDeniedReasonInvalidSubject,
+		},
+		{
+			name:           "cluster not found",
+			username:       bootstrapUser,
+			request:        newTestCertificateRequest(t, clusterName),
+			expectedReason: csrDeniedReasonManagedClusterNotFound,
+		},
+		{
+			name:     "cluster is deleting",
+			username: bootstrapUser,
+			request:  newTestCertificateRequest(t, clusterName),
+			clusters: []client.Object{&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{
+				Name:              clusterName,
+				DeletionTimestamp: &now,
+				Finalizers:        []string{"test"},
+			}}},
+			expectedReason: csrDeniedReasonManagedClusterDeleting,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			csr := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: csrNameReconcile,
+					Labels: map[string]string{
+						constants.CSRClusterNameLabel: clusterName,
+					},
+				},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Username: c.username,
+					Request:  c.request,
+				},
+			}
+			kubeClient := fakeclientset.NewSimpleClientset(csr)
+			mcRecorder := kevents.NewFakeRecorder(10)
+			r := &ReconcileCSR{
+				clientHolder: &helpers.ClientHolder{
+					KubeClient:    kubeClient,
+					RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(c.clusters...).Build(),
+				},
+				recorder:   eventstesting.NewTestingEventRecorder(t),
+				mcRecorder: mcRecorder,
+			}
+
+			if _, err := r.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: csrNameReconcile},
+			}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			csr, err := kubeClient.CertificatesV1().CertificateSigningRequests().Get(
+				context.TODO(), csrNameReconcile, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(c.expectedReason) == 0 {
+				if len(csr.Status.Conditions) != 0 {
+					t.Errorf("expected no condition, but got %v", csr.Status.Conditions)
+				}
+				return
+			}
+			if len(csr.Status.Conditions) != 1 || csr.Status.Conditions[0].Type != certificatesv1.CertificateDenied ||
+				csr.Status.Conditions[0].Reason != c.expectedReason {
+				t.Errorf("expected the csr is denied with reason %s, but got %v", c.expectedReason,
+					csr.Status.Conditions)
+			}
+			select {
+			case event := <-mcRecorder.Events:
+				if !strings.Contains(event, constants.EventReasonManagedClusterCSRDenied) {
+					t.Errorf("unexpected event %s", event)
+				}
+			default:
+				t.Errorf("expected a managed cluster event")
+			}
+		})
+	}
+}
+
+func TestReconcileCSR_AgentRegistration(t *testing.T) {
+	agentRegistrationUser := "system:serviceaccount:open-cluster-management:agent-registration-bootstrap"
+	newTokenSecret := func(tokenID, clusterName, usedBy string, expiration time.Time) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "registration-token-" + tokenID,
+				Namespace: "open-cluster-management",
+				Labels:    map[string]string{constants.LabelRegistrationTokenID: tokenID},
+				Annotations: map[string]string{
+					constants.AnnotationRegistrationTokenCluster:    clusterName,
+					constants.AnnotationRegistrationTokenExpiration: expiration.UTC().Format(time.RFC3339),
+				},
+			},
+			Type: constants.RegistrationTokenSecretType,
+		}
+		if len(usedBy) > 0 {
+			secret.Annotations[constants.AnnotationRegistrationTokenUsedBy] = usedBy
+		}
+		return secret
+	}
+
+	notExpired := time.Now().Add(time.Hour)
+	cases := []struct {
+		name             string
+		username         string
+		tokenID          string
+		secrets          []runtime.Object
+		expectedApproved bool
+		expectedReason   string
+	}{
+		{
+			name:             "valid token",
+			username:         agentRegistrationUser,
+			tokenID:          "token-1",
+			secrets:          []runtime.Object{newTokenSecret("token-1", clusterName, "", notExpired)},
+			expectedApproved: true,
+			expectedReason:   "AutoApprovedWithRegistrationToken",
+		},
+		{
+			name:           "no token",
+			username:       agentRegistrationUser,
+			secrets:        []runtime.Object{newTokenSecret("token-1", clusterName, "", notExpired)},
+			expectedReason: csrDeniedReasonRegistrationTokenInvalid,
+		},
+		{
+			name:           "revoked token",
+			username:       agentRegistrationUser,
+			tokenID:        "token-1",
+			expectedReason: csrDeniedReasonRegistrationTokenInvalid,
+		},
+		{
+			name:           "token used by another csr",
+			username:       agentRegistrationUser,
+			tokenID:        "token-1",
+			secrets:        []runtime.Object{newTokenSecret("token-1", clusterName, "other-csr", notExpired)},
+			expectedReason: csrDeniedReasonRegistrationTokenInvalid,
+		},
+		{
+			name:           "expired token",
+			username:       agentRegistrationUser,
+			tokenID:        "token-1",
+			secrets:        []runtime.Object{newTokenSecret("token-1", clusterName, "", time.Now().Add(-time.Hour))},
+			expectedReason: csrDeniedReasonRegistrationTokenInvalid,
+		},
+		{
+			name:           "token of another cluster",
+			username:       agentRegistrationUser,
+			tokenID:        "token-1",
+			secrets:        []runtime.Object{newTokenSecret("token-1", "other", "", notExpired)},
+			expectedReason: csrDeniedReasonRegistrationTokenInvalid,
+		},
+		{
+			name:     "not the agent registration user",
+			username: "system:serviceaccount:open-cluster-management:other",
+			tokenID:  "token-1",
+			secrets:  []runtime.Object{newTokenSecret("token-1", clusterName, "", notExpired)},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			csr := &certificatesv1.CertificateSigningRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: csrNameReconcile,
+					Labels: map[string]string{
+						constants.CSRClusterNameLabel: clusterName,
+					},
+				},
+				Spec: certificatesv1.CertificateSigningRequestSpec{
+					Username: c.username,
+				},
+			}
+			if len(c.tokenID) > 0 {
+				csr.Spec.Extra = map[string]certificatesv1.ExtraValue{
+					"authentication.kubernetes.io/credential-id": {"JTI=" + c.tokenID},
+				}
+			}
+			kubeClient := fakeclientset.NewSimpleClientset(append(c.secrets, csr)...)
+			r := &ReconcileCSR{
+				clientHolder: &helpers.ClientHolder{
+					KubeClient: kubeClient,
+				},
+				recorder:                   eventstesting.NewTestingEventRecorder(t),
+				agentRegistrationUser:      agentRegistrationUser,
+				registrationTokenNamespace: "open-cluster-management",
+			}
+
+			if _, err := r.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: csrNameReconcile},
+			}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			csr, err := kubeClient.CertificatesV1().CertificateSigningRequests().Get(
+				context.TODO(), csrNameReconcile, metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(c.expectedReason) == 0 {
+				if len(csr.Status.Conditions) != 0 {
+					t.Errorf("expected no condition, but got %v", csr.Status.Conditions)
+				}
+				return
+			}
+			expectedType := certificatesv1.CertificateDenied
+			if c.expectedApproved {
+				expectedType = certificatesv1.CertificateApproved
+			}
+			if len(csr.Status.Conditions) != 1 || csr.Status.Conditions[0].Type != expectedType ||
+				csr.Status.Conditions[0].Reason != c.expectedReason {
+				t.Errorf("expected the csr is %s with reason %s, but got %v", expectedType, c.expectedReason,
+					csr.Status.Conditions)
+			}
+		})
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"context"
 
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/source"
 	certificatesv1 "k8s.io/api/certificates/v1"
+	kevents "k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -21,10 +23,15 @@ const (
 )
 
 // Add creates a new CSR Controller and adds it to the Manager. The Manager will set fields on the Controller
-// and Start it when the Manager is Started.
+// and Start it when the Manager is Started. The CSRs of the agentRegistrationUser are approved or denied with the
+// registration tokens, it is empty if the agent registration is disabled.
 func Add(ctx context.Context,
 	mgr manager.Manager,
 	clientHolder *helpers.ClientHolder,
+	informerHolder *source.InformerHolder,
+	componentNamespace string,
+	mcRecorder kevents.EventRecorder,
+	agentRegistrationUser string,
 	extraApprovalConditions []func(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (bool, error)) error {
 
 	err := ctrl.NewControllerManagedBy(mgr).Named(ControllerName).
@@ -47,12 +54,17 @@ func Add(ctx context.Context,
 		Complete(&ReconcileCSR{
 			clientHolder: clientHolder,
 			recorder:     helpers.NewEventRecorder(clientHolder.KubeClient, ControllerName),
+			mcRecorder:   mcRecorder,
+			approvalPolicyGetter: helpers.CSRApprovalPolicyGetter(componentNamespace,
+				informerHolder.ControllerConfigLister, log),
 			approvalConditions: append([]func(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (bool, error){
 				// The DEFAULT approval condition: if a CSR comes from a managed cluster, and the managed cluster already exists, approve it
 				func(ctx context.Context, csr *certificatesv1.CertificateSigningRequest) (bool, error) {
 					return approveExistingManagedClusterCSR(ctx, csr, clientHolder)
 				},
 			}, extraApprovalConditions...),
+			agentRegistrationUser:      agentRegistrationUser,
+			registrationTokenNamespace: componentNamespace,
 		})
 
 	return err
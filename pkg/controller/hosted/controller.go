@@ -109,6 +109,7 @@ func (r *ReconcileHosted) Reconcile(ctx context.Context, request reconcile.Reque
 				constants.ConditionReasonManagedClusterWaitForImporting,
 				"Wait for importing"),
 			r.mcRecorder,
+			helpers.ImportAttemptSource{Controller: helpers.ImportSourceHosted},
 		)
 	}
 
@@ -120,11 +121,17 @@ func (r *ReconcileHosted) Reconcile(ctx context.Context, request reconcile.Reque
 	}
 
 	result, condition, iErr := r.importCluster(ctx, managedCluster, autoImportSecret)
+
+	source := helpers.ImportAttemptSource{Controller: helpers.ImportSourceHosted}
+	if autoImportSecret != nil {
+		source.SecretType = autoImportSecret.Type
+	}
 	if err := helpers.UpdateManagedClusterImportCondition(
 		r.clientHolder.RuntimeClient,
 		managedCluster,
 		condition,
 		r.mcRecorder,
+		source,
 	); err != nil {
 		return reconcile.Result{}, err
 	}
@@ -15,6 +15,7 @@ import (
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog/v2"
@@ -40,7 +41,9 @@ func extractBootstrapKubeConfigDataFromImportSecret(importSecret *corev1.Secret)
 	for _, yaml := range helpers.SplitYamls(importYaml) {
 		obj := helpers.MustCreateObject(yaml)
 		if secret, ok := obj.(*corev1.Secret); ok {
-			if secret.Name == constants.DefaultBootstrapHubKubeConfigSecretName {
+			// the bootstrap kubeconfig is in the first endpoint secret if the hub has additional kube apiserver URLs
+			if secret.Name == constants.DefaultBootstrapHubKubeConfigSecretName ||
+				secret.Name == bootstrap.EndpointBootstrapKubeConfigSecretName(0) {
 				return secret.Data["kubeconfig"]
 			}
 		}
@@ -78,7 +81,17 @@ func parseKubeConfigData(kubeConfigData []byte) (
 	return
 }
 
-func validateToken(token string, creation, expiration []byte) bool {
+// bootstrapKubeconfig is the bootstrap kubeconfig of a managed cluster with the data of its token and CA rotation,
+// they are saved in the import secret.
+type bootstrapKubeconfig struct {
+	kubeconfigData      []byte
+	tokenCreation       []byte
+	tokenExpiration     []byte
+	tokenRevocation     []byte
+	caRotationStartedAt []byte
+}
+
+func validateToken(token string, creation, expiration []byte, tokenPolicy helpers.BootstrapTokenPolicy) bool {
 	if len(token) == 0 {
 		// no token in the kubeconfig
 		return false
@@ -88,49 +101,78 @@ func validateToken(token string, creation, expiration []byte) bool {
 		// token is from the service account token secret
 		return true
 	}
-	expirationTime, err := time.Parse(time.RFC3339, string(expiration))
+
+	refreshTime, err := getTokenRefreshTime(creation, expiration, tokenPolicy)
 	if err != nil {
-		klog.Errorf("failed to parse expiration time: %v", err)
+		klog.Errorf("failed to get the refresh time of the token: %v", err)
 		return false
 	}
 
-	refreshThreshold := constants.DefaultSecretTokenRefreshThreshold
+	// refresh the token if its lifetime is longer than the required one, e.g. the lifetime is shortened
 	if len(creation) != 0 {
-		creationTime, err := time.Parse(time.RFC3339, string(creation))
-		if err != nil {
-			klog.Errorf("failed to parse creation time: %v", err)
+		creationTime, _ := time.Parse(time.RFC3339, string(creation))
+		expirationTime, _ := time.Parse(time.RFC3339, string(expiration))
+		if expirationTime.Sub(creationTime) > tokenPolicy.Lifetime+time.Minute {
 			return false
 		}
+	}
+
+	return time.Now().Before(refreshTime)
+}
 
-		refreshThreshold = expirationTime.Sub(creationTime) / 5
+// getTokenRefreshTime returns when the token should be refreshed, it is when the refresh ratio of the token
+// lifetime remains. A zero time is returned if the token does not expire.
+func getTokenRefreshTime(creation, expiration []byte, tokenPolicy helpers.BootstrapTokenPolicy) (time.Time, error) {
+	if len(expiration) == 0 {
+		return time.Time{}, nil
 	}
+	expirationTime, err := time.Parse(time.RFC3339, string(expiration))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse expiration time: %v", err)
+	}
+
+	var lifetime time.Duration
+	if len(creation) != 0 {
+		creationTime, err := time.Parse(time.RFC3339, string(creation))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse creation time: %v", err)
+		}
 
-	lifetime := time.Until(expirationTime)
-	return lifetime > refreshThreshold
+		lifetime = expirationTime.Sub(creationTime)
+	}
+	return expirationTime.Add(-tokenPolicy.RefreshThreshold(lifetime)), nil
 }
 
 func buildBootstrapKubeconfigData(ctx context.Context, clientHolder *helpers.ClientHolder,
 	managedCluster *clusterv1.ManagedCluster,
-	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig) ([]byte, []byte, []byte, error) {
-	var bootstrapKubeconfigData, tokenData, tokenCreation, tokenExpiration []byte
+	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig,
+	caRotationOverlap time.Duration, tokenPolicy helpers.BootstrapTokenPolicy) (*bootstrapKubeconfig, error) {
+	var tokenData []byte
+	result := &bootstrapKubeconfig{}
 
 	// get the import secret
 	importSecret, err := getImportSecret(ctx, clientHolder, managedCluster.Name)
 	if err != nil && !apierrors.IsNotFound(err) {
-		return nil, nil, nil, err
+		return nil, err
 	}
 
 	// get the latest kube apiserver configuration
 	requiredKubeAPIServer, requiredProxyURL, requiredCA, requiredCAData, err := bootstrap.GetKubeAPIServerConfig(
 		ctx, clientHolder, managedCluster.Name, klusterletConfig, isSelfManaged(managedCluster))
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
+	}
+
+	requiredAdditionalConfigs, err := bootstrap.GetAdditionalKubeAPIServerConfigs(ctx, clientHolder,
+		managedCluster.Name, klusterletConfig, requiredKubeAPIServer, isSelfManaged(managedCluster))
+	if err != nil {
+		return nil, err
 	}
 
 	// get the cluster name in the kubeconfig
 	requiredCtxClusterName, err := bootstrap.GetKubeconfigClusterName(ctx, clientHolder.RuntimeClient)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 
 	if importSecret == nil {
@@ -140,17 +182,36 @@ func buildBootstrapKubeconfigData(ctx context.Context, clientHolder *helpers.Cli
 	// check if the bootstrap kubeconfig and token in the import secret are still valid
 	if kubeconfigData := extractBootstrapKubeConfigDataFromImportSecret(importSecret); len(kubeconfigData) > 0 {
 		kubeAPIServer, proxyURL, ca, caData, tokenString, ctxClusterName, err := parseKubeConfigData(kubeconfigData)
+		var additionalConfigs []bootstrap.KubeAPIServerConfig
+		if err == nil {
+			additionalConfigs, err = bootstrap.GetAdditionalKubeAPIServerConfigsFromKubeconfig(kubeconfigData)
+		}
 		if err != nil {
 			klog.Infof("failed to parse the bootstrap hub kubeconfig in the import.yaml. Recreation is required: %v", err)
 		} else {
-			// use the existing token if it is still valid
+			// keep the certificates removed from the CA data in the overlap period of the CA rotation
+			requiredCAData, result.caRotationStartedAt, err = rotateBootstrapCAData(caData, requiredCAData,
+				additionalConfigs, requiredAdditionalConfigs,
+				importSecret.Data[constants.ImportSecretCARotationStartedAt], caRotationOverlap)
+			if err != nil {
+				return nil, err
+			}
+
+			// use the existing token if it is still valid, a revoked token is kept until the cluster is not
+			// imported anymore
 			creation := importSecret.Data[constants.ImportSecretTokenCreation]
 			expiration := importSecret.Data[constants.ImportSecretTokenExpiration]
-			if valid := validateToken(tokenString, creation, expiration); valid {
+			revocation := importSecret.Data[constants.ImportSecretTokenRevocation]
+			switch {
+			case len(revocation) != 0 && !isImported(managedCluster):
+				klog.Infof("token should be refreshed for the managed cluster %s, the token is revoked at %v",
+					managedCluster.Name, string(revocation))
+			case len(revocation) != 0 || validateToken(tokenString, creation, expiration, tokenPolicy):
 				tokenData = []byte(tokenString)
-				tokenCreation = creation
-				tokenExpiration = expiration
-			} else {
+				result.tokenCreation = creation
+				result.tokenExpiration = expiration
+				result.tokenRevocation = revocation
+			default:
 				klog.Infof("token should be refreshed for the managed cluster %s, creation: %v, expiration: %v",
 					managedCluster.Name, string(creation), string(expiration))
 			}
@@ -158,8 +219,9 @@ func buildBootstrapKubeconfigData(ctx context.Context, clientHolder *helpers.Cli
 			// use the kubeconfig if it is still valid
 			if valid := bootstrap.ValidateBootstrapKubeconfig(managedCluster.Name,
 				kubeAPIServer, proxyURL, ca, caData, ctxClusterName,
-				requiredKubeAPIServer, requiredProxyURL, requiredCA, requiredCAData, requiredCtxClusterName); valid {
-				bootstrapKubeconfigData = kubeconfigData
+				requiredKubeAPIServer, requiredProxyURL, requiredCA, requiredCAData, requiredCtxClusterName,
+				additionalConfigs, requiredAdditionalConfigs); valid {
+				result.kubeconfigData = kubeconfigData
 			}
 		}
 	}
@@ -167,28 +229,142 @@ func buildBootstrapKubeconfigData(ctx context.Context, clientHolder *helpers.Cli
 	// retrieve the non-expiring token if available or generate a new one.
 	if len(tokenData) == 0 {
 		klog.Infof("create a new token for the managed cluster %s", managedCluster.Name)
-		tokenData, tokenCreation, tokenExpiration, err = bootstrap.GetBootstrapToken(ctx, clientHolder.KubeClient,
-			helpers.GetBootstrapSAName(managedCluster.Name),
-			managedCluster.Name, constants.DefaultSecretTokenExpirationSecond)
+		tokenData, result.tokenCreation, result.tokenExpiration, err = bootstrap.GetBootstrapToken(ctx,
+			clientHolder.KubeClient, helpers.GetBootstrapSAName(managedCluster.Name),
+			managedCluster.Name, int64(tokenPolicy.Lifetime.Seconds()))
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, err
 		}
 
 		// reset the bootstrap kubeconfig to trigger the re since the token is updated
-		bootstrapKubeconfigData = nil
+		result.kubeconfigData = nil
 	}
 
 	// create a new bootstrap kubeconfig if it is invalid or missing
-	if len(bootstrapKubeconfigData) == 0 {
+	if len(result.kubeconfigData) == 0 {
 		klog.Infof("create a new bootstrap kubeconfig for the managed cluster %s", managedCluster.Name)
-		bootstrapKubeconfigData, err = bootstrap.CreateBootstrapKubeConfig(requiredCtxClusterName,
-			requiredKubeAPIServer, requiredProxyURL, requiredCA, requiredCAData, tokenData)
+		result.kubeconfigData, err = bootstrap.CreateBootstrapKubeConfig(requiredCtxClusterName,
+			requiredKubeAPIServer, requiredProxyURL, requiredCA, requiredCAData, tokenData, requiredAdditionalConfigs...)
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// revokeBootstrapToken revokes the tokens of the bootstrap service account of the managed cluster by recreating the
+// service account, the tokens which are bound to the old service account, including the tokens in the legacy
+// service account token secrets, are invalid once it is deleted.
+func revokeBootstrapToken(ctx context.Context, clientHolder *helpers.ClientHolder,
+	managedCluster *clusterv1.ManagedCluster) error {
+	saName := helpers.GetBootstrapSAName(managedCluster.Name)
+	sa, err := clientHolder.KubeClient.CoreV1().ServiceAccounts(managedCluster.Name).Get(ctx, saName,
+		metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	klog.Infof("revoke the bootstrap token of the managed cluster %s", managedCluster.Name)
+	err = clientHolder.KubeClient.CoreV1().ServiceAccounts(managedCluster.Name).Delete(ctx, saName,
+		metav1.DeleteOptions{Preconditions: metav1.NewUIDPreconditions(string(sa.UID))})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// isImported checks if the ManagedClusterImportSucceeded condition of the managed cluster is true
+func isImported(managedCluster *clusterv1.ManagedCluster) bool {
+	return meta.IsStatusConditionTrue(managedCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+}
+
+// rotateBootstrapCAData returns the required CA data with the certificates removed from the existing CA data in the
+// overlap period of the CA rotation, the CA data of the required additional kube apiservers are rotated in place.
+// It also returns when the rotation is started, it is empty if there is no rotation in progress.
+func rotateBootstrapCAData(caData, requiredCAData []byte,
+	additionalConfigs, requiredAdditionalConfigs []bootstrap.KubeAPIServerConfig,
+	rotationStartedAt []byte, overlap time.Duration) ([]byte, []byte, error) {
+	now := time.Now()
+	rotatedCAData, startedAt, err := bootstrap.RotateCAData(caData, requiredCAData, string(rotationStartedAt),
+		overlap, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range requiredAdditionalConfigs {
+		for _, config := range additionalConfigs {
+			if config.URL != requiredAdditionalConfigs[i].URL {
+				continue
+			}
+
+			var additionalStartedAt string
+			requiredAdditionalConfigs[i].CAData, additionalStartedAt, err = bootstrap.RotateCAData(config.CAData,
+				requiredAdditionalConfigs[i].CAData, string(rotationStartedAt), overlap, now)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(startedAt) == 0 {
+				startedAt = additionalStartedAt
+			}
 		}
 	}
 
-	return bootstrapKubeconfigData, tokenCreation, tokenExpiration, nil
+	if len(startedAt) == 0 {
+		return rotatedCAData, nil, nil
+	}
+	return rotatedCAData, []byte(startedAt), nil
+}
+
+// getBootstrapCredentials returns when the token and the CA bundles in the bootstrap kubeconfig expire, and when the
+// bootstrap kubeconfig is rotated next time, i.e. the token is refreshed or the overlap period of the CA rotation
+// passes, whichever comes first. A revoked token is not refreshed.
+func getBootstrapCredentials(kubeconfig *bootstrapKubeconfig, caRotationOverlap time.Duration,
+	tokenPolicy helpers.BootstrapTokenPolicy) (helpers.BootstrapCredentials, error) {
+	credentials := helpers.BootstrapCredentials{}
+
+	var err error
+	if len(kubeconfig.tokenCreation) > 0 {
+		if credentials.TokenCreation, err = time.Parse(time.RFC3339, string(kubeconfig.tokenCreation)); err != nil {
+			return credentials, fmt.Errorf("failed to parse creation time: %v", err)
+		}
+	}
+	if len(kubeconfig.tokenExpiration) > 0 {
+		if credentials.TokenExpiration, err = time.Parse(time.RFC3339, string(kubeconfig.tokenExpiration)); err != nil {
+			return credentials, fmt.Errorf("failed to parse expiration time: %v", err)
+		}
+	}
+	if len(kubeconfig.tokenRevocation) > 0 {
+		if credentials.TokenRevocation, err = time.Parse(time.RFC3339, string(kubeconfig.tokenRevocation)); err != nil {
+			return credentials, fmt.Errorf("failed to parse revocation time: %v", err)
+		}
+	}
+
+	if credentials.CAExpiration, err = bootstrap.GetBootstrapCAExpiry(kubeconfig.kubeconfigData); err != nil {
+		return credentials, err
+	}
+
+	if credentials.TokenRevocation.IsZero() {
+		credentials.NextRotation, err = getTokenRefreshTime(kubeconfig.tokenCreation, kubeconfig.tokenExpiration,
+			tokenPolicy)
+		if err != nil {
+			return credentials, err
+		}
+	}
+	if len(kubeconfig.caRotationStartedAt) > 0 {
+		startedAt, err := time.Parse(time.RFC3339, string(kubeconfig.caRotationStartedAt))
+		if err != nil {
+			return credentials, fmt.Errorf("failed to parse CA rotation started time: %v", err)
+		}
+		if caRotationEnd := startedAt.Add(caRotationOverlap); credentials.NextRotation.IsZero() ||
+			caRotationEnd.Before(credentials.NextRotation) {
+			credentials.NextRotation = caRotationEnd
+		}
+	}
+	return credentials, nil
 }
 
 func isSelfManaged(managedCluster *clusterv1.ManagedCluster) bool {
@@ -203,53 +379,23 @@ func isSelfManaged(managedCluster *clusterv1.ManagedCluster) bool {
 
 func buildImportSecret(ctx context.Context, clientHolder *helpers.ClientHolder, managedCluster *clusterv1.ManagedCluster,
 	mode operatorv1.InstallMode, klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig,
-	bootstrapKubeconfigData, tokenCreation, tokenExpiration []byte) (*corev1.Secret, error) {
-	var yamlcontent, crdsYAML []byte
-	var secretAnnotations map[string]string
-	var err error
-	switch mode {
-	case operatorv1.InstallModeDefault, operatorv1.InstallModeSingleton:
-		supportPriorityClass, err := helpers.SupportPriorityClass(managedCluster)
-		if err != nil {
-			return nil, err
-		}
-		var priorityClassName string
-		if supportPriorityClass {
-			priorityClassName = constants.DefaultKlusterletPriorityClassName
-		}
-		config := bootstrap.NewKlusterletManifestsConfig(
-			mode,
-			managedCluster.Name,
-			bootstrapKubeconfigData).
-			WithManagedCluster(managedCluster).
-			WithKlusterletConfig(klusterletConfig).
-			WithPriorityClassName(priorityClassName)
-		yamlcontent, crdsYAML, err = config.Generate(ctx, clientHolder)
-		if err != nil {
-			return nil, err
-		}
-
-	case operatorv1.InstallModeHosted, operatorv1.InstallModeSingletonHosted:
-		yamlcontent, _, err = bootstrap.NewKlusterletManifestsConfig(
-			mode,
-			managedCluster.Name,
-			bootstrapKubeconfigData).
-			WithManagedCluster(managedCluster).
-			WithoutImagePullSecretGenerate().
-			// the hosting cluster should support PriorityClass API and have
-			// already had the default PriorityClass
-			WithPriorityClassName(constants.DefaultKlusterletPriorityClassName).
-			WithKlusterletConfig(klusterletConfig).
-			Generate(ctx, clientHolder)
-		if err != nil {
-			return nil, err
-		}
+	kubeconfig *bootstrapKubeconfig) (*corev1.Secret, error) {
+	config, err := bootstrap.NewManagedClusterKlusterletManifestsConfig(mode, managedCluster, klusterletConfig,
+		kubeconfig.kubeconfigData)
+	if err != nil {
+		return nil, err
+	}
+	// the CRDs are not generated in the hosted mode
+	yamlcontent, crdsYAML, err := config.Generate(ctx, clientHolder)
+	if err != nil {
+		return nil, err
+	}
 
+	var secretAnnotations map[string]string
+	if mode == operatorv1.InstallModeHosted || mode == operatorv1.InstallModeSingletonHosted {
 		secretAnnotations = map[string]string{
 			constants.KlusterletDeployModeAnnotation: string(operatorv1.InstallModeHosted),
 		}
-	default:
-		return nil, fmt.Errorf("klusterlet deploy mode %s not supported", mode)
 	}
 
 	// generate import secret
@@ -269,11 +415,17 @@ func buildImportSecret(ctx context.Context, clientHolder *helpers.ClientHolder,
 		},
 	}
 
-	if len(tokenCreation) != 0 {
-		importSecret.Data[constants.ImportSecretTokenCreation] = tokenCreation
+	if len(kubeconfig.tokenCreation) != 0 {
+		importSecret.Data[constants.ImportSecretTokenCreation] = kubeconfig.tokenCreation
+	}
+	if len(kubeconfig.tokenExpiration) != 0 {
+		importSecret.Data[constants.ImportSecretTokenExpiration] = kubeconfig.tokenExpiration
+	}
+	if len(kubeconfig.tokenRevocation) != 0 {
+		importSecret.Data[constants.ImportSecretTokenRevocation] = kubeconfig.tokenRevocation
 	}
-	if len(tokenExpiration) != 0 {
-		importSecret.Data[constants.ImportSecretTokenExpiration] = tokenExpiration
+	if len(kubeconfig.caRotationStartedAt) != 0 {
+		importSecret.Data[constants.ImportSecretCARotationStartedAt] = kubeconfig.caRotationStartedAt
 	}
 	return importSecret, nil
 }
@@ -304,11 +304,13 @@ func TestBuildBootstrapKubeconfigData(t *testing.T) {
 				}
 			}
 
-			kubeconfigData, _, _, err := buildBootstrapKubeconfigData(context.Background(), clientHolder, cluster, tt.klusterletConfig) // cluster.Name = testcluster
+			kubeconfig, err := buildBootstrapKubeconfigData(context.Background(), clientHolder, cluster,
+				tt.klusterletConfig, 0, helpers.DefaultBootstrapTokenPolicy()) // cluster.Name = testcluster
 			if err != nil {
 				t.Errorf("buildBootstrapKubeconfigData() error = %v", err)
 				return
 			}
+			kubeconfigData := kubeconfig.kubeconfigData
 
 			if tt.want == nil {
 				if kubeconfigData == nil {
@@ -449,6 +451,7 @@ func TestValidateToken(t *testing.T) {
 		name                 string
 		token                string
 		creation, expiration []byte
+		tokenPolicy          *helpers.BootstrapTokenPolicy
 		expectedResult       bool
 	}{
 		{
@@ -513,13 +516,123 @@ func TestValidateToken(t *testing.T) {
 			expiration:     timeToString(time.Now().Add(71 * time.Hour * 24)),
 			expectedResult: false,
 		},
+		{
+			name:           "custom refresh ratio, not expired",
+			token:          "abc",
+			expiration:     timeToString(time.Now().Add(1 * time.Hour)),
+			creation:       timeToString(time.Now().Add(-59 * time.Minute)),
+			tokenPolicy:    &helpers.BootstrapTokenPolicy{Lifetime: 2 * time.Hour, RefreshRatio: 0.5},
+			expectedResult: true,
+		},
+		{
+			name:           "custom refresh ratio, expired",
+			token:          "abc",
+			expiration:     timeToString(time.Now().Add(1 * time.Hour)),
+			creation:       timeToString(time.Now().Add(-61 * time.Minute)),
+			tokenPolicy:    &helpers.BootstrapTokenPolicy{Lifetime: 2 * time.Hour, RefreshRatio: 0.5},
+			expectedResult: false,
+		},
+		{
+			name:           "lifetime is shortened",
+			token:          "abc",
+			expiration:     timeToString(time.Now().Add(300 * time.Hour * 24)),
+			creation:       timeToString(time.Now().Add(-60 * time.Hour * 24)),
+			tokenPolicy:    &helpers.BootstrapTokenPolicy{Lifetime: 30 * time.Hour * 24, RefreshRatio: 0.2},
+			expectedResult: false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.expectedResult != validateToken(tt.token, tt.creation, tt.expiration) {
+			tokenPolicy := helpers.DefaultBootstrapTokenPolicy()
+			if tt.tokenPolicy != nil {
+				tokenPolicy = *tt.tokenPolicy
+			}
+			if tt.expectedResult != validateToken(tt.token, tt.creation, tt.expiration, tokenPolicy) {
 				t.Errorf("validateToken() expected %v, got %v", tt.expectedResult, tt.expectedResult)
 			}
 		})
 	}
 }
+
+func TestGetBootstrapCredentials(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	creation := now.Add(-10 * time.Hour)
+	expiration := now.Add(90 * time.Hour)
+
+	cases := []struct {
+		name                 string
+		creation, expiration []byte
+		caRotationStartedAt  []byte
+		expectedCredentials  helpers.BootstrapCredentials
+		expectedErr          bool
+	}{
+		{
+			name:                "token does not expire",
+			expectedCredentials: helpers.BootstrapCredentials{},
+		},
+		{
+			name:       "token refresh",
+			creation:   timeToString(creation),
+			expiration: timeToString(expiration),
+			expectedCredentials: helpers.BootstrapCredentials{
+				TokenCreation:   creation,
+				TokenExpiration: expiration,
+				NextRotation:    now.Add(70 * time.Hour),
+			},
+		},
+		{
+			name:                "ca rotation ends before the token refresh",
+			creation:            timeToString(creation),
+			expiration:          timeToString(expiration),
+			caRotationStartedAt: timeToString(now.Add(-1 * time.Hour)),
+			expectedCredentials: helpers.BootstrapCredentials{
+				TokenCreation:   creation,
+				TokenExpiration: expiration,
+				NextRotation:    now.Add(23 * time.Hour),
+			},
+		},
+		{
+			name:                "ca rotation with a non-expiring token",
+			caRotationStartedAt: timeToString(now.Add(-1 * time.Hour)),
+			expectedCredentials: helpers.BootstrapCredentials{
+				NextRotation: now.Add(23 * time.Hour),
+			},
+		},
+		{
+			name:        "invalid expiration",
+			creation:    timeToString(creation),
+			expiration:  []byte("abc"),
+			expectedErr: true,
+		},
+	}
+
+	kubeconfigData, err := bootstrap.CreateBootstrapKubeConfig("hub", "https://api.example.com:6443", "", "",
+		nil, []byte("token"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			credentials, err := getBootstrapCredentials(&bootstrapKubeconfig{
+				kubeconfigData:      kubeconfigData,
+				tokenCreation:       c.creation,
+				tokenExpiration:     c.expiration,
+				caRotationStartedAt: c.caRotationStartedAt,
+			}, 24*time.Hour, helpers.DefaultBootstrapTokenPolicy())
+			if (err != nil) != c.expectedErr {
+				t.Fatalf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if c.expectedErr {
+				return
+			}
+			if !credentials.TokenCreation.Equal(c.expectedCredentials.TokenCreation) ||
+				!credentials.TokenExpiration.Equal(c.expectedCredentials.TokenExpiration) ||
+				!credentials.CAExpiration.Equal(c.expectedCredentials.CAExpiration) ||
+				!credentials.NextRotation.Equal(c.expectedCredentials.NextRotation) {
+				t.Errorf("expected %v, but got %v", c.expectedCredentials, credentials)
+			}
+		})
+	}
+}
@@ -5,10 +5,12 @@ package importconfig
 
 import (
 	"context"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	kevents "k8s.io/client-go/tools/events"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -17,10 +19,9 @@ import (
 
 	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/metrics"
 
 	listerklusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
-
-	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
 )
 
 var log = logf.Log.WithName(ControllerName)
@@ -31,6 +32,9 @@ type ReconcileImportConfig struct {
 	klusterletconfigLister listerklusterletconfigv1alpha1.KlusterletConfigLister
 	scheme                 *runtime.Scheme
 	recorder               events.Recorder
+	mcRecorder             kevents.EventRecorder
+	caRotationPolicyGetter helpers.CARotationPolicyGetterFunc
+	tokenPolicyGetter      helpers.BootstrapTokenPolicyGetterFunc
 }
 
 // blank assignment to verify that ReconcileImportConfig implements reconcile.Reconciler
@@ -46,6 +50,7 @@ func (r *ReconcileImportConfig) Reconcile(ctx context.Context, request reconcile
 	managedCluster := &clusterv1.ManagedCluster{}
 	err := r.clientHolder.RuntimeClient.Get(ctx, types.NamespacedName{Name: request.Name}, managedCluster)
 	if errors.IsNotFound(err) {
+		metrics.DeleteBootstrapCredentialExpirations(request.Name)
 		return reconcile.Result{}, nil
 	}
 	if err != nil {
@@ -70,32 +75,115 @@ func (r *ReconcileImportConfig) Reconcile(ctx context.Context, request reconcile
 		return reconcile.Result{}, err
 	}
 
-	klusterletconfigName := managedCluster.GetAnnotations()[apiconstants.AnnotationKlusterletConfig]
-
-	// Get the merged KlusterletConfig, it merges the user assigned KlusterletConfig with the global KlusterletConfig.
-	mergedKlusterletConfig, err := helpers.GetMergedKlusterletConfigWithGlobal(klusterletconfigName, r.klusterletconfigLister)
+	// Get the merged KlusterletConfig, it merges the global KlusterletConfig, the KlusterletConfigs selected by the
+	// managed cluster selector and the user assigned KlusterletConfig.
+	mergedKlusterletConfig, err := helpers.GetMergedKlusterletConfig(managedCluster, r.klusterletconfigLister)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 
+	// the CA rotation mode is disabled and the CA expiry is not tracked if there is no CA rotation policy
+	var caRotationPolicy helpers.CARotationPolicy
+	if r.caRotationPolicyGetter != nil {
+		caRotationPolicy, err = r.caRotationPolicyGetter()
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	tokenPolicy := helpers.DefaultBootstrapTokenPolicy()
+	if r.tokenPolicyGetter != nil {
+		tokenPolicy, err = r.tokenPolicyGetter(mergedKlusterletConfig)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
 	// build the bootstrap kubeconfig
-	bootstrapKubeconfigData, tokenCreation, tokenExpiration, err := buildBootstrapKubeconfigData(ctx, r.clientHolder,
-		managedCluster, mergedKlusterletConfig)
+	kubeconfig, err := buildBootstrapKubeconfigData(ctx, r.clientHolder, managedCluster, mergedKlusterletConfig,
+		caRotationPolicy.Overlap, tokenPolicy)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 
+	// revoke the bootstrap token once the managed cluster is imported in the ephemeral mode
+	if tokenPolicy.Ephemeral && isImported(managedCluster) && len(kubeconfig.tokenRevocation) == 0 {
+		if err := revokeBootstrapToken(ctx, r.clientHolder, managedCluster); err != nil {
+			return reconcile.Result{}, err
+		}
+		kubeconfig.tokenRevocation = []byte(time.Now().UTC().Format(time.RFC3339))
+
+		// recreate the bootstrap service account for the next import
+		if _, err := helpers.ApplyResources(
+			r.clientHolder, r.recorder, r.scheme, managedCluster, objects...); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
 	// rebuild the import secret and save it if it is modified
 	importSecret, err := buildImportSecret(ctx, r.clientHolder, managedCluster, mode, mergedKlusterletConfig,
-		bootstrapKubeconfigData, tokenCreation, tokenExpiration)
+		kubeconfig)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// report the expiration of the bootstrap credentials with the annotations of the import secret
+	credentials, err := getBootstrapCredentials(kubeconfig, caRotationPolicy.Overlap, tokenPolicy)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
+	if importSecret.Annotations == nil {
+		importSecret.Annotations = map[string]string{}
+	}
+	for key, value := range credentials.Annotations() {
+		importSecret.Annotations[key] = value
+	}
 
 	if _, err := helpers.ApplyResources(
 		r.clientHolder, r.recorder, r.scheme, managedCluster, importSecret); err != nil {
 		return reconcile.Result{}, err
 	}
 
+	now := time.Now()
+	if err := helpers.UpdateManagedClusterBootstrapCredentialsCondition(r.clientHolder.RuntimeClient, managedCluster,
+		helpers.NewManagedClusterBootstrapCredentialsCondition(credentials, now)); err != nil {
+		return reconcile.Result{}, err
+	}
+	tokenExpiration := credentials.TokenExpiration
+	if !credentials.TokenRevocation.IsZero() {
+		// the revoked token is not counted
+		tokenExpiration = time.Time{}
+	}
+	metrics.RecordBootstrapCredentialExpiration(managedCluster.Name, metrics.BootstrapCredentialToken,
+		tokenExpiration)
+	metrics.RecordBootstrapCredentialExpiration(managedCluster.Name, metrics.BootstrapCredentialCA,
+		credentials.CAExpiration)
+
+	// requeue the managed cluster to rotate the bootstrap kubeconfig as planned, e.g. refresh the token, or drop
+	// the removed certificates once the overlap period of the CA rotation passes
+	requeueAfter := credentials.NextRotation.Sub(now)
+	if credentials.NextRotation.IsZero() {
+		requeueAfter = 0
+	}
+
+	// track the expiry of the CA bundle in the bootstrap kubeconfig
+	if r.caRotationPolicyGetter != nil && !credentials.CAExpiration.IsZero() {
+		cond := helpers.NewManagedClusterBootstrapCACondition(credentials.CAExpiration,
+			caRotationPolicy.ExpiryWarningThreshold, now)
+		if err := helpers.UpdateManagedClusterBootstrapCACondition(r.clientHolder.RuntimeClient, managedCluster,
+			cond, r.mcRecorder); err != nil {
+			return reconcile.Result{}, err
+		}
+
+		// requeue the managed cluster to warn about the expiry once the threshold is reached
+		if warnAfter := credentials.CAExpiration.Add(-caRotationPolicy.ExpiryWarningThreshold).Sub(now); warnAfter > 0 &&
+			(requeueAfter <= 0 || warnAfter < requeueAfter) {
+			requeueAfter = warnAfter
+		}
+	}
+
+	if requeueAfter > 0 {
+		return reconcile.Result{RequeueAfter: requeueAfter}, nil
+	}
 	return reconcile.Result{}, nil
 }
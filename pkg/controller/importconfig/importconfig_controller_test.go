@@ -26,17 +26,22 @@ import (
 	hivev1 "github.com/openshift/hive/apis/hive/v1"
 	operatorv1 "open-cluster-management.io/api/operator/v1"
 
+	authv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	kubeinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
 
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
@@ -196,6 +201,19 @@ func TestReconcile(t *testing.T) {
 						t.Errorf("expected bootstrap secret data %v, but got empty", string(data))
 					}
 				}
+
+				if len(importSecret.Annotations[constants.AnnotationBootstrapCAExpiration]) == 0 {
+					t.Errorf("expected the annotation %s, but got %v", constants.AnnotationBootstrapCAExpiration,
+						importSecret.Annotations)
+				}
+				managedCluster := &clusterv1.ManagedCluster{}
+				if err := client.Get(context.TODO(), types.NamespacedName{Name: "test"}, managedCluster); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if !meta.IsStatusConditionTrue(managedCluster.Status.Conditions,
+					constants.ConditionManagedClusterBootstrapCredentialsValid) {
+					t.Errorf("expected the bootstrap credentials are valid, but got %v", managedCluster.Status.Conditions)
+				}
 			},
 		},
 		{
@@ -791,6 +809,109 @@ func TestReconcile(t *testing.T) {
 
 			},
 		},
+		{
+			name: "ephemeral bootstrap token",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							apiconstants.AnnotationKlusterletConfig: "test-klusterletconfig",
+						},
+					},
+					Status: clusterv1.ManagedClusterStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:               constants.ConditionManagedClusterImportSucceeded,
+								Status:             metav1.ConditionTrue,
+								Reason:             constants.ConditionReasonManagedClusterImported,
+								LastTransitionTime: metav1.Now(),
+							},
+						},
+					},
+				},
+				&configv1.Infrastructure{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "test",
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kube-root-ca.crt",
+						Namespace: "test",
+					},
+					Data: map[string]string{
+						"ca.crt": string(rootCACertData),
+					},
+				},
+			},
+			klusterletconfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-klusterletconfig",
+					Annotations: map[string]string{
+						constants.AnnotationBootstrapTokenLifetime:  "1h",
+						constants.AnnotationBootstrapTokenEphemeral: "true",
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				importSecret, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if len(importSecret.Data[constants.ImportSecretTokenRevocation]) == 0 {
+					t.Errorf("expected the token is revoked, but got %v", importSecret.Data)
+				}
+
+				deleted := false
+				for _, action := range kubeClient.(*kubefake.Clientset).Actions() {
+					if action.GetVerb() == "delete" && action.GetResource().Resource == "serviceaccounts" {
+						deleted = true
+					}
+					if action.GetVerb() == "create" && action.GetSubresource() == "token" {
+						tokenRequest := action.(clienttesting.CreateAction).GetObject().(*authv1.TokenRequest)
+						if *tokenRequest.Spec.ExpirationSeconds != 3600 {
+							t.Errorf("expected the token lifetime 3600s, but got %d", *tokenRequest.Spec.ExpirationSeconds)
+						}
+					}
+				}
+				if !deleted {
+					t.Errorf("expected the bootstrap service account is recreated")
+				}
+				if _, err := kubeClient.CoreV1().ServiceAccounts("test").Get(context.TODO(), "test-bootstrap-sa",
+					metav1.GetOptions{}); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
 		{
 			name: "klusterletconfig with proxy config",
 			clientObjs: []runtimeclient.Object{
@@ -1005,6 +1126,22 @@ func TestReconcile(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			kubeClient := kubefake.NewSimpleClientset(c.runtimeObjs...)
+			kubeClient.PrependReactor(
+				"create",
+				"serviceaccounts/token",
+				func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+					tokenRequest := action.(clienttesting.CreateAction).GetObject().(*authv1.TokenRequest)
+					return true, &authv1.TokenRequest{
+						ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Now()},
+						Spec:       tokenRequest.Spec,
+						Status: authv1.TokenRequestStatus{
+							Token: "fake-token",
+							ExpirationTimestamp: metav1.NewTime(
+								time.Now().Add(time.Duration(*tokenRequest.Spec.ExpirationSeconds) * time.Second)),
+						},
+					}, nil
+				},
+			)
 
 			// setup klusterletconfig informer
 			klusterletconfigs := []runtime.Object{}
@@ -1021,8 +1158,9 @@ func TestReconcile(t *testing.T) {
 			klusterletconfigLister := listerklusterletconfigv1alpha1.NewKlusterletConfigLister(klusterletconfigInformer.GetIndexer())
 
 			clientHolder := &helpers.ClientHolder{
-				KubeClient:          kubeClient,
-				RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).WithObjects(c.clientObjs...).Build(),
+				KubeClient: kubeClient,
+				RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(c.clientObjs...).
+					WithStatusSubresource(&clusterv1.ManagedCluster{}).Build(),
 				ImageRegistryClient: imageregistry.NewClient(kubeClient),
 			}
 
@@ -1031,6 +1169,8 @@ func TestReconcile(t *testing.T) {
 				scheme:                 testscheme,
 				klusterletconfigLister: klusterletconfigLister,
 				recorder:               eventstesting.NewTestingEventRecorder(t),
+				tokenPolicyGetter: helpers.BootstrapTokenPolicyGetter("cluster-secret",
+					kubeinformers.NewSharedInformerFactory(kubeClient, 0).Core().V1().ConfigMaps().Lister(), logf.Log),
 			}
 
 			_, err := r.Reconcile(context.TODO(), c.request)
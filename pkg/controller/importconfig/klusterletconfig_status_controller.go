@@ -0,0 +1,93 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importconfig
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	listerklusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+const KlusterletConfigStatusControllerName = "klusterletconfig-status-controller"
+
+// ReconcileKlusterletConfigStatus reconciles a KlusterletConfig to report its status. The status of KlusterletConfig
+// has no fields, so the status is reported with the annotations of the KlusterletConfig. The KlusterletConfigs are
+// owned by the users, only the observed generation and the validation errors are reported, the managed clusters which
+// use a KlusterletConfig are not, so the KlusterletConfigs are not updated when the managed clusters change.
+type ReconcileKlusterletConfigStatus struct {
+	clientHolder           *helpers.ClientHolder
+	klusterletconfigLister listerklusterletconfigv1alpha1.KlusterletConfigLister
+}
+
+// blank assignment to verify that ReconcileKlusterletConfigStatus implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileKlusterletConfigStatus{}
+
+func (r *ReconcileKlusterletConfigStatus) Reconcile(ctx context.Context,
+	request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("KlusterletConfig.Name", request.Name)
+
+	klusterletConfig, err := r.klusterletconfigLister.Get(request.Name)
+	if errors.IsNotFound(err) {
+		return reconcile.Result{}, nil
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	reqLogger.V(5).Info("Reconciling klusterletconfig status")
+
+	status := map[string]string{
+		constants.AnnotationKlusterletConfigObservedGeneration: strconv.FormatInt(klusterletConfig.Generation, 10),
+	}
+
+	err = bootstrap.ValidateKlusterletConfig(ctx, r.clientHolder.KubeClient, klusterletConfig)
+	if err != nil {
+		reqLogger.Info("The klusterletconfig is invalid", "error", err.Error())
+		status[constants.AnnotationKlusterletConfigValidationErrors] = validationErrorsMessage(err)
+	}
+
+	modified := klusterletConfig.DeepCopy()
+	if modified.Annotations == nil {
+		modified.Annotations = map[string]string{}
+	}
+	delete(modified.Annotations, constants.AnnotationKlusterletConfigValidationErrors)
+	for key, value := range status {
+		modified.Annotations[key] = value
+	}
+	if equality.Semantic.DeepEqual(modified.Annotations, klusterletConfig.Annotations) {
+		return reconcile.Result{}, nil
+	}
+
+	// only patch the annotations, the object from the lister should not be modified
+	if err := r.clientHolder.RuntimeClient.Patch(ctx, modified, client.MergeFrom(klusterletConfig)); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
+// validationErrorsMessage flattens the aggregated validation errors to a message
+func validationErrorsMessage(err error) string {
+	agg, ok := err.(utilerrors.Aggregate)
+	if !ok {
+		return err.Error()
+	}
+
+	messages := []string{}
+	for _, e := range utilerrors.Flatten(agg).Errors() {
+		messages = append(messages, e.Error())
+	}
+	return strings.Join(messages, "; ")
+}
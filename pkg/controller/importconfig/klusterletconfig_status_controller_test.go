@@ -0,0 +1,149 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importconfig
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	listerklusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+func init() {
+	testscheme.AddKnownTypes(klusterletconfigv1alpha1.GroupVersion, &klusterletconfigv1alpha1.KlusterletConfig{})
+}
+
+func TestReconcileKlusterletConfigStatus(t *testing.T) {
+	cases := []struct {
+		name                string
+		klusterletConfig    *klusterletconfigv1alpha1.KlusterletConfig
+		expectedAnnotations map[string]string
+		expectedErrors      string
+	}{
+		{
+			name: "valid klusterletconfig",
+			klusterletConfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Generation: 2,
+					Annotations: map[string]string{
+						constants.AnnotationKlusterletConfigValidationErrors: "invalid tolerations",
+					},
+				},
+			},
+			expectedAnnotations: map[string]string{
+				constants.AnnotationKlusterletConfigObservedGeneration: "2",
+			},
+		},
+		{
+			name: "invalid klusterletconfig",
+			klusterletConfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "test",
+					Generation: 1,
+				},
+				Spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+					AppliedManifestWorkEvictionGracePeriod: "abc",
+				},
+			},
+			expectedAnnotations: map[string]string{
+				constants.AnnotationKlusterletConfigObservedGeneration: "1",
+			},
+			expectedErrors: "parse appliedManifestWorkEvictionGracePeriod abc failed",
+		},
+		{
+			name: "global klusterletconfig",
+			klusterletConfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: constants.GlobalKlusterletConfigName,
+				},
+			},
+			expectedAnnotations: map[string]string{
+				constants.AnnotationKlusterletConfigObservedGeneration: "0",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			if err := kcIndexer.Add(c.klusterletConfig); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			runtimeClient := fake.NewClientBuilder().WithScheme(testscheme).
+				WithObjects(c.klusterletConfig).Build()
+			r := &ReconcileKlusterletConfigStatus{
+				clientHolder: &helpers.ClientHolder{
+					KubeClient:    kubefake.NewSimpleClientset(),
+					RuntimeClient: runtimeClient,
+				},
+				klusterletconfigLister: listerklusterletconfigv1alpha1.NewKlusterletConfigLister(kcIndexer),
+			}
+
+			if _, err := r.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: c.klusterletConfig.Name},
+			}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			kc := &klusterletconfigv1alpha1.KlusterletConfig{}
+			if err := runtimeClient.Get(context.TODO(), types.NamespacedName{Name: c.klusterletConfig.Name},
+				kc); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for key, value := range c.expectedAnnotations {
+				if kc.Annotations[key] != value {
+					t.Errorf("expected annotation %s=%q, but got %q", key, value, kc.Annotations[key])
+				}
+			}
+			errors, ok := kc.Annotations[constants.AnnotationKlusterletConfigValidationErrors]
+			if len(c.expectedErrors) == 0 && ok {
+				t.Errorf("expected no validation errors, but got %q", errors)
+			}
+			if !strings.Contains(errors, c.expectedErrors) {
+				t.Errorf("expected validation errors %q, but got %q", c.expectedErrors, errors)
+			}
+		})
+	}
+}
+
+func TestIsKlusterletConfigChanged(t *testing.T) {
+	old := &klusterletconfigv1alpha1.KlusterletConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Generation:  1,
+			Annotations: map[string]string{constants.AnnotationBootstrapTokenLifetime: "1h"},
+		},
+	}
+
+	statusChanged := old.DeepCopy()
+	statusChanged.Annotations[constants.AnnotationKlusterletConfigObservedGeneration] = "1"
+	if isKlusterletConfigChanged(old, statusChanged) {
+		t.Errorf("expected the status changes are ignored")
+	}
+
+	annotationChanged := old.DeepCopy()
+	annotationChanged.Annotations[constants.AnnotationBootstrapTokenLifetime] = "2h"
+	if !isKlusterletConfigChanged(old, annotationChanged) {
+		t.Errorf("expected the annotation changes are handled")
+	}
+
+	specChanged := old.DeepCopy()
+	specChanged.Generation = 2
+	if !isKlusterletConfigChanged(old, specChanged) {
+		t.Errorf("expected the spec changes are handled")
+	}
+}
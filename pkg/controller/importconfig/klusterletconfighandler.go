@@ -4,7 +4,11 @@ import (
 	"context"
 	"fmt"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
@@ -18,6 +22,7 @@ import (
 	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
 	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 )
 
 var _ handler.EventHandler = &enqueueManagedClusterInKlusterletConfigAnnotation{}
@@ -28,43 +33,160 @@ type enqueueManagedClusterInKlusterletConfigAnnotation struct {
 
 func (e *enqueueManagedClusterInKlusterletConfigAnnotation) Create(ctx context.Context,
 	evt event.TypedCreateEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-	e.enqueue(evt.Object.GetName(), q)
+	e.enqueue(evt.Object, q)
 }
 
 func (e *enqueueManagedClusterInKlusterletConfigAnnotation) Update(ctx context.Context,
 	evt event.TypedUpdateEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-	e.enqueue(evt.ObjectNew.GetName(), q)
+	// the managed clusters selected by the old selector are enqueued as well, so they stop using the klusterletconfig
+	e.enqueue(evt.ObjectOld, q)
+	e.enqueue(evt.ObjectNew, q)
 }
 
 func (e *enqueueManagedClusterInKlusterletConfigAnnotation) Delete(ctx context.Context,
 	evt event.TypedDeleteEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-	e.enqueue(evt.Object.GetName(), q)
+	e.enqueue(evt.Object, q)
 }
 
 func (e *enqueueManagedClusterInKlusterletConfigAnnotation) Generic(ctx context.Context,
 	evt event.TypedGenericEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-	e.enqueue(evt.Object.GetName(), q)
+	e.enqueue(evt.Object, q)
 }
 
-func (e *enqueueManagedClusterInKlusterletConfigAnnotation) enqueue(klusterletconfigName string,
+func (e *enqueueManagedClusterInKlusterletConfigAnnotation) enqueue(obj client.Object,
 	q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-	objs, err := e.managedclusterIndexer.ByIndex(ManagedClusterKlusterletConfigAnnotationIndexKey, klusterletconfigName)
+	kc, ok := obj.(*klusterletconfigv1alpha1.KlusterletConfig)
+	if !ok {
+		kc = &klusterletconfigv1alpha1.KlusterletConfig{ObjectMeta: metav1.ObjectMeta{Name: obj.GetName()}}
+	}
+	mcs, err := getManagedClustersOfKlusterletConfig(e.managedclusterIndexer, kc)
 	if err != nil {
-		klog.Error(err, "Failed to get managed clusters by klusterletconfig annotation by indexer", "klusterletconfig", klusterletconfigName)
+		klog.Error(err, "Failed to get managed clusters by klusterletconfig annotation by indexer", "klusterletconfig", kc.GetName())
 		return
 	}
-	for _, obj := range objs {
-		mc := obj.(*clusterv1.ManagedCluster)
+	for _, mc := range mcs {
 		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
 			Name: mc.GetName(),
 		}})
 	}
 }
 
+// getManagedClustersOfKlusterletConfig returns the managedclusters which use the klusterletconfig, including the
+// managedclusters that using the klusterletconfig in the annotation and the managedclusters selected by the managed
+// cluster selector of the klusterletconfig.
+func getManagedClustersOfKlusterletConfig(managedclusterIndexer cache.Indexer,
+	kc *klusterletconfigv1alpha1.KlusterletConfig) ([]*clusterv1.ManagedCluster, error) {
+	objs, err := managedclusterIndexer.ByIndex(ManagedClusterKlusterletConfigAnnotationIndexKey, kc.GetName())
+	if err != nil {
+		return nil, err
+	}
+	mcs := []*clusterv1.ManagedCluster{}
+	names := sets.New[string]()
+	for _, obj := range objs {
+		mc := obj.(*clusterv1.ManagedCluster)
+		mcs = append(mcs, mc)
+		names.Insert(mc.Name)
+	}
+
+	if kc.GetName() == constants.GlobalKlusterletConfigName || !helpers.HasKlusterletConfigSelector(kc) {
+		return mcs, nil
+	}
+	selector, err := helpers.GetKlusterletConfigSelector(kc)
+	if err != nil {
+		klog.Warningf("the managed cluster selector of klusterletconfig %s is invalid: %v", kc.Name, err)
+		return mcs, nil
+	}
+	candidates, err := getManagedClustersCandidatesOfSelector(managedclusterIndexer, selector)
+	if err != nil {
+		return nil, err
+	}
+	for _, mc := range candidates {
+		if names.Has(mc.Name) {
+			continue
+		}
+		if selector.Matches(labels.Set(mc.GetLabels())) {
+			mcs = append(mcs, mc)
+		}
+	}
+	return mcs, nil
+}
+
+// getManagedClustersCandidatesOfSelector returns the managedclusters which may be selected by the selector. The
+// managedclusters which match the equality requirements (=, == and in) of the selector are found with the label index
+// and intersected. If the selector has no equality requirement, e.g. it only has the exists or notin requirements,
+// all of the managedclusters are returned, it costs O(N) of the number of the managedclusters.
+func getManagedClustersCandidatesOfSelector(managedclusterIndexer cache.Indexer,
+	selector labels.Selector) ([]*clusterv1.ManagedCluster, error) {
+	requirements, selectable := selector.Requirements()
+	if !selectable {
+		return nil, nil
+	}
+
+	var candidates map[string]*clusterv1.ManagedCluster
+	for _, requirement := range requirements {
+		switch requirement.Operator() {
+		case selection.Equals, selection.DoubleEquals, selection.In:
+		default:
+			continue
+		}
+
+		matched := map[string]*clusterv1.ManagedCluster{}
+		for _, value := range requirement.Values().List() {
+			objs, err := managedclusterIndexer.ByIndex(ManagedClusterLabelIndexKey, labelIndexValue(requirement.Key(), value))
+			if err != nil {
+				return nil, err
+			}
+			for _, obj := range objs {
+				if mc, ok := obj.(*clusterv1.ManagedCluster); ok && (candidates == nil || candidates[mc.Name] != nil) {
+					matched[mc.Name] = mc
+				}
+			}
+		}
+		candidates = matched
+	}
+
+	if candidates == nil {
+		mcs := []*clusterv1.ManagedCluster{}
+		for _, obj := range managedclusterIndexer.List() {
+			if mc, ok := obj.(*clusterv1.ManagedCluster); ok {
+				mcs = append(mcs, mc)
+			}
+		}
+		return mcs, nil
+	}
+
+	mcs := []*clusterv1.ManagedCluster{}
+	for _, name := range sets.List(sets.KeySet(candidates)) {
+		mcs = append(mcs, candidates[name])
+	}
+	return mcs, nil
+}
+
 const (
 	ManagedClusterKlusterletConfigAnnotationIndexKey = "annotation-klusterletconfig"
+
+	// ManagedClusterLabelIndexKey indexes the managedclusters by each of their labels in the key=value format, so the
+	// managedclusters selected by a klusterletconfig are found without listing all of the managedclusters.
+	ManagedClusterLabelIndexKey = "managedcluster-label"
 )
 
+// IndexManagedClusterByLabels indexes the managedclusters by each of their labels
+func IndexManagedClusterByLabels(obj interface{}) ([]string, error) {
+	managedCluster, ok := obj.(*clusterv1.ManagedCluster)
+	if !ok {
+		return nil, fmt.Errorf("not a managedcluster object")
+	}
+	values := []string{}
+	for key, value := range managedCluster.GetLabels() {
+		values = append(values, labelIndexValue(key, value))
+	}
+	return values, nil
+}
+
+func labelIndexValue(key, value string) string {
+	return key + "=" + value
+}
+
 func IndexManagedClusterByKlusterletconfigAnnotation(obj interface{}) ([]string, error) {
 	managedCluster, ok := obj.(*clusterv1.ManagedCluster)
 	if !ok {
@@ -122,15 +244,13 @@ func (e *enqueueManagedClusterByBootstrapKubeConfigSecrets) enqueue(secretName s
 	}
 	for _, kcObj := range klusterletconfigObjs {
 		kc := kcObj.(*klusterletconfigv1alpha1.KlusterletConfig)
-		managedclusterObjs, err := e.managedclusterIndexer.ByIndex(
-			ManagedClusterKlusterletConfigAnnotationIndexKey, kc.GetName())
+		mcs, err := getManagedClustersOfKlusterletConfig(e.managedclusterIndexer, kc)
 		if err != nil {
 			klog.Error(err, "Failed to get managedclusters by klusterletconfig annotation by indexer",
 				"klusterletconfig", kc.GetName())
 			return
 		}
-		for _, mcObj := range managedclusterObjs {
-			mc := mcObj.(*clusterv1.ManagedCluster)
+		for _, mc := range mcs {
 			q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
 				Name: mc.GetName(),
 			}})
@@ -205,15 +325,13 @@ func (e *enqueueManagedClusterByCustomizedCAConfigmaps) enqueue(
 	}
 	for _, kcObj := range klusterletconfigObjs {
 		kc := kcObj.(*klusterletconfigv1alpha1.KlusterletConfig)
-		managedclusterObjs, err := e.managedclusterIndexer.ByIndex(
-			ManagedClusterKlusterletConfigAnnotationIndexKey, kc.GetName())
+		mcs, err := getManagedClustersOfKlusterletConfig(e.managedclusterIndexer, kc)
 		if err != nil {
 			klog.Error(err, "Failed to get managedclusters by klusterletconfig annotation by indexer",
 				"klusterletconfig", kc.GetName())
 			return
 		}
-		for _, mcObj := range managedclusterObjs {
-			mc := mcObj.(*clusterv1.ManagedCluster)
+		for _, mc := range mcs {
 			q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
 				Name: mc.GetName(),
 			}})
@@ -242,3 +360,46 @@ func IndexKlusterletConfigByCustomizedCAConfigmaps() func(obj interface{}) ([]st
 func configmapKey(namespace, name string) string {
 	return namespace + "/" + name
 }
+
+var _ handler.EventHandler = &enqueueKlusterletConfigByBootstrapKubeConfigSecrets{}
+
+// enqueueKlusterletConfigByBootstrapKubeConfigSecrets enqueues the klusterletconfigs that using the secret.
+type enqueueKlusterletConfigByBootstrapKubeConfigSecrets struct {
+	// index klusterletconfig by the spec bootstrap kubeconfig secrets
+	klusterletconfigIndexer cache.Indexer
+}
+
+func (e *enqueueKlusterletConfigByBootstrapKubeConfigSecrets) Create(ctx context.Context,
+	evt event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	e.enqueue(evt.Object.GetName(), q)
+}
+
+func (e *enqueueKlusterletConfigByBootstrapKubeConfigSecrets) Update(ctx context.Context,
+	evt event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	e.enqueue(evt.ObjectNew.GetName(), q)
+}
+
+func (e *enqueueKlusterletConfigByBootstrapKubeConfigSecrets) Delete(ctx context.Context,
+	evt event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	e.enqueue(evt.Object.GetName(), q)
+}
+
+func (e *enqueueKlusterletConfigByBootstrapKubeConfigSecrets) Generic(ctx context.Context,
+	evt event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	e.enqueue(evt.Object.GetName(), q)
+}
+
+func (e *enqueueKlusterletConfigByBootstrapKubeConfigSecrets) enqueue(secretName string,
+	q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	klusterletconfigObjs, err := e.klusterletconfigIndexer.ByIndex(KlusterletConfigBootstrapKubeConfigSecretsIndexKey, secretName)
+	if err != nil {
+		klog.Error(err, "Failed to get klusterletconfigs by bootstrap kubeconfig secrets by indexer", "secret", secretName)
+		return
+	}
+	for _, kcObj := range klusterletconfigObjs {
+		kc := kcObj.(*klusterletconfigv1alpha1.KlusterletConfig)
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+			Name: kc.GetName(),
+		}})
+	}
+}
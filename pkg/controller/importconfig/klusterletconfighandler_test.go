@@ -2,6 +2,8 @@ package importconfig
 
 import (
 	"context"
+	"reflect"
+	"sort"
 	"testing"
 
 	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
@@ -34,6 +36,7 @@ func TestEnqueueManagedClusterInKlusterletConfigAnnotation(t *testing.T) {
 		{
 			ObjectMeta: v1.ObjectMeta{
 				Name:        "test3",
+				Labels:      map[string]string{"env": "dev"},
 				Annotations: map[string]string{"agent.open-cluster-management.io/klusterlet-config": "test-klusterletconfig2"},
 			},
 		},
@@ -108,12 +111,72 @@ func TestEnqueueManagedClusterInKlusterletConfigAnnotation(t *testing.T) {
 				}
 			},
 		},
+		{
+			addEvent: func(h handler.EventHandler, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				// the klusterletconfig selects the managed clusters with the env=dev label
+				evt := event.CreateEvent{
+					Object: &klusterletconfigv1alpha1.KlusterletConfig{
+						ObjectMeta: v1.ObjectMeta{
+							Name: "test-klusterletconfig1",
+							Annotations: map[string]string{
+								"import.open-cluster-management.io/managed-cluster-selector": "env=dev",
+							},
+						},
+					},
+				}
+				h.Create(context.Background(), evt, queue)
+			},
+			verify: func(t *testing.T, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				if queue.Len() != 2 {
+					t.Errorf("Expected queue length to be 2, but got %d", queue.Len())
+				}
+			},
+		},
+		{
+			addEvent: func(h handler.EventHandler, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				// the managed clusters selected by the old selector are enqueued
+				evt := event.UpdateEvent{
+					ObjectOld: &klusterletconfigv1alpha1.KlusterletConfig{
+						ObjectMeta: v1.ObjectMeta{
+							Name: "test-selector",
+							Annotations: map[string]string{
+								"import.open-cluster-management.io/managed-cluster-selector": "env=dev",
+							},
+						},
+					},
+					ObjectNew: &klusterletconfigv1alpha1.KlusterletConfig{
+						ObjectMeta: v1.ObjectMeta{
+							Name: "test-selector",
+							Annotations: map[string]string{
+								"import.open-cluster-management.io/managed-cluster-selector": "env=prod",
+							},
+						},
+					},
+				}
+				h.Update(context.Background(), evt, queue)
+			},
+			verify: func(t *testing.T, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				if queue.Len() != 1 {
+					t.Errorf("Expected queue length to be 1, but got %d", queue.Len())
+				}
+				expectedRequest := reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name: "test3",
+					},
+				}
+				item, _ := queue.Get()
+				if item != expectedRequest {
+					t.Errorf("Expected item to be %v, but got %v", expectedRequest, item)
+				}
+			},
+		},
 	}
 
 	for _, tc := range testcases {
 		// Create a fake clientset and indexer
 		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
 			ManagedClusterKlusterletConfigAnnotationIndexKey: IndexManagedClusterByKlusterletconfigAnnotation,
+			ManagedClusterLabelIndexKey:                      IndexManagedClusterByLabels,
 		})
 
 		// Create a new kcHandler
@@ -171,6 +234,131 @@ func TestIndexManagedClusterByKlusterletconfigAnnotation(t *testing.T) {
 	}
 }
 
+func TestIndexManagedClusterByLabels(t *testing.T) {
+	result, err := IndexManagedClusterByLabels(&clusterv1.ManagedCluster{
+		ObjectMeta: v1.ObjectMeta{
+			Name:   "test",
+			Labels: map[string]string{"env": "dev", "cloud": "aws"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sort.Strings(result)
+	if !reflect.DeepEqual(result, []string{"cloud=aws", "env=dev"}) {
+		t.Errorf("Expected result to be [cloud=aws env=dev], but got %v", result)
+	}
+
+	if _, err := IndexManagedClusterByLabels(&corev1.Secret{}); err == nil {
+		t.Errorf("Expected an error, but got nil")
+	}
+}
+
+func TestGetManagedClustersOfKlusterletConfig(t *testing.T) {
+	mcs := []*clusterv1.ManagedCluster{
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name:   "dev-aws",
+				Labels: map[string]string{"env": "dev", "cloud": "aws", "cluster.open-cluster-management.io/clusterset": "set1"},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name:   "dev-gcp",
+				Labels: map[string]string{"env": "dev", "cloud": "gcp"},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name:   "prod-aws",
+				Labels: map[string]string{"env": "prod", "cloud": "aws"},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name:        "annotated",
+				Labels:      map[string]string{"env": "test"},
+				Annotations: map[string]string{"agent.open-cluster-management.io/klusterlet-config": "test"},
+			},
+		},
+	}
+
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		expected    []string
+	}{
+		{
+			name:     "without selector",
+			expected: []string{"annotated"},
+		},
+		{
+			name:        "equality selector",
+			annotations: map[string]string{"import.open-cluster-management.io/managed-cluster-selector": "env=dev"},
+			expected:    []string{"annotated", "dev-aws", "dev-gcp"},
+		},
+		{
+			name:        "intersected equality selectors",
+			annotations: map[string]string{"import.open-cluster-management.io/managed-cluster-selector": "env=dev,cloud=aws"},
+			expected:    []string{"annotated", "dev-aws"},
+		},
+		{
+			name:        "in selector",
+			annotations: map[string]string{"import.open-cluster-management.io/managed-cluster-selector": "env in (dev,prod),cloud=gcp"},
+			expected:    []string{"annotated", "dev-gcp"},
+		},
+		{
+			name:        "equality selector with an inequality requirement",
+			annotations: map[string]string{"import.open-cluster-management.io/managed-cluster-selector": "cloud=aws,env!=dev"},
+			expected:    []string{"annotated", "prod-aws"},
+		},
+		{
+			name:        "selector without an equality requirement",
+			annotations: map[string]string{"import.open-cluster-management.io/managed-cluster-selector": "env,env notin (test)"},
+			expected:    []string{"annotated", "dev-aws", "dev-gcp", "prod-aws"},
+		},
+		{
+			name:        "cluster set",
+			annotations: map[string]string{"import.open-cluster-management.io/managed-cluster-set": "set1"},
+			expected:    []string{"annotated", "dev-aws"},
+		},
+		{
+			name:        "invalid selector",
+			annotations: map[string]string{"import.open-cluster-management.io/managed-cluster-selector": "env in dev"},
+			expected:    []string{"annotated"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+				ManagedClusterKlusterletConfigAnnotationIndexKey: IndexManagedClusterByKlusterletconfigAnnotation,
+				ManagedClusterLabelIndexKey:                      IndexManagedClusterByLabels,
+			})
+			for _, mc := range mcs {
+				if err := indexer.Add(mc); err != nil {
+					t.Fatalf("Failed to add managed cluster to indexer: %v", err)
+				}
+			}
+
+			result, err := getManagedClustersOfKlusterletConfig(indexer, &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: v1.ObjectMeta{Name: "test", Annotations: c.annotations},
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			names := []string{}
+			for _, mc := range result {
+				names = append(names, mc.Name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, c.expected) {
+				t.Errorf("Expected managed clusters %v, but got %v", c.expected, names)
+			}
+		})
+	}
+}
+
 func TestEnqueueManagedClusterByBootstrapKubeconfigSecret(t *testing.T) {
 	mcs := []*clusterv1.ManagedCluster{
 		{
@@ -307,6 +495,7 @@ func TestEnqueueManagedClusterByBootstrapKubeconfigSecret(t *testing.T) {
 		// Create fake clientet and indexer
 		managedClusterIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
 			ManagedClusterKlusterletConfigAnnotationIndexKey: IndexManagedClusterByKlusterletconfigAnnotation,
+			ManagedClusterLabelIndexKey:                      IndexManagedClusterByLabels,
 		})
 
 		klusterletconfigIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
@@ -538,6 +727,7 @@ func TestEnqueueManagedClusterByCustomizedCAConfigmaps(t *testing.T) {
 		// Create fake clientet and indexer
 		managedClusterIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
 			ManagedClusterKlusterletConfigAnnotationIndexKey: IndexManagedClusterByKlusterletconfigAnnotation,
+			ManagedClusterLabelIndexKey:                      IndexManagedClusterByLabels,
 		})
 
 		klusterletconfigIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
@@ -7,13 +7,16 @@ import (
 	"context"
 	"os"
 	"reflect"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	kevents "k8s.io/client-go/tools/events"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -25,6 +28,7 @@ import (
 
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/metrics"
 	"github.com/stolostron/managedcluster-import-controller/pkg/source"
 )
 
@@ -35,11 +39,23 @@ const ControllerName = "importconfig-controller"
 func Add(ctx context.Context,
 	mgr manager.Manager,
 	clientHolder *helpers.ClientHolder,
-	informerHolder *source.InformerHolder) error {
+	informerHolder *source.InformerHolder,
+	mcRecorder kevents.EventRecorder) error {
 
 	// All bootstrap kubeconfigs should created in the same pod namespace
 	podNS := os.Getenv(constants.PodNamespaceEnvVarName)
 
+	caRotationPolicyGetter := helpers.CARotationPolicyGetter(podNS, informerHolder.ControllerConfigLister, log)
+
+	// the bootstrap credentials expiring within the caExpiryWarningThreshold are counted as expiring in the metrics
+	metrics.SetBootstrapCredentialExpiringWindow(func() time.Duration {
+		policy, err := caRotationPolicyGetter()
+		if err != nil {
+			log.Error(err, "failed to get the CA rotation policy, use the default CA expiry warning threshold")
+		}
+		return policy.ExpiryWarningThreshold
+	})
+
 	err := ctrl.NewControllerManagedBy(mgr).Named(ControllerName).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: helpers.GetMaxConcurrentReconciles(),
@@ -55,9 +71,11 @@ func Add(ctx context.Context,
 					// handle the labels changes for image registry
 					// handle the annotations changes for node placement and klusterletconfig
 					// handle the claim changes for priority class
+					// handle the import condition changes for the ephemeral bootstrap token
 					return !equality.Semantic.DeepEqual(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels()) ||
 						!equality.Semantic.DeepEqual(e.ObjectOld.GetAnnotations(), e.ObjectNew.GetAnnotations()) ||
-						helpers.IsKubeVersionChanged(e.ObjectOld, e.ObjectNew)
+						helpers.IsKubeVersionChanged(e.ObjectOld, e.ObjectNew) ||
+						isImportedChanged(e.ObjectOld, e.ObjectNew)
 				},
 			}),
 		).
@@ -115,7 +133,10 @@ func Add(ctx context.Context,
 				GenericFunc: func(e event.GenericEvent) bool { return true },
 				CreateFunc:  func(e event.CreateEvent) bool { return true },
 				DeleteFunc:  func(e event.DeleteEvent) bool { return true },
-				UpdateFunc:  func(e event.UpdateEvent) bool { return true },
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					// ignore the status reported by the klusterletconfig status controller
+					return isKlusterletConfigChanged(e.ObjectOld, e.ObjectNew)
+				},
 			}),
 		).
 		WatchesRawSource(
@@ -183,6 +204,87 @@ func Add(ctx context.Context,
 			klusterletconfigLister: informerHolder.KlusterletConfigLister,
 			scheme:                 mgr.GetScheme(),
 			recorder:               helpers.NewEventRecorder(clientHolder.KubeClient, ControllerName),
+			mcRecorder:             mcRecorder,
+			caRotationPolicyGetter: caRotationPolicyGetter,
+			tokenPolicyGetter: helpers.BootstrapTokenPolicyGetter(podNS, informerHolder.ControllerConfigLister,
+				log),
+		})
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).Named(KlusterletConfigStatusControllerName).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: helpers.GetMaxConcurrentReconciles(),
+		}).
+		Watches(
+			&klusterletconfigv1alpha1.KlusterletConfig{},
+			&handler.EnqueueRequestForObject{},
+			builder.WithPredicates(predicate.Funcs{
+				GenericFunc: func(e event.GenericEvent) bool { return false },
+				DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+				CreateFunc:  func(e event.CreateEvent) bool { return true },
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					return isKlusterletConfigChanged(e.ObjectOld, e.ObjectNew)
+				},
+			}),
+		).
+		WatchesMetadata(
+			&corev1.Secret{},
+			&enqueueKlusterletConfigByBootstrapKubeConfigSecrets{
+				klusterletconfigIndexer: informerHolder.KlusterletConfigInformer.GetIndexer(),
+			},
+			builder.WithPredicates(predicate.Funcs{
+				GenericFunc: func(e event.GenericEvent) bool {
+					return e.Object.GetNamespace() == podNS
+				},
+				CreateFunc: func(e event.CreateEvent) bool {
+					return e.Object.GetNamespace() == podNS
+				},
+				DeleteFunc: func(e event.DeleteEvent) bool {
+					return e.Object.GetNamespace() == podNS
+				},
+				UpdateFunc: func(e event.UpdateEvent) bool {
+					return e.ObjectNew.GetNamespace() == podNS || e.ObjectOld.GetNamespace() == podNS
+				},
+			}),
+		).
+		Complete(&ReconcileKlusterletConfigStatus{
+			clientHolder:           clientHolder,
+			klusterletconfigLister: informerHolder.KlusterletConfigLister,
 		})
-	return err
+}
+
+// isKlusterletConfigChanged checks if the spec or the annotations of the klusterletconfig are changed, the
+// annotations which report the status of the klusterletconfig are ignored
+func isKlusterletConfigChanged(old, new client.Object) bool {
+	if old.GetGeneration() != new.GetGeneration() {
+		return true
+	}
+	return !equality.Semantic.DeepEqual(withoutKlusterletConfigStatus(old.GetAnnotations()),
+		withoutKlusterletConfigStatus(new.GetAnnotations()))
+}
+
+func withoutKlusterletConfigStatus(annotations map[string]string) map[string]string {
+	filtered := map[string]string{}
+	for key, value := range annotations {
+		switch key {
+		case constants.AnnotationKlusterletConfigObservedGeneration,
+			constants.AnnotationKlusterletConfigValidationErrors:
+			continue
+		}
+		filtered[key] = value
+	}
+	return filtered
+}
+
+// isImportedChanged checks if the ManagedClusterImportSucceeded condition of the managed cluster is changed between
+// true and false
+func isImportedChanged(old, new client.Object) bool {
+	oldCluster, okOld := old.(*clusterv1.ManagedCluster)
+	newCluster, okNew := new.(*clusterv1.ManagedCluster)
+	if !okOld || !okNew {
+		return false
+	}
+	return isImported(oldCluster) != isImported(newCluster)
 }
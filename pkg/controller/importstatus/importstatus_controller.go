@@ -95,6 +95,7 @@ func (r *ReconcileImportStatus) Reconcile(ctx context.Context, request reconcile
 				"Wait for importing",
 			),
 			r.mcRecorder,
+			helpers.ImportAttemptSource{Controller: helpers.ImportSourceImportStatus},
 		)
 	}
 
@@ -127,5 +128,6 @@ func (r *ReconcileImportStatus) Reconcile(ctx context.Context, request reconcile
 			"Import succeeded",
 		),
 		r.mcRecorder,
+		helpers.ImportAttemptSource{Controller: helpers.ImportSourceImportStatus},
 	)
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/openshift/library-go/pkg/operator/events"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/metrics"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -117,6 +118,7 @@ func (r *ReconcileResourceCleanup) updateDetachingCondition(cluster *clusterv1.M
 				conditionMsg,
 			),
 			r.mcRecorder,
+			helpers.ImportAttemptSource{Controller: helpers.ImportSourceResourceCleanup},
 		)
 	}
 	return nil
@@ -332,7 +334,12 @@ func (r *ReconcileResourceCleanup) removeClusterFinalizers(ctx context.Context,
 	if errors.IsNotFound(err) {
 		return nil
 	}
-	return err
+	if err != nil {
+		return err
+	}
+
+	metrics.RecordDetachDuration(cluster.DeletionTimestamp.Time)
+	return nil
 }
 
 func (r *ReconcileResourceCleanup) namespaceExists(ctx context.Context, name string) (bool, error) {
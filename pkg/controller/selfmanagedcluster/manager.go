@@ -133,7 +133,10 @@ func Add(ctx context.Context,
 			mgr.GetRESTMapper(),
 			helpers.NewEventRecorder(clientHolder.KubeClient, ControllerName),
 			mcRecorder,
-			helpers.AutoImportStrategyGetter(componentNamespace, informerHolder.ControllerConfigLister, log),
+			helpers.AutoImportStrategyGetter(componentNamespace, informerHolder.ControllerConfigLister,
+				informerHolder.KlusterletConfigLister, log),
+			helpers.ClusterTakeoverPolicyGetter(componentNamespace, informerHolder.ControllerConfigLister, log),
+			helpers.ImportSyncWindowsGetter(componentNamespace, informerHolder.ControllerConfigLister, log),
 		))
 	return err
 }
@@ -34,6 +34,7 @@ type ReconcileLocalCluster struct {
 	mcRecorder               kevents.EventRecorder
 	importHelper             *helpers.ImportHelper
 	autoImportStrategyGetter helpers.AutoImportStrategyGetterFunc
+	importSyncWindowsGetter  helpers.ImportSyncWindowsGetterFunc
 }
 
 func NewReconcileLocalCluster(
@@ -43,6 +44,8 @@ func NewReconcileLocalCluster(
 	recorder events.Recorder,
 	mcRecorder kevents.EventRecorder,
 	autoImportStrategyGetter helpers.AutoImportStrategyGetterFunc,
+	clusterTakeoverPolicyGetter helpers.ClusterTakeoverPolicyGetterFunc,
+	importSyncWindowsGetter helpers.ImportSyncWindowsGetterFunc,
 ) *ReconcileLocalCluster {
 
 	return &ReconcileLocalCluster{
@@ -55,8 +58,9 @@ func NewReconcileLocalCluster(
 			func(secret *v1.Secret) (reconcile.Result, *helpers.ClientHolder, meta.RESTMapper, error) {
 				return reconcile.Result{}, clientHolder, restMapper, nil
 			},
-		),
+		).WithClusterTakeoverPolicy(clusterTakeoverPolicyGetter, mcRecorder),
 		autoImportStrategyGetter: autoImportStrategyGetter,
+		importSyncWindowsGetter:  importSyncWindowsGetter,
 	}
 }
 
@@ -109,7 +113,7 @@ func (r *ReconcileLocalCluster) Reconcile(ctx context.Context, request reconcile
 	}
 
 	immediateImport := helpers.IsImmediateImport(managedCluster.Annotations)
-	autoImportStrategy, err := r.autoImportStrategyGetter()
+	autoImportStrategy, err := r.autoImportStrategyGetter(managedCluster)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -126,6 +130,20 @@ func (r *ReconcileLocalCluster) Reconcile(ctx context.Context, request reconcile
 
 	reqLogger.V(5).Info("Reconciling self managed cluster")
 
+	if !immediateImport && importSucceeded {
+		// the importing resources are only synced to the imported managed cluster in the maintenance windows
+		requeueAfter, deferred, err := helpers.DeferImportSync(r.clientHolder.RuntimeClient, managedCluster,
+			r.importSyncWindowsGetter)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if deferred {
+			reqLogger.Info("Auto import sync is deferred to the next maintenance window",
+				"managedCluster", managedCluster.Name, "requeueAfter", requeueAfter)
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
+		}
+	}
+
 	result, condition, modified, iErr := r.importHelper.Import(false, managedCluster, nil)
 	// if resources are applied but NOT modified, will not update the condition, keep the original condition.
 	// This check is to prevent the current controller and import status controller from modifying the
@@ -136,6 +154,7 @@ func (r *ReconcileLocalCluster) Reconcile(ctx context.Context, request reconcile
 			managedCluster,
 			condition,
 			r.mcRecorder,
+			helpers.ImportAttemptSource{Controller: helpers.ImportSourceSelfManaged},
 		); err != nil {
 			return reconcile.Result{}, err
 		}
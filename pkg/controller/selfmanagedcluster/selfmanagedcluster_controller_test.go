@@ -5,6 +5,7 @@ package selfmanagedcluster
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -64,12 +65,19 @@ func init() {
 }
 
 func TestReconcile(t *testing.T) {
+	// the window is opened two hours later
+	closedWindows, err := helpers.ParseImportSyncWindows(fmt.Sprintf("0 %d * * * 1h", (time.Now().UTC().Hour()+2)%24))
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	cases := []struct {
 		name               string
 		objs               []client.Object
 		works              []runtime.Object
 		secrets            []runtime.Object
 		autoImportStrategy string
+		importSyncWindows  []helpers.ImportSyncWindow
 		validateFunc       func(t *testing.T, runtimeClient client.Client)
 	}{
 		{
@@ -270,6 +278,49 @@ func TestReconcile(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "with ImportAndSync strategy out of the maintenance windows",
+			objs: []client.Object{
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "local-cluster",
+						Labels: map[string]string{
+							"local-cluster": "true",
+						},
+					},
+					Status: clusterv1.ManagedClusterStatus{
+						Conditions: []metav1.Condition{
+							{
+								Type:   constants.ConditionManagedClusterImportSucceeded,
+								Status: metav1.ConditionTrue,
+							},
+						},
+					},
+				},
+			},
+			works: []runtime.Object{},
+			secrets: []runtime.Object{
+				testinghelpers.GetImportSecret("local-cluster"),
+			},
+			autoImportStrategy: apiconstants.AutoImportStrategyImportAndSync,
+			importSyncWindows:  closedWindows,
+			validateFunc: func(t *testing.T, runtimeClient client.Client) {
+				cluster := &clusterv1.ManagedCluster{}
+				err := runtimeClient.Get(context.TODO(), types.NamespacedName{Name: "local-cluster"}, cluster)
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				condition := meta.FindStatusCondition(
+					cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
+				if condition == nil || condition.Status != metav1.ConditionTrue {
+					t.Errorf("unexpected condition")
+				}
+				if !meta.IsStatusConditionTrue(cluster.Status.Conditions,
+					constants.ConditionManagedClusterImportSyncPending) {
+					t.Errorf("expected the import sync is pending")
+				}
+			},
+		},
 		{
 			name: "has auto-import-secret",
 			objs: []client.Object{
@@ -430,12 +481,18 @@ func TestReconcile(t *testing.T) {
 				restmapper.NewDiscoveryRESTMapper(apiGroupResources),
 				eventstesting.NewTestingEventRecorder(t),
 				helpers.NewManagedClusterEventRecorder(ctx, kubeClient),
-				func() (strategy string, err error) {
+				func(_ *clusterv1.ManagedCluster) (strategy string, err error) {
 					if len(c.autoImportStrategy) > 0 {
 						return c.autoImportStrategy, nil
 					}
 					return constants.DefaultAutoImportStrategy, nil
 				},
+				func() (string, error) {
+					return constants.DefaultClusterTakeoverPolicy, nil
+				},
+				func(_ *clusterv1.ManagedCluster) ([]helpers.ImportSyncWindow, error) {
+					return c.importSyncWindows, nil
+				},
 			)
 
 			_, err := r.Reconcile(context.TODO(), reconcile.Request{
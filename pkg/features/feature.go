@@ -21,6 +21,10 @@ const (
 
 	// AgentRegistration enables a server to provide an endpoint for clients to get manifests
 	AgentRegistration featuregate.Feature = "AgentRegistration"
+
+	// ValidatingWebhook enables a webhook server to validate the KlusterletConfigs and the import annotations of
+	// the ManagedClusters at admission time
+	ValidatingWebhook featuregate.Feature = "ValidatingWebhook"
 )
 
 var (
@@ -40,4 +44,5 @@ func init() {
 var defaultRegistrationFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
 	KlusterletHostedMode: {Default: true, PreRelease: featuregate.Alpha},
 	AgentRegistration:    {Default: true, PreRelease: featuregate.Alpha},
+	ValidatingWebhook:    {Default: false, PreRelease: featuregate.Alpha},
 }
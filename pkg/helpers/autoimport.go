@@ -6,6 +6,7 @@ package helpers
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -18,10 +19,12 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes"
 	corev1listers "k8s.io/client-go/listers/core/v1"
+	kevents "k8s.io/client-go/tools/events"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/openshift/library-go/pkg/operator/events"
+	listerklusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
 	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/source"
@@ -98,6 +101,9 @@ type ImportHelper struct {
 	log            logr.Logger
 
 	generateClientHolderFunc GenerateClientHolderFunc
+
+	clusterTakeoverPolicyGetter ClusterTakeoverPolicyGetterFunc
+	mcRecorder                  kevents.EventRecorder
 }
 
 func (i *ImportHelper) WithGenerateClientHolderFunc(f GenerateClientHolderFunc) *ImportHelper {
@@ -105,6 +111,15 @@ func (i *ImportHelper) WithGenerateClientHolderFunc(f GenerateClientHolderFunc)
 	return i
 }
 
+// WithClusterTakeoverPolicy enables the detection of the managed cluster which is registered to another hub,
+// the detection is handled by the policy and recorded as the managed cluster events.
+func (i *ImportHelper) WithClusterTakeoverPolicy(f ClusterTakeoverPolicyGetterFunc,
+	mcRecorder kevents.EventRecorder) *ImportHelper {
+	i.clusterTakeoverPolicyGetter = f
+	i.mcRecorder = mcRecorder
+	return i
+}
+
 func NewImportHelper(informerHolder *source.InformerHolder,
 	recorder events.Recorder,
 	log logr.Logger) *ImportHelper {
@@ -206,6 +221,31 @@ func (i *ImportHelper) Import(backupRestore bool, cluster *clusterv1.ManagedClus
 			), false, err
 	}
 
+	// the backup restore case is expected to move the managed cluster from another hub
+	takeoverMessage := ""
+	if !backupRestore {
+		message, refused, err := i.checkClusterTakeover(cluster, clientHolder, importSecret)
+		if err != nil {
+			return reconcile.Result{},
+				NewManagedClusterImportSucceededCondition(
+					metav1.ConditionFalse,
+					constants.ConditionReasonManagedClusterImporting,
+					fmt.Sprintf("Detect whether the managed cluster is registered to another hub failed: %v. "+
+						"Will retry", err),
+				), false, err
+		}
+		if refused {
+			return reconcile.Result{},
+				NewManagedClusterImportSucceededCondition(
+					metav1.ConditionFalse,
+					constants.ConditionReasonManagedClusterImportFailed,
+					fmt.Sprintf("%s, the import is refused by the cluster takeover policy %s",
+						message, constants.ClusterTakeoverPolicyRefuse),
+				), false, nil
+		}
+		takeoverMessage = message
+	}
+
 	modified, err := applyResourcesFunc(backupRestore, clientHolder, restMapper, i.recorder, importSecret)
 	if err != nil {
 		condition := NewManagedClusterImportSucceededCondition(
@@ -231,12 +271,16 @@ func (i *ImportHelper) Import(backupRestore bool, cluster *clusterv1.ManagedClus
 		return reconcile.Result{}, condition, modified, err
 	}
 
-	return reconcile.Result{},
-		NewManagedClusterImportSucceededCondition(
-			metav1.ConditionFalse,
-			constants.ConditionReasonManagedClusterImporting,
-			conditionMessageImportingResourcesApplied,
-		), modified, nil
+	condition := NewManagedClusterImportSucceededCondition(
+		metav1.ConditionFalse,
+		constants.ConditionReasonManagedClusterImporting,
+		conditionMessageImportingResourcesApplied,
+	)
+	if len(takeoverMessage) > 0 {
+		condition.Message = fmt.Sprintf("%s. %s, it is taken over", conditionMessageImportingResourcesApplied,
+			takeoverMessage)
+	}
+	return reconcile.Result{}, condition, modified, nil
 }
 
 func failureMessageOfKubeClientGerneration(managedClusterKubeClientSecret *corev1.Secret,
@@ -275,16 +319,40 @@ const (
 func ImportingResourcesApplied(condition *metav1.Condition) bool {
 	if condition != nil && condition.Type == constants.ConditionManagedClusterImportSucceeded &&
 		condition.Reason == constants.ConditionReasonManagedClusterImporting &&
-		condition.Message == conditionMessageImportingResourcesApplied {
+		strings.HasPrefix(condition.Message, conditionMessageImportingResourcesApplied) {
 		return true
 	}
 	return false
 }
 
-type AutoImportStrategyGetterFunc func() (strategy string, err error)
+type AutoImportStrategyGetterFunc func(cluster *clusterv1.ManagedCluster) (strategy string, err error)
+
+// AutoImportStrategyGetter returns the auto import strategy of a managed cluster. The strategy is determined by the
+// auto-import-strategy annotation of the managed cluster, then the auto-import-strategy annotation of the merged
+// KlusterletConfig of the managed cluster, and then the autoImportStrategy in the import-controller-config ConfigMap.
+func AutoImportStrategyGetter(componentNamespace string, configMapLister corev1listers.ConfigMapLister,
+	kcLister listerklusterletconfigv1alpha1.KlusterletConfigLister, log logr.Logger) AutoImportStrategyGetterFunc {
+	return func(cluster *clusterv1.ManagedCluster) (string, error) {
+		if cluster != nil {
+			if strategy, ok := validAutoImportStrategy(cluster.Annotations, log,
+				"managedCluster", cluster.Name); ok {
+				return strategy, nil
+			}
+
+			if kcLister != nil {
+				mergedKlusterletConfig, err := GetMergedKlusterletConfig(cluster, kcLister)
+				if err != nil {
+					return "", err
+				}
+				if mergedKlusterletConfig != nil {
+					if strategy, ok := validAutoImportStrategy(mergedKlusterletConfig.Annotations, log,
+						"klusterletConfig", cluster.Annotations[apiconstants.AnnotationKlusterletConfig]); ok {
+						return strategy, nil
+					}
+				}
+			}
+		}
 
-func AutoImportStrategyGetter(componentNamespace string, configMapLister corev1listers.ConfigMapLister, log logr.Logger) AutoImportStrategyGetterFunc {
-	return func() (string, error) {
 		cm, err := configMapLister.ConfigMaps(componentNamespace).Get(constants.ControllerConfigConfigMapName)
 		if errors.IsNotFound(err) {
 			return constants.DefaultAutoImportStrategy, nil
@@ -308,3 +376,53 @@ func AutoImportStrategyGetter(componentNamespace string, configMapLister corev1l
 		}
 	}
 }
+
+// validAutoImportStrategy returns the auto import strategy in the annotations and whether it is valid, the invalid
+// strategy is ignored.
+func validAutoImportStrategy(annotations map[string]string, log logr.Logger,
+	keysAndValues ...interface{}) (string, bool) {
+	strategy, ok := annotations[constants.AnnotationAutoImportStrategy]
+	if !ok {
+		return "", false
+	}
+
+	switch strategy {
+	case apiconstants.AutoImportStrategyImportAndSync, apiconstants.AutoImportStrategyImportOnly:
+		return strategy, true
+	default:
+		log.Info("Invalid auto import strategy annotation found and ignore it.",
+			append(keysAndValues, constants.AnnotationAutoImportStrategy, strategy)...)
+		return "", false
+	}
+}
+
+type InsecureAutoImportRefusedGetterFunc func() (refused bool, err error)
+
+// InsecureAutoImportRefusedGetter returns whether to refuse the token based auto import when the certificate of
+// the managed cluster kube apiserver cannot be verified, it is false by default.
+func InsecureAutoImportRefusedGetter(componentNamespace string, configMapLister corev1listers.ConfigMapLister,
+	log logr.Logger) InsecureAutoImportRefusedGetterFunc {
+	return func() (bool, error) {
+		cm, err := configMapLister.ConfigMaps(componentNamespace).Get(constants.ControllerConfigConfigMapName)
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		refused := cm.Data[constants.AutoImportRefuseInsecureKey]
+		switch strings.ToLower(refused) {
+		case "true":
+			return true, nil
+		case "false", "":
+			return false, nil
+		default:
+			log.Info("Invalid config value found and use default instead.",
+				"configmap", constants.ControllerConfigConfigMapName,
+				constants.AutoImportRefuseInsecureKey, refused,
+				"default", false)
+			return false, nil
+		}
+	}
+}
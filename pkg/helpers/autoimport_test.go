@@ -27,6 +27,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
 	"github.com/stolostron/managedcluster-import-controller/pkg/source"
@@ -83,6 +84,11 @@ func TestImportHelper(t *testing.T) {
 	}
 	defer apiServer.Stop()
 
+	generateClientFromKubeConfigSecret := func(secret *corev1.Secret) (
+		reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
+		return GenerateImportClientFromKubeConfigSecret(secret, false)
+	}
+
 	spokeKubeClient, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		t.Fatal(err)
@@ -180,7 +186,7 @@ func TestImportHelper(t *testing.T) {
 					"kubeconfig": testinghelpers.BuildKubeconfig(config),
 				},
 			},
-			generateClientHolderFunc: GenerateImportClientFromKubeConfigSecret,
+			generateClientHolderFunc: generateClientFromKubeConfigSecret,
 			expectedErr:              false,
 			expectedConditionStatus:  metav1.ConditionFalse,
 			expectedConditionReason:  constants.ConditionReasonManagedClusterImporting,
@@ -218,11 +224,13 @@ func TestImportHelper(t *testing.T) {
 					// no auth info
 				},
 			},
-			generateClientHolderFunc: GenerateImportClientFromKubeTokenSecret,
-			expectedErr:              true,
-			expectedRequeueAfter:     0 * time.Second,
-			expectedConditionStatus:  metav1.ConditionFalse,
-			expectedConditionReason:  constants.ConditionReasonManagedClusterImportFailed,
+			expectedErr:             true,
+			expectedRequeueAfter:    0 * time.Second,
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
+			generateClientHolderFunc: func(secret *corev1.Secret) (reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
+				return GenerateImportClientFromKubeTokenSecret(secret, false)
+			},
 		},
 		{
 			name: "only update the bootstrap secret",
@@ -259,7 +267,7 @@ func TestImportHelper(t *testing.T) {
 					"kubeconfig": testinghelpers.BuildKubeconfig(config),
 				},
 			},
-			generateClientHolderFunc: GenerateImportClientFromKubeConfigSecret,
+			generateClientHolderFunc: generateClientFromKubeConfigSecret,
 			expectedErr:              false,
 			expectedRequeueAfter:     0 * time.Second,
 			expectedConditionStatus:  metav1.ConditionFalse,
@@ -297,7 +305,7 @@ func TestImportHelper(t *testing.T) {
 					"kubeconfig": testinghelpers.BuildKubeconfig(config),
 				},
 			},
-			generateClientHolderFunc: GenerateImportClientFromKubeConfigSecret,
+			generateClientHolderFunc: generateClientFromKubeConfigSecret,
 			expectedErr:              true,
 			expectedRequeueAfter:     0,
 			expectedConditionStatus:  metav1.ConditionFalse,
@@ -307,7 +315,7 @@ func TestImportHelper(t *testing.T) {
 			name: "importing condition does not exist",
 			managedCluster: testinghelpers.NewManagedClusterBuilder(managedClusterName).
 				WithImportingCondition(false).Build(),
-			generateClientHolderFunc: GenerateImportClientFromKubeConfigSecret,
+			generateClientHolderFunc: generateClientFromKubeConfigSecret,
 			expectedErr:              false,
 			expectedRequeueAfter:     0,
 			expectedConditionStatus:  metav1.ConditionFalse,
@@ -495,15 +503,124 @@ func TestFailureMessageOfInvalidAutoImportSecretPrivileges(t *testing.T) {
 
 func TestAutoImportStrategyGetter(t *testing.T) {
 
+	importAndSyncConfig := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "import-controller-config",
+			Namespace: "test",
+		},
+		Data: map[string]string{
+			"autoImportStrategy": apiconstants.AutoImportStrategyImportAndSync,
+		},
+	}
+
 	cases := []struct {
-		name             string
-		controllerConfig *corev1.ConfigMap
-		expectedStrategy string
+		name              string
+		controllerConfig  *corev1.ConfigMap
+		cluster           *clusterv1.ManagedCluster
+		klusterletConfigs map[string]*klusterletconfigv1alpha1.KlusterletConfig
+		expectedStrategy  string
 	}{
 		{
 			name:             "default auto-import-strategy",
 			expectedStrategy: constants.DefaultAutoImportStrategy,
 		},
+		{
+			name:             "managed cluster annotation overrides configmap",
+			controllerConfig: importAndSyncConfig,
+			cluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+					Annotations: map[string]string{
+						"import.open-cluster-management.io/auto-import-strategy": apiconstants.AutoImportStrategyImportOnly,
+					},
+				},
+			},
+			expectedStrategy: apiconstants.AutoImportStrategyImportOnly,
+		},
+		{
+			name:             "invalid managed cluster annotation is ignored",
+			controllerConfig: importAndSyncConfig,
+			cluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+					Annotations: map[string]string{
+						"import.open-cluster-management.io/auto-import-strategy": "invalid-strategy",
+					},
+				},
+			},
+			expectedStrategy: apiconstants.AutoImportStrategyImportAndSync,
+		},
+		{
+			name: "global klusterletconfig annotation overrides configmap",
+			cluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			},
+			klusterletConfigs: map[string]*klusterletconfigv1alpha1.KlusterletConfig{
+				"global": {
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "global",
+						Annotations: map[string]string{
+							"import.open-cluster-management.io/auto-import-strategy": apiconstants.AutoImportStrategyImportAndSync,
+						},
+					},
+				},
+			},
+			expectedStrategy: apiconstants.AutoImportStrategyImportAndSync,
+		},
+		{
+			name: "klusterletconfig annotation overrides global klusterletconfig",
+			cluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+					Annotations: map[string]string{
+						"agent.open-cluster-management.io/klusterlet-config": "test",
+					},
+				},
+			},
+			klusterletConfigs: map[string]*klusterletconfigv1alpha1.KlusterletConfig{
+				"global": {
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "global",
+						Annotations: map[string]string{
+							"import.open-cluster-management.io/auto-import-strategy": apiconstants.AutoImportStrategyImportAndSync,
+						},
+					},
+				},
+				"test": {
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							"import.open-cluster-management.io/auto-import-strategy": apiconstants.AutoImportStrategyImportOnly,
+						},
+					},
+				},
+			},
+			expectedStrategy: apiconstants.AutoImportStrategyImportOnly,
+		},
+		{
+			name:             "managed cluster annotation overrides klusterletconfig",
+			controllerConfig: importAndSyncConfig,
+			cluster: &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+					Annotations: map[string]string{
+						"agent.open-cluster-management.io/klusterlet-config":     "test",
+						"import.open-cluster-management.io/auto-import-strategy": apiconstants.AutoImportStrategyImportAndSync,
+					},
+				},
+			},
+			klusterletConfigs: map[string]*klusterletconfigv1alpha1.KlusterletConfig{
+				"test": {
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							"import.open-cluster-management.io/auto-import-strategy": apiconstants.AutoImportStrategyImportOnly,
+						},
+					},
+				},
+			},
+			expectedStrategy: apiconstants.AutoImportStrategyImportAndSync,
+		},
 		{
 			name: "configmap without strategy config",
 			controllerConfig: &corev1.ConfigMap{
@@ -570,8 +687,17 @@ func TestAutoImportStrategyGetter(t *testing.T) {
 			if c.controllerConfig != nil {
 				configmapInformer.GetStore().Add(c.controllerConfig)
 			}
-			autoImportStrategyGetter := AutoImportStrategyGetter("test", kubeInformerFactory.Core().V1().ConfigMaps().Lister(), logf.Log.WithName("auto-import-strategy-getter"))
-			autoImportStrategy, err := autoImportStrategyGetter()
+			kcLister := &mockKlusterletConfigLister{
+				GetFunc: func(name string) (*klusterletconfigv1alpha1.KlusterletConfig, error) {
+					if kc, ok := c.klusterletConfigs[name]; ok {
+						return kc, nil
+					}
+					return nil, errors.NewNotFound(klusterletconfigv1alpha1.Resource("klusterletconfigs"), name)
+				},
+			}
+			autoImportStrategyGetter := AutoImportStrategyGetter("test", kubeInformerFactory.Core().V1().ConfigMaps().Lister(),
+				kcLister, logf.Log.WithName("auto-import-strategy-getter"))
+			autoImportStrategy, err := autoImportStrategyGetter(c.cluster)
 			if err != nil {
 				t.Errorf("unexpected err %v", err)
 			}
@@ -0,0 +1,108 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// BootstrapCredentials describes when the credentials in the bootstrap kubeconfig of a managed cluster expire and
+// when the bootstrap kubeconfig is rotated next time. A zero time means it is unknown or not applicable, e.g. the
+// token does not expire.
+type BootstrapCredentials struct {
+	TokenCreation   time.Time
+	TokenExpiration time.Time
+	TokenRevocation time.Time
+	CAExpiration    time.Time
+	NextRotation    time.Time
+}
+
+// Annotations returns the annotations of the import secret to report the bootstrap credentials. The key of a zero
+// time has a "-" suffix, so the annotation is removed from the existing import secret when it is applied.
+func (c BootstrapCredentials) Annotations() map[string]string {
+	annotations := map[string]string{}
+	for key, t := range map[string]time.Time{
+		constants.AnnotationBootstrapTokenCreation:   c.TokenCreation,
+		constants.AnnotationBootstrapTokenExpiration: c.TokenExpiration,
+		constants.AnnotationBootstrapTokenRevocation: c.TokenRevocation,
+		constants.AnnotationBootstrapCAExpiration:    c.CAExpiration,
+		constants.AnnotationBootstrapNextRotation:    c.NextRotation,
+	} {
+		if t.IsZero() {
+			annotations[key+"-"] = ""
+			continue
+		}
+		annotations[key] = t.UTC().Format(time.RFC3339)
+	}
+	return annotations
+}
+
+// NewManagedClusterBootstrapCredentialsCondition returns the bootstrap credentials condition, the condition status
+// is false if the token or all of the certificates in the CA bundle of the bootstrap kubeconfig are expired. A token
+// which is revoked in the ephemeral mode is expected, so it does not make the condition false.
+func NewManagedClusterBootstrapCredentialsCondition(credentials BootstrapCredentials, now time.Time) metav1.Condition {
+	messages := []string{}
+	if credentials.TokenExpiration.IsZero() {
+		messages = append(messages, "The bootstrap token does not expire.")
+	} else {
+		messages = append(messages, fmt.Sprintf("The bootstrap token is created at %s and expires at %s.",
+			formatTime(credentials.TokenCreation), formatTime(credentials.TokenExpiration)))
+	}
+	if !credentials.TokenRevocation.IsZero() {
+		messages = append(messages, fmt.Sprintf("The bootstrap token is revoked at %s.",
+			formatTime(credentials.TokenRevocation)))
+	}
+	if !credentials.CAExpiration.IsZero() {
+		messages = append(messages, fmt.Sprintf("The CA bundle expires at %s.", formatTime(credentials.CAExpiration)))
+	}
+	if !credentials.NextRotation.IsZero() {
+		messages = append(messages, fmt.Sprintf("The bootstrap kubeconfig is rotated at %s.",
+			formatTime(credentials.NextRotation)))
+	}
+
+	expired := (credentials.TokenRevocation.IsZero() && !credentials.TokenExpiration.IsZero() &&
+		!now.Before(credentials.TokenExpiration)) ||
+		(!credentials.CAExpiration.IsZero() && !now.Before(credentials.CAExpiration))
+	if expired {
+		return metav1.Condition{
+			Type:    constants.ConditionManagedClusterBootstrapCredentialsValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  constants.ConditionReasonManagedClusterBootstrapCredentialsExpired,
+			Message: strings.Join(messages, " "),
+		}
+	}
+
+	return metav1.Condition{
+		Type:    constants.ConditionManagedClusterBootstrapCredentialsValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  constants.ConditionReasonManagedClusterBootstrapCredentialsValid,
+		Message: strings.Join(messages, " "),
+	}
+}
+
+// UpdateManagedClusterBootstrapCredentialsCondition update managed cluster bootstrap credentials condition
+func UpdateManagedClusterBootstrapCredentialsCondition(client client.Client,
+	managedCluster *clusterv1.ManagedCluster, cond metav1.Condition) error {
+	if cond.Type != constants.ConditionManagedClusterBootstrapCredentialsValid {
+		return fmt.Errorf("the condition type %s is not supported", cond.Type)
+	}
+
+	_, err := updateManagedClusterStatus(client, managedCluster.Name, cond)
+	return err
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestBootstrapCredentialsAnnotations(t *testing.T) {
+	expiration := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	credentials := BootstrapCredentials{
+		TokenExpiration: expiration,
+		CAExpiration:    expiration,
+	}
+
+	expected := map[string]string{
+		constants.AnnotationBootstrapTokenCreation + "-":   "",
+		constants.AnnotationBootstrapTokenExpiration:       "2026-01-02T03:04:05Z",
+		constants.AnnotationBootstrapTokenRevocation + "-": "",
+		constants.AnnotationBootstrapCAExpiration:          "2026-01-02T03:04:05Z",
+		constants.AnnotationBootstrapNextRotation + "-":    "",
+	}
+	if annotations := credentials.Annotations(); !reflect.DeepEqual(annotations, expected) {
+		t.Errorf("expected %v, but got %v", expected, annotations)
+	}
+}
+
+func TestNewManagedClusterBootstrapCredentialsCondition(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name            string
+		credentials     BootstrapCredentials
+		expectedStatus  metav1.ConditionStatus
+		expectedMessage string
+	}{
+		{
+			name:            "token does not expire",
+			credentials:     BootstrapCredentials{},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedMessage: "The bootstrap token does not expire.",
+		},
+		{
+			name: "valid",
+			credentials: BootstrapCredentials{
+				TokenCreation:   now.AddDate(0, -1, 0),
+				TokenExpiration: now.AddDate(1, 0, 0),
+				CAExpiration:    now.AddDate(2, 0, 0),
+				NextRotation:    now.AddDate(0, 9, 0),
+			},
+			expectedStatus: metav1.ConditionTrue,
+			expectedMessage: "The bootstrap token is created at 2025-12-01T00:00:00Z and expires at " +
+				"2027-01-01T00:00:00Z. The CA bundle expires at 2028-01-01T00:00:00Z. The bootstrap kubeconfig is " +
+				"rotated at 2026-10-01T00:00:00Z.",
+		},
+		{
+			name: "ca expired",
+			credentials: BootstrapCredentials{
+				CAExpiration: now.Add(-1 * time.Hour),
+			},
+			expectedStatus: metav1.ConditionFalse,
+			expectedMessage: "The bootstrap token does not expire. The CA bundle expires at " +
+				"2025-12-31T23:00:00Z.",
+		},
+		{
+			name: "expired token is revoked",
+			credentials: BootstrapCredentials{
+				TokenCreation:   now.Add(-2 * time.Hour),
+				TokenExpiration: now.Add(-1 * time.Hour),
+				TokenRevocation: now.Add(-90 * time.Minute),
+			},
+			expectedStatus: metav1.ConditionTrue,
+			expectedMessage: "The bootstrap token is created at 2025-12-31T22:00:00Z and expires at " +
+				"2025-12-31T23:00:00Z. The bootstrap token is revoked at 2025-12-31T22:30:00Z.",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cond := NewManagedClusterBootstrapCredentialsCondition(c.credentials, now)
+			if cond.Status != c.expectedStatus {
+				t.Errorf("expected status %s, but got %s", c.expectedStatus, cond.Status)
+			}
+			if cond.Message != c.expectedMessage {
+				t.Errorf("expected message %q, but got %q", c.expectedMessage, cond.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	kevents "k8s.io/client-go/tools/events"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// CARotationPolicy is the policy to rotate the CA bundle of the bootstrap kubeconfigs
+type CARotationPolicy struct {
+	// Overlap is the period in which the certificates removed from the CA bundle are kept in the bootstrap
+	// kubeconfigs with the new ones, the CA rotation mode is disabled if it is 0
+	Overlap time.Duration
+	// ExpiryWarningThreshold is how long before all of the certificates in the CA bundle expire to warn about it
+	ExpiryWarningThreshold time.Duration
+}
+
+type CARotationPolicyGetterFunc func() (CARotationPolicy, error)
+
+// CARotationPolicyGetter returns the CA rotation policy specified by the caRotationOverlap and
+// caExpiryWarningThreshold in the import-controller-config ConfigMap.
+func CARotationPolicyGetter(componentNamespace string, configMapLister corev1listers.ConfigMapLister,
+	log logr.Logger) CARotationPolicyGetterFunc {
+	return func() (CARotationPolicy, error) {
+		policy := CARotationPolicy{
+			ExpiryWarningThreshold: constants.DefaultCAExpiryWarningThreshold,
+		}
+
+		cm, err := configMapLister.ConfigMaps(componentNamespace).Get(constants.ControllerConfigConfigMapName)
+		if errors.IsNotFound(err) {
+			return policy, nil
+		}
+		if err != nil {
+			return policy, err
+		}
+
+		policy.Overlap = durationConfig(cm.Data, constants.CARotationOverlapKey, policy.Overlap, log)
+		policy.ExpiryWarningThreshold = durationConfig(cm.Data, constants.CAExpiryWarningThresholdKey,
+			policy.ExpiryWarningThreshold, log)
+		return policy, nil
+	}
+}
+
+// NewManagedClusterBootstrapCACondition returns the bootstrap CA expiring condition, the condition status is true
+// if all of the certificates in the CA bundle of the bootstrap kubeconfig expire within the warning threshold.
+func NewManagedClusterBootstrapCACondition(expiry time.Time, threshold time.Duration, now time.Time) metav1.Condition {
+	if expiry.Sub(now) <= threshold {
+		return metav1.Condition{
+			Type:   constants.ConditionManagedClusterBootstrapCAExpiring,
+			Status: metav1.ConditionTrue,
+			Reason: constants.ConditionReasonManagedClusterBootstrapCAExpiring,
+			Message: fmt.Sprintf("The CA bundle of the bootstrap kubeconfig expires at %s, the hub CA should be "+
+				"renewed", expiry.UTC().Format(time.RFC3339)),
+		}
+	}
+
+	return metav1.Condition{
+		Type:    constants.ConditionManagedClusterBootstrapCAExpiring,
+		Status:  metav1.ConditionFalse,
+		Reason:  constants.ConditionReasonManagedClusterBootstrapCAValid,
+		Message: fmt.Sprintf("The CA bundle of the bootstrap kubeconfig expires at %s", expiry.UTC().Format(time.RFC3339)),
+	}
+}
+
+// UpdateManagedClusterBootstrapCACondition update managed cluster bootstrap CA expiring condition and record the
+// event when the status of the condition is changed
+func UpdateManagedClusterBootstrapCACondition(client client.Client, managedCluster *clusterv1.ManagedCluster,
+	cond metav1.Condition, recorder kevents.EventRecorder) error {
+	if cond.Type != constants.ConditionManagedClusterBootstrapCAExpiring {
+		return fmt.Errorf("the condition type %s is not supported", cond.Type)
+	}
+
+	// the message is changed with the expiry, only the status change is recorded
+	oldStatus := metav1.ConditionUnknown
+	if oldCond := meta.FindStatusCondition(managedCluster.Status.Conditions, cond.Type); oldCond != nil {
+		oldStatus = oldCond.Status
+	}
+
+	if _, err := updateManagedClusterStatus(client, managedCluster.Name, cond); err != nil {
+		return err
+	}
+	if oldStatus == cond.Status || recorder == nil {
+		return nil
+	}
+
+	mc := managedCluster.DeepCopy()
+	mc.SetNamespace(mc.Name)
+	switch cond.Reason {
+	case constants.ConditionReasonManagedClusterBootstrapCAExpiring:
+		recorder.Eventf(mc, nil, corev1.EventTypeWarning,
+			constants.EventReasonManagedClusterBootstrapCAExpiring,
+			constants.EventReasonManagedClusterBootstrapCAExpiring,
+			"The bootstrap CA of %s is about to expire. %s", mc.Name, cond.Message)
+	case constants.ConditionReasonManagedClusterBootstrapCAValid:
+		// the condition is initialized as valid, only the renewal of an expiring CA is recorded
+		if oldStatus == metav1.ConditionTrue {
+			recorder.Eventf(mc, nil, corev1.EventTypeNormal,
+				constants.EventReasonManagedClusterBootstrapCARenewed,
+				constants.EventReasonManagedClusterBootstrapCARenewed,
+				"The bootstrap CA of %s is renewed. %s", mc.Name, cond.Message)
+		}
+	default:
+		return fmt.Errorf("the condition reason %s is not supported", cond.Reason)
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestCARotationPolicyGetter(t *testing.T) {
+	cases := []struct {
+		name           string
+		configMap      *corev1.ConfigMap
+		expectedPolicy CARotationPolicy
+	}{
+		{
+			name:           "no configmap",
+			expectedPolicy: CARotationPolicy{ExpiryWarningThreshold: constants.DefaultCAExpiryWarningThreshold},
+		},
+		{
+			name: "valid policy",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: constants.ControllerConfigConfigMapName, Namespace: "test"},
+				Data: map[string]string{
+					constants.CARotationOverlapKey:        "24h",
+					constants.CAExpiryWarningThresholdKey: "168h",
+				},
+			},
+			expectedPolicy: CARotationPolicy{Overlap: 24 * time.Hour, ExpiryWarningThreshold: 168 * time.Hour},
+		},
+		{
+			name: "invalid policy",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: constants.ControllerConfigConfigMapName, Namespace: "test"},
+				Data: map[string]string{
+					constants.CARotationOverlapKey:        "-1h",
+					constants.CAExpiryWarningThresholdKey: "invalid",
+				},
+			},
+			expectedPolicy: CARotationPolicy{ExpiryWarningThreshold: constants.DefaultCAExpiryWarningThreshold},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+			if c.configMap != nil {
+				if err := informerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(c.configMap); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			policy, err := CARotationPolicyGetter("test", informerFactory.Core().V1().ConfigMaps().Lister(),
+				logf.Log)()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if policy != c.expectedPolicy {
+				t.Errorf("expected %v, but got %v", c.expectedPolicy, policy)
+			}
+		})
+	}
+}
+
+func TestUpdateManagedClusterBootstrapCACondition(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name           string
+		existingStatus metav1.ConditionStatus
+		expiry         time.Time
+		expectedStatus metav1.ConditionStatus
+		expectedEvents int
+	}{
+		{
+			name:           "valid",
+			expiry:         now.Add(60 * 24 * time.Hour),
+			expectedStatus: metav1.ConditionFalse,
+		},
+		{
+			name:           "expiring",
+			expiry:         now.Add(10 * 24 * time.Hour),
+			expectedStatus: metav1.ConditionTrue,
+			expectedEvents: 1,
+		},
+		{
+			name:           "still expiring",
+			existingStatus: metav1.ConditionTrue,
+			expiry:         now.Add(5 * 24 * time.Hour),
+			expectedStatus: metav1.ConditionTrue,
+		},
+		{
+			name:           "renewed",
+			existingStatus: metav1.ConditionTrue,
+			expiry:         now.Add(365 * 24 * time.Hour),
+			expectedStatus: metav1.ConditionFalse,
+			expectedEvents: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedCluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test_cluster"},
+			}
+			if len(c.existingStatus) > 0 {
+				managedCluster.Status.Conditions = []metav1.Condition{{
+					Type:               constants.ConditionManagedClusterBootstrapCAExpiring,
+					Status:             c.existingStatus,
+					Reason:             constants.ConditionReasonManagedClusterBootstrapCAExpiring,
+					LastTransitionTime: metav1.Now(),
+				}}
+			}
+
+			fakeClient := fake.NewClientBuilder().WithScheme(testscheme).
+				WithObjects(managedCluster).WithStatusSubresource(managedCluster).Build()
+			kubeClient := kubefake.NewSimpleClientset()
+			recorder := NewManagedClusterEventRecorder(context.TODO(), kubeClient)
+
+			cond := NewManagedClusterBootstrapCACondition(c.expiry, constants.DefaultCAExpiryWarningThreshold, now)
+			if err := UpdateManagedClusterBootstrapCACondition(fakeClient, managedCluster, cond, recorder); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			updated := &clusterv1.ManagedCluster{}
+			if err := fakeClient.Get(context.TODO(), client.ObjectKeyFromObject(managedCluster), updated); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !meta.IsStatusConditionPresentAndEqual(updated.Status.Conditions,
+				constants.ConditionManagedClusterBootstrapCAExpiring, c.expectedStatus) {
+				t.Errorf("expected condition status %s, but got %v", c.expectedStatus, updated.Status.Conditions)
+			}
+
+			// under the hood, the events are created asynchronously, so we need to wait a bit
+			time.Sleep(1 * time.Second)
+			if len(kubeClient.Actions()) != c.expectedEvents {
+				t.Errorf("expected %d events, but got %d", c.expectedEvents, len(kubeClient.Actions()))
+			}
+		})
+	}
+}
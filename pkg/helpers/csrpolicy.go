@@ -0,0 +1,226 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-logr/logr"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// clusterNamePlaceholder is replaced with the cluster name of the CSR in the common name pattern of a rule
+const clusterNamePlaceholder = "{clusterName}"
+
+type CSRApprovalAction string
+
+const (
+	CSRApprovalActionApprove CSRApprovalAction = "Approve"
+	CSRApprovalActionDeny    CSRApprovalAction = "Deny"
+)
+
+// CSRApprovalPolicy is a list of rules to approve or deny the CSRs of the managed clusters. The rules are evaluated
+// in order, and the first matched rule decides the CSR. If no rule matches, the CSR is handled by the built-in
+// approval conditions.
+type CSRApprovalPolicy struct {
+	Rules []CSRApprovalRule `json:"rules"`
+}
+
+// CSRApprovalRule matches a CSR if all of its specified fields match the CSR.
+type CSRApprovalRule struct {
+	// Name is the unique name of the rule, it is recorded in the condition reason of the CSR
+	Name string `json:"name"`
+	// Action is Approve or Deny
+	Action CSRApprovalAction `json:"action"`
+	// SignerNames matches the signer name of the CSR
+	SignerNames []string `json:"signerNames,omitempty"`
+	// Users matches the requesting user of the CSR
+	Users []string `json:"users,omitempty"`
+	// Groups matches if the requesting user of the CSR is in one of the groups
+	Groups []string `json:"groups,omitempty"`
+	// ClusterNamePattern is a regular expression which the whole cluster name label of the CSR must match
+	ClusterNamePattern string `json:"clusterNamePattern,omitempty"`
+	// CommonNamePattern is a regular expression which the whole subject common name of the CSR must match,
+	// {clusterName} in it is replaced with the cluster name of the CSR
+	CommonNamePattern string `json:"commonNamePattern,omitempty"`
+	// SANPattern is a regular expression which each of the requested subject alternative names (DNS names,
+	// IP addresses, email addresses and URIs) of the CSR must match
+	SANPattern string `json:"sanPattern,omitempty"`
+	// Usages matches if the requested key usages of the CSR are a subset of them
+	Usages []certificatesv1.KeyUsage `json:"usages,omitempty"`
+
+	clusterNameRegexp *regexp.Regexp
+	sanRegexp         *regexp.Regexp
+}
+
+// ParseCSRApprovalPolicy parses the CSR approval policy from YAML and validates its rules.
+func ParseCSRApprovalPolicy(value string) (*CSRApprovalPolicy, error) {
+	policy := &CSRApprovalPolicy{}
+	if err := yaml.UnmarshalStrict([]byte(value), policy); err != nil {
+		return nil, err
+	}
+
+	names := sets.New[string]()
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		if len(rule.Name) == 0 {
+			return nil, fmt.Errorf("the name of the rule %d is empty", i)
+		}
+		if names.Has(rule.Name) {
+			return nil, fmt.Errorf("the rule name %q is duplicated", rule.Name)
+		}
+		names.Insert(rule.Name)
+
+		if rule.Action != CSRApprovalActionApprove && rule.Action != CSRApprovalActionDeny {
+			return nil, fmt.Errorf("the action %q of the rule %q is invalid, it should be %s or %s",
+				rule.Action, rule.Name, CSRApprovalActionApprove, CSRApprovalActionDeny)
+		}
+
+		var err error
+		if rule.clusterNameRegexp, err = compileFullMatchRegexp(rule.ClusterNamePattern); err != nil {
+			return nil, fmt.Errorf("the cluster name pattern of the rule %q is invalid: %v", rule.Name, err)
+		}
+		// the cluster name is unknown until a CSR is matched, validate the pattern with a placeholder
+		if _, err = compileFullMatchRegexp(strings.ReplaceAll(rule.CommonNamePattern, clusterNamePlaceholder,
+			"cluster")); err != nil {
+			return nil, fmt.Errorf("the common name pattern of the rule %q is invalid: %v", rule.Name, err)
+		}
+		if rule.sanRegexp, err = compileFullMatchRegexp(rule.SANPattern); err != nil {
+			return nil, fmt.Errorf("the SAN pattern of the rule %q is invalid: %v", rule.Name, err)
+		}
+	}
+	return policy, nil
+}
+
+// Match returns the first rule which matches the CSR, nil is returned if there is no matched rule.
+func (p *CSRApprovalPolicy) Match(csr *certificatesv1.CertificateSigningRequest) *CSRApprovalRule {
+	if p == nil {
+		return nil
+	}
+
+	// the request is parsed only when a rule checks the subject, a CSR with an invalid request does not match
+	// these rules
+	var request *x509.CertificateRequest
+	requestParsed := false
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if len(rule.CommonNamePattern) > 0 || len(rule.SANPattern) > 0 {
+			if !requestParsed {
+				request, _ = parseCertificateRequest(csr.Spec.Request)
+				requestParsed = true
+			}
+			if request == nil {
+				continue
+			}
+		}
+
+		if rule.matches(csr, request) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (r *CSRApprovalRule) matches(csr *certificatesv1.CertificateSigningRequest, request *x509.CertificateRequest) bool {
+	if len(r.SignerNames) > 0 && !sets.New(r.SignerNames...).Has(csr.Spec.SignerName) {
+		return false
+	}
+	if len(r.Users) > 0 && !sets.New(r.Users...).Has(csr.Spec.Username) {
+		return false
+	}
+	if len(r.Groups) > 0 && !sets.New(r.Groups...).HasAny(csr.Spec.Groups...) {
+		return false
+	}
+	if r.clusterNameRegexp != nil && !r.clusterNameRegexp.MatchString(GetClusterName(csr)) {
+		return false
+	}
+	if len(r.Usages) > 0 && !sets.New(r.Usages...).HasAll(csr.Spec.Usages...) {
+		return false
+	}
+
+	if len(r.CommonNamePattern) > 0 {
+		commonNameRegexp, err := compileFullMatchRegexp(strings.ReplaceAll(r.CommonNamePattern, clusterNamePlaceholder,
+			regexp.QuoteMeta(GetClusterName(csr))))
+		if err != nil || !commonNameRegexp.MatchString(request.Subject.CommonName) {
+			return false
+		}
+	}
+	if r.sanRegexp != nil {
+		for _, san := range subjectAlternativeNames(request) {
+			if !r.sanRegexp.MatchString(san) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func compileFullMatchRegexp(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) == 0 {
+		return nil, nil
+	}
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+func parseCertificateRequest(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("the request is not a PEM encoded certificate request")
+	}
+	return x509.ParseCertificateRequest(block.Bytes)
+}
+
+func subjectAlternativeNames(request *x509.CertificateRequest) []string {
+	sans := append([]string{}, request.DNSNames...)
+	sans = append(sans, request.EmailAddresses...)
+	for _, ip := range request.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range request.URIs {
+		sans = append(sans, uri.String())
+	}
+	return sans
+}
+
+type CSRApprovalPolicyGetterFunc func() (*CSRApprovalPolicy, error)
+
+// CSRApprovalPolicyGetter returns the CSR approval policy in the csrApprovalPolicy of the import-controller-config
+// ConfigMap, nil is returned if there is no policy or the policy is invalid.
+func CSRApprovalPolicyGetter(componentNamespace string, configMapLister corev1listers.ConfigMapLister,
+	log logr.Logger) CSRApprovalPolicyGetterFunc {
+	return func() (*CSRApprovalPolicy, error) {
+		cm, err := configMapLister.ConfigMaps(componentNamespace).Get(constants.ControllerConfigConfigMapName)
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		value, ok := cm.Data[constants.CSRApprovalPolicyKey]
+		if !ok {
+			return nil, nil
+		}
+
+		policy, err := ParseCSRApprovalPolicy(value)
+		if err != nil {
+			log.Info("Invalid config value found and use default instead.",
+				"configmap", constants.ControllerConfigConfigMapName,
+				constants.CSRApprovalPolicyKey, value,
+				"error", err.Error())
+			return nil, nil
+		}
+		return policy, nil
+	}
+}
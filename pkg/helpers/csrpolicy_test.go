@@ -0,0 +1,255 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"testing"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+const testCSRApprovalPolicy = `
+rules:
+- name: deny-prod
+  action: Deny
+  clusterNamePattern: prod-.*
+- name: approve-agents
+  action: Approve
+  signerNames:
+  - kubernetes.io/kube-apiserver-client
+  groups:
+  - system:bootstrappers:managedcluster
+  commonNamePattern: "system:open-cluster-management:{clusterName}:[a-z0-9]+"
+  sanPattern: ""
+  usages:
+  - digital signature
+  - key encipherment
+  - client auth
+- name: approve-no-san
+  action: Approve
+  users:
+  - admin
+  sanPattern: "^$"
+`
+
+func newTestCertificateRequest(t *testing.T, commonName string, dnsNames ...string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: commonName},
+		DNSNames: dnsNames,
+	}, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate request: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func newPolicyTestCSR(clusterName, username string, groups []string, request []byte,
+	usages ...certificatesv1.KeyUsage) *certificatesv1.CertificateSigningRequest {
+	return &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "csr",
+			Labels: map[string]string{constants.CSRClusterNameLabel: clusterName},
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			SignerName: certificatesv1.KubeAPIServerClientSignerName,
+			Username:   username,
+			Groups:     groups,
+			Request:    request,
+			Usages:     usages,
+		},
+	}
+}
+
+func TestParseCSRApprovalPolicy(t *testing.T) {
+	cases := []struct {
+		name        string
+		value       string
+		expectedErr bool
+	}{
+		{
+			name:  "valid policy",
+			value: testCSRApprovalPolicy,
+		},
+		{
+			name:  "empty policy",
+			value: "",
+		},
+		{
+			name:        "no name",
+			value:       "rules:\n- action: Approve\n",
+			expectedErr: true,
+		},
+		{
+			name:        "duplicated name",
+			value:       "rules:\n- name: a\n  action: Approve\n- name: a\n  action: Deny\n",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid action",
+			value:       "rules:\n- name: a\n  action: Ignore\n",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid pattern",
+			value:       "rules:\n- name: a\n  action: Approve\n  clusterNamePattern: \"(\"\n",
+			expectedErr: true,
+		},
+		{
+			name:        "unknown field",
+			value:       "rules:\n- name: a\n  action: Approve\n  user: admin\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := ParseCSRApprovalPolicy(c.value)
+			if (err != nil) != c.expectedErr {
+				t.Errorf("expected error %v, but got %v", c.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestCSRApprovalPolicyMatch(t *testing.T) {
+	policy, err := ParseCSRApprovalPolicy(testCSRApprovalPolicy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	agentGroups := []string{"system:bootstrappers:managedcluster", "system:authenticated"}
+	agentUsages := []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageClientAuth}
+	cases := []struct {
+		name         string
+		csr          *certificatesv1.CertificateSigningRequest
+		expectedRule string
+	}{
+		{
+			name: "denied by cluster name",
+			csr: newPolicyTestCSR("prod-1", "agent", agentGroups,
+				newTestCertificateRequest(t, "system:open-cluster-management:prod-1:abc"), agentUsages...),
+			expectedRule: "deny-prod",
+		},
+		{
+			name: "approved agent",
+			csr: newPolicyTestCSR("cluster1", "agent", agentGroups,
+				newTestCertificateRequest(t, "system:open-cluster-management:cluster1:abc"), agentUsages...),
+			expectedRule: "approve-agents",
+		},
+		{
+			name: "common name of another cluster",
+			csr: newPolicyTestCSR("cluster1", "agent", agentGroups,
+				newTestCertificateRequest(t, "system:open-cluster-management:cluster2:abc"), agentUsages...),
+		},
+		{
+			name: "not in the group",
+			csr: newPolicyTestCSR("cluster1", "agent", []string{"system:authenticated"},
+				newTestCertificateRequest(t, "system:open-cluster-management:cluster1:abc"), agentUsages...),
+		},
+		{
+			name: "unexpected usage",
+			csr: newPolicyTestCSR("cluster1", "agent", agentGroups,
+				newTestCertificateRequest(t, "system:open-cluster-management:cluster1:abc"),
+				certificatesv1.UsageServerAuth),
+		},
+		{
+			name:         "user without sans",
+			csr:          newPolicyTestCSR("cluster1", "admin", nil, newTestCertificateRequest(t, "admin")),
+			expectedRule: "approve-no-san",
+		},
+		{
+			name: "user with sans",
+			csr: newPolicyTestCSR("cluster1", "admin", nil,
+				newTestCertificateRequest(t, "admin", "example.com")),
+		},
+		{
+			name: "invalid request",
+			csr:  newPolicyTestCSR("cluster1", "admin", nil, []byte("invalid")),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rule := policy.Match(c.csr)
+			ruleName := ""
+			if rule != nil {
+				ruleName = rule.Name
+			}
+			if ruleName != c.expectedRule {
+				t.Errorf("expected rule %q, but got %q", c.expectedRule, ruleName)
+			}
+		})
+	}
+}
+
+func TestCSRApprovalPolicyGetter(t *testing.T) {
+	cases := []struct {
+		name          string
+		configMap     *corev1.ConfigMap
+		expectedRules int
+	}{
+		{
+			name:          "no configmap",
+			expectedRules: 0,
+		},
+		{
+			name: "valid policy",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: constants.ControllerConfigConfigMapName, Namespace: "test"},
+				Data:       map[string]string{constants.CSRApprovalPolicyKey: testCSRApprovalPolicy},
+			},
+			expectedRules: 3,
+		},
+		{
+			name: "invalid policy",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: constants.ControllerConfigConfigMapName, Namespace: "test"},
+				Data:       map[string]string{constants.CSRApprovalPolicyKey: "rules: invalid"},
+			},
+			expectedRules: 0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+			if c.configMap != nil {
+				if err := informerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(c.configMap); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			policy, err := CSRApprovalPolicyGetter("test", informerFactory.Core().V1().ConfigMaps().Lister(),
+				logf.Log)()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			rules := 0
+			if policy != nil {
+				rules = len(policy.Rules)
+			}
+			if rules != c.expectedRules {
+				t.Errorf("expected %d rules, but got %d", c.expectedRules, rules)
+			}
+		})
+	}
+}
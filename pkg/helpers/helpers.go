@@ -64,14 +64,17 @@ import (
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/features"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+	"github.com/stolostron/managedcluster-import-controller/pkg/metrics"
 	"sigs.k8s.io/yaml"
 )
 
 const maxConcurrentReconcilesEnvVarName = "MAX_CONCURRENT_RECONCILES"
 
 const (
-	nodeSelectorAnnotation = "open-cluster-management/nodeSelector"
-	tolerationsAnnotation  = "open-cluster-management/tolerations"
+	// NodeSelectorAnnotation and TolerationsAnnotation are the annotation keys of the managed cluster to specify
+	// the node selector and tolerations of the klusterlet in JSON.
+	NodeSelectorAnnotation = "open-cluster-management/nodeSelector"
+	TolerationsAnnotation  = "open-cluster-management/tolerations"
 )
 
 // DeployOnOCP is set once at the beginning
@@ -119,25 +122,39 @@ func GetMaxConcurrentReconciles() int {
 	return maxConcurrentReconciles
 }
 
-// GenerateImportClientFromKubeConfigSecret generate a client from a given secret that contains a kubeconfig
-func GenerateImportClientFromKubeConfigSecret(secret *corev1.Secret) (reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
+// GenerateImportClientFromKubeConfigSecret generate a client from a given secret that contains a kubeconfig, if the
+// refuseInsecure is true, the kubeconfig which skips the TLS verification or has no certificate authority is refused.
+func GenerateImportClientFromKubeConfigSecret(secret *corev1.Secret, refuseInsecure bool) (
+	reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
 	if kubeconfig, ok := secret.Data["kubeconfig"]; ok {
 		config, err := clientcmd.Load(kubeconfig)
 		if err != nil {
 			return reconcile.Result{}, nil, nil, err
 		}
+		if refuseInsecure {
+			if err := validateKubeConfigTLS(config); err != nil {
+				return reconcile.Result{}, nil, nil, err
+			}
+		}
 		return buildImportClient(config)
 	}
 
 	return reconcile.Result{}, nil, nil, fmt.Errorf("kubeconfig is missing")
 }
 
-// GenerateImportClientFromKubeTokenSecret generate a client from a given secret that contains kube apiserver and token
-func GenerateImportClientFromKubeTokenSecret(secret *corev1.Secret) (reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
+// GenerateImportClientFromKubeTokenSecret generate a client from a given secret that contains kube apiserver and token,
+// the certificate of the kube apiserver is verified with the ca.crt or the ca_fingerprint of the secret. If the secret
+// does not have them, the verification is skipped unless the refuseInsecure is true.
+func GenerateImportClientFromKubeTokenSecret(secret *corev1.Secret, refuseInsecure bool) (
+	reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
 	token, tok := secret.Data["token"]
 	server, sok := secret.Data["server"]
 	if tok && sok {
-		return buildImportClient(buildKubeConfigFileWithToken(string(server), string(token)))
+		config := buildKubeConfigFileWithToken(string(server), string(token))
+		if err := setServerTLSVerification(config, secret, refuseInsecure); err != nil {
+			return reconcile.Result{}, nil, nil, err
+		}
+		return buildImportClient(config)
 	}
 
 	return reconcile.Result{}, nil, nil, fmt.Errorf("kube token or server is missing")
@@ -188,13 +205,14 @@ func GenerateImportClientFromRosaCluster(getter *RosaKubeConfigGetter, secret *c
 		getter.SetTokenURL(string(tokeURL))
 	}
 
-	if retryTimes, ok := secret.Data[constants.AutoImportSecretRosaConfigRetryTimesKey]; ok {
-		getter.SetRetryTimes(string(retryTimes))
+	// the import is retried with the auto import retry policy until the kubeconfig is ready
+	config, err := getter.KubeConfig()
+	if err != nil {
+		return reconcile.Result{}, nil, nil, err
 	}
 
-	requeue, config, err := getter.KubeConfig()
-	if err != nil {
-		return reconcile.Result{Requeue: requeue, RequeueAfter: rosaImportRetryPeriod}, nil, nil, err
+	if err := setServerTLSVerification(config, secret, getter.insecureRefused); err != nil {
+		return reconcile.Result{}, nil, nil, err
 	}
 
 	return buildImportClient(config)
@@ -351,9 +369,9 @@ func SetImmediateImportCompleted(client client.Client, managedClusterName string
 	return nil
 }
 
-// UpdateManagedClusterImportCondition update managed cluster status and record the event
+// UpdateManagedClusterImportCondition update managed cluster status, record the event and the import history
 func UpdateManagedClusterImportCondition(client client.Client, managedCluster *clusterv1.ManagedCluster,
-	cond metav1.Condition, recorder kevents.EventRecorder) error {
+	cond metav1.Condition, recorder kevents.EventRecorder, source ImportAttemptSource) error {
 	if cond.Type != constants.ConditionManagedClusterImportSucceeded {
 		return fmt.Errorf("the condition type %s is not supported", cond.Type)
 	}
@@ -366,6 +384,19 @@ func UpdateManagedClusterImportCondition(client client.Client, managedCluster *c
 		return nil
 	}
 
+	recordImportConditionMetrics(managedCluster, cond)
+
+	// the import history is only used for troubleshooting, so the failure of recording it is ignored
+	if err := appendImportHistory(context.TODO(), client, managedCluster.Name, ImportAttempt{
+		Time:       metav1.Now(),
+		Controller: source.Controller,
+		SecretType: source.SecretType,
+		Outcome:    cond.Reason,
+		Message:    cond.Message,
+	}); err != nil {
+		klog.Errorf("Failed to record the import history of the managed cluster %s: %v", managedCluster.Name, err)
+	}
+
 	mc := managedCluster.DeepCopy()
 	mc.SetNamespace(mc.Name)
 	switch cond.Reason {
@@ -400,6 +431,22 @@ func UpdateManagedClusterImportCondition(client client.Client, managedCluster *c
 	return nil
 }
 
+func recordImportConditionMetrics(managedCluster *clusterv1.ManagedCluster, cond metav1.Condition) {
+	existing := meta.FindStatusCondition(managedCluster.Status.Conditions, cond.Type)
+	if existing != nil && existing.Reason == cond.Reason {
+		return
+	}
+
+	metrics.RecordImportConditionTransition(cond.Reason)
+
+	// the condition status is kept false from waiting for importing to importing, so the last transition
+	// time of the existing condition is the time when the cluster started to wait for importing.
+	if cond.Reason == constants.ConditionReasonManagedClusterImported &&
+		existing != nil && existing.Status == metav1.ConditionFalse {
+		metrics.RecordImportDuration(existing.LastTransitionTime.Time)
+	}
+}
+
 // ValidateImportSecret validate managed cluster import secret
 func ValidateImportSecret(importSecret *corev1.Secret) error {
 	if data, ok := importSecret.Data[constants.ImportSecretImportYamlKey]; !ok || len(data) == 0 {
@@ -807,7 +854,7 @@ func GetComponentNamespace() (string, error) {
 func GetNodeSelectorFromManagedClusterAnnotations(clusterAnnotations map[string]string) (map[string]string, error) {
 	nodeSelector := map[string]string{}
 
-	nodeSelectorString, ok := clusterAnnotations[nodeSelectorAnnotation]
+	nodeSelectorString, ok := clusterAnnotations[NodeSelectorAnnotation]
 	if !ok {
 		return nodeSelector, nil
 	}
@@ -822,7 +869,7 @@ func GetNodeSelectorFromManagedClusterAnnotations(clusterAnnotations map[string]
 func GetTolerationsFromManagedClusterAnnotations(clusterAnnotations map[string]string) ([]corev1.Toleration, error) {
 	tolerations := []corev1.Toleration{}
 
-	tolerationsString, ok := clusterAnnotations[tolerationsAnnotation]
+	tolerationsString, ok := clusterAnnotations[TolerationsAnnotation]
 	if !ok {
 		// return a defautl toleration
 		return []corev1.Toleration{
@@ -142,7 +142,7 @@ func TestGenerateClientFromSecret(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			secret := c.generateSecret(config.Host, config)
-			_, _, _, err = GenerateImportClientFromKubeConfigSecret(secret)
+			_, _, _, err = GenerateImportClientFromKubeConfigSecret(secret, false)
 			if c.expectedErr != "" && err == nil {
 				t.Errorf("expected error, but failed")
 			}
@@ -332,7 +332,8 @@ func TestUpdateManagedClusterImportCondition(t *testing.T) {
 			ctx := context.TODO()
 			recorder := NewManagedClusterEventRecorder(ctx, kubeClient)
 
-			err := UpdateManagedClusterImportCondition(fakeClient, c.managedCluster, c.cond, recorder)
+			err := UpdateManagedClusterImportCondition(fakeClient, c.managedCluster, c.cond, recorder,
+				ImportAttemptSource{Controller: ImportSourceAutoImport})
 			if len(c.expectedErr) > 0 {
 				if err == nil {
 					t.Errorf("expected error %s, but got nil", c.expectedErr)
@@ -1749,7 +1750,7 @@ func TestGenerateImportClientFromKubeTokenSecret(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			_, _, _, err := GenerateImportClientFromKubeTokenSecret(c.secret)
+			_, _, _, err := GenerateImportClientFromKubeTokenSecret(c.secret, false)
 			if !c.expectErr && err != nil {
 				t.Errorf("unexpected error %v", err)
 			}
@@ -1836,11 +1837,10 @@ func TestGenerateImportClientFromRosaCluster(t *testing.T) {
 					Name: "auto-import-secret",
 				},
 				Data: map[string][]byte{
-					"api_token":   []byte(accessToken),
-					"cluster_id":  []byte("c0001"),
-					"api_url":     []byte(apiServer.URL()),
-					"token_url":   []byte(oidServer.URL()),
-					"retry_times": []byte("2"),
+					"api_token":  []byte(accessToken),
+					"cluster_id": []byte("c0001"),
+					"api_url":    []byte(apiServer.URL()),
+					"token_url":  []byte(oidServer.URL()),
 				},
 			},
 		},
@@ -1848,9 +1848,13 @@ func TestGenerateImportClientFromRosaCluster(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			result, _, _, _ := GenerateImportClientFromRosaCluster(c.getter, c.secret)
-			if !result.Requeue {
-				t.Errorf("expected requeue result, but failed")
+			// the import is retried with the auto import retry policy until the kubeconfig is ready
+			result, _, _, err := GenerateImportClientFromRosaCluster(c.getter, c.secret)
+			if err == nil {
+				t.Errorf("expected the kubeconfig is not ready, but no error")
+			}
+			if result.Requeue || result.RequeueAfter > 0 {
+				t.Errorf("expected no explicit requeue, but got %v", result)
 			}
 		})
 	}
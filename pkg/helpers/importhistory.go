@@ -0,0 +1,131 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// the sources of the import attempts
+const (
+	ImportSourceAutoImport        = "autoimport"
+	ImportSourceClusterDeployment = "clusterdeployment"
+	ImportSourceSelfManaged       = "selfmanaged"
+	ImportSourceHosted            = "hosted"
+	ImportSourceImportStatus      = "importstatus"
+	ImportSourceResourceCleanup   = "resourcecleanup"
+)
+
+// ImportAttemptSource describes who updates the import condition of a managed cluster
+type ImportAttemptSource struct {
+	// Controller is the source controller of the import attempt
+	Controller string
+	// SecretType is the type of the secret used to import the managed cluster, it is empty if the managed
+	// cluster is not imported with a secret
+	SecretType corev1.SecretType
+}
+
+// ImportAttempt is an entry of the import history of a managed cluster
+type ImportAttempt struct {
+	Time       metav1.Time       `json:"time"`
+	Controller string            `json:"controller"`
+	SecretType corev1.SecretType `json:"secretType,omitempty"`
+	Outcome    string            `json:"outcome"`
+	Message    string            `json:"message,omitempty"`
+}
+
+// appendImportHistory appends the import attempt to the import history ConfigMap in the managed cluster namespace,
+// only the latest ImportHistoryLimit attempts are kept.
+func appendImportHistory(ctx context.Context, runtimeClient client.Client, clusterName string,
+	attempt ImportAttempt) error {
+	cm := &corev1.ConfigMap{}
+	err := runtimeClient.Get(ctx, types.NamespacedName{Namespace: clusterName, Name: constants.ImportHistoryConfigMapName}, cm)
+	if errors.IsNotFound(err) {
+		history, err := json.Marshal([]ImportAttempt{attempt})
+		if err != nil {
+			return err
+		}
+
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      constants.ImportHistoryConfigMapName,
+				Namespace: clusterName,
+			},
+			Data: map[string]string{constants.ImportHistoryKey: string(history)},
+		}
+		err = runtimeClient.Create(ctx, cm)
+		if errors.IsNotFound(err) {
+			// the managed cluster namespace is not created yet or is deleted
+			return nil
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	history, err := parseImportHistory(cm)
+	if err != nil {
+		// the history is broken, start a new one
+		klog.Warningf("The import history %s/%s is reset: %v", cm.Namespace, cm.Name, err)
+		history = nil
+	}
+
+	history = append(history, attempt)
+	if len(history) > constants.ImportHistoryLimit {
+		history = history[len(history)-constants.ImportHistoryLimit:]
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+
+	cm = cm.DeepCopy()
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[constants.ImportHistoryKey] = string(data)
+	return runtimeClient.Update(ctx, cm)
+}
+
+// GetImportHistory returns the import history of a managed cluster, the latest attempt is the last one.
+func GetImportHistory(ctx context.Context, kubeClient kubernetes.Interface,
+	clusterName string) ([]ImportAttempt, error) {
+	cm, err := kubeClient.CoreV1().ConfigMaps(clusterName).Get(ctx, constants.ImportHistoryConfigMapName,
+		metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return []ImportAttempt{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseImportHistory(cm)
+}
+
+func parseImportHistory(cm *corev1.ConfigMap) ([]ImportAttempt, error) {
+	history := []ImportAttempt{}
+	data, ok := cm.Data[constants.ImportHistoryKey]
+	if !ok || len(data) == 0 {
+		return history, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &history); err != nil {
+		return nil, fmt.Errorf("failed to parse the import history: %v", err)
+	}
+	return history, nil
+}
@@ -0,0 +1,93 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAppendImportHistory(t *testing.T) {
+	cases := []struct {
+		name             string
+		existing         []runtime.Object
+		attempts         int
+		expectedAttempts int
+	}{
+		{
+			name:             "no history",
+			attempts:         1,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "bounded history",
+			attempts:         25,
+			expectedAttempts: 20,
+		},
+		{
+			name: "broken history",
+			existing: []runtime.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "import-history", Namespace: "test"},
+					Data:       map[string]string{"history": "broken"},
+				},
+			},
+			attempts:         2,
+			expectedAttempts: 2,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			runtimeClient := fake.NewClientBuilder().WithScheme(testscheme).WithRuntimeObjects(c.existing...).Build()
+
+			for i := 0; i < c.attempts; i++ {
+				if err := appendImportHistory(context.TODO(), runtimeClient, "test", ImportAttempt{
+					Time:       metav1.Now(),
+					Controller: ImportSourceAutoImport,
+					SecretType: corev1.SecretTypeOpaque,
+					Outcome:    "ManagedClusterImportFailed",
+					Message:    fmt.Sprintf("attempt %d", i),
+				}); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+
+			cm := &corev1.ConfigMap{}
+			if err := runtimeClient.Get(context.TODO(),
+				types.NamespacedName{Namespace: "test", Name: "import-history"}, cm); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			history, err := GetImportHistory(context.TODO(), kubefake.NewSimpleClientset(cm), "test")
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(history) != c.expectedAttempts {
+				t.Fatalf("expected %d attempts, but got %d", c.expectedAttempts, len(history))
+			}
+			if history[len(history)-1].Message != fmt.Sprintf("attempt %d", c.attempts-1) {
+				t.Errorf("expected the latest attempt is the last one, but got %v", history[len(history)-1])
+			}
+		})
+	}
+}
+
+func TestGetImportHistory(t *testing.T) {
+	history, err := GetImportHistory(context.TODO(), kubefake.NewSimpleClientset(), "test")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("expected no attempts, but got %v", history)
+	}
+}
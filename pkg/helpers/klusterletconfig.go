@@ -2,12 +2,23 @@ package helpers
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 
 	listerklusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
+	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
 	klusterletconfighelper "github.com/stolostron/cluster-lifecycle-api/helpers/klusterletconfig"
 	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
 )
 
 func GetMergedKlusterletConfigWithGlobal(
@@ -28,6 +39,210 @@ func GetMergedKlusterletConfigWithGlobal(
 		return nil, fmt.Errorf("failed to get global klusterletconfig: %v", err)
 	}
 
+	return MergeKlusterletConfigs(globalKlusterletConfig, kc)
+}
+
+// GetMergedKlusterletConfig returns the merged KlusterletConfig of a managed cluster. The KlusterletConfigs are
+// merged in the order of the global KlusterletConfig, the KlusterletConfigs selected by the managed cluster selector
+// from the lowest priority to the highest, and the KlusterletConfig assigned by the klusterlet-config annotation of
+// the managed cluster.
+func GetMergedKlusterletConfig(
+	managedCluster *clusterv1.ManagedCluster,
+	kcLister listerklusterletconfigv1alpha1.KlusterletConfigLister,
+) (*klusterletconfigv1alpha1.KlusterletConfig, error) {
+	klusterletconfigs, err := GetKlusterletConfigsOfManagedCluster(managedCluster, kcLister)
+	if err != nil {
+		return nil, err
+	}
+	return MergeKlusterletConfigs(klusterletconfigs...)
+}
+
+// GetKlusterletConfigsOfManagedCluster returns the KlusterletConfigs which the managed cluster uses in the merging
+// order, see GetMergedKlusterletConfig.
+func GetKlusterletConfigsOfManagedCluster(
+	managedCluster *clusterv1.ManagedCluster,
+	kcLister listerklusterletconfigv1alpha1.KlusterletConfigLister,
+) ([]*klusterletconfigv1alpha1.KlusterletConfig, error) {
+	klusterletconfigs := []*klusterletconfigv1alpha1.KlusterletConfig{}
+
+	globalKlusterletConfig, err := kcLister.Get(constants.GlobalKlusterletConfigName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get global klusterletconfig: %v", err)
+	}
+	if err == nil {
+		klusterletconfigs = append(klusterletconfigs, globalKlusterletConfig)
+	}
+
+	klusterletconfigName := managedCluster.GetAnnotations()[apiconstants.AnnotationKlusterletConfig]
+
+	all, err := kcLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list klusterletconfigs: %v", err)
+	}
+	selected := []*klusterletconfigv1alpha1.KlusterletConfig{}
+	for _, kc := range all {
+		if kc.Name == constants.GlobalKlusterletConfigName || kc.Name == klusterletconfigName {
+			continue
+		}
+		if MatchKlusterletConfig(kc, managedCluster) {
+			selected = append(selected, kc)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		pi, pj := GetKlusterletConfigPriority(selected[i]), GetKlusterletConfigPriority(selected[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return selected[i].Name < selected[j].Name
+	})
+	klusterletconfigs = append(klusterletconfigs, selected...)
+
+	if klusterletconfigName != "" {
+		kc, err := kcLister.Get(klusterletconfigName)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get klusterletconfig %s: %v", klusterletconfigName, err)
+		}
+		if err == nil {
+			klusterletconfigs = append(klusterletconfigs, kc)
+		}
+	}
+
+	return klusterletconfigs, nil
+}
+
+// mergedKlusterletConfigSpecFields are the fields of the KlusterletConfig spec which are merged from all of the
+// KlusterletConfigs, the other fields are overridden by the last KlusterletConfig which sets them.
+var mergedKlusterletConfigSpecFields = sets.New[string]("hubKubeAPIServerConfig", "featureGates",
+	"clusterClaimConfiguration")
+
+// GetKlusterletConfigSources returns the names of the KlusterletConfigs which each field of the merged
+// KlusterletConfig is from, the KlusterletConfigs should be in the merging order. The keys are the json fields of
+// the spec and the annotation keys.
+func GetKlusterletConfigSources(klusterletconfigs ...*klusterletconfigv1alpha1.KlusterletConfig) map[string][]string {
+	sources := map[string][]string{}
+	for _, kc := range klusterletconfigs {
+		if kc == nil {
+			continue
+		}
+
+		v := reflect.ValueOf(kc.Spec)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).IsZero() {
+				continue
+			}
+			field := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+			if mergedKlusterletConfigSpecFields.Has(field) {
+				sources[field] = append(sources[field], kc.Name)
+			} else {
+				sources[field] = []string{kc.Name}
+			}
+		}
+
+		for key := range kc.Annotations {
+			sources[key] = []string{kc.Name}
+		}
+	}
+	return sources
+}
+
+// MatchKlusterletConfig checks if the KlusterletConfig selects the managed cluster by its managed cluster selector,
+// a KlusterletConfig without a valid selector selects nothing.
+func MatchKlusterletConfig(kc *klusterletconfigv1alpha1.KlusterletConfig,
+	managedCluster *clusterv1.ManagedCluster) bool {
+	selector, err := GetKlusterletConfigSelector(kc)
+	if err != nil {
+		klog.Warningf("the managed cluster selector of klusterletconfig %s is invalid: %v", kc.Name, err)
+		return false
+	}
+	return selector.Matches(labels.Set(managedCluster.GetLabels()))
+}
+
+// GetKlusterletConfigSelector returns the managed cluster selector of the KlusterletConfig, it is built from the
+// managed-cluster-selector and managed-cluster-set annotations. It returns a selector which selects nothing if
+// neither of them is set.
+func GetKlusterletConfigSelector(kc *klusterletconfigv1alpha1.KlusterletConfig) (labels.Selector, error) {
+	selectorValue, hasSelector := kc.GetAnnotations()[constants.AnnotationKlusterletConfigManagedClusterSelector]
+	clusterSet, hasClusterSet := kc.GetAnnotations()[constants.AnnotationKlusterletConfigManagedClusterSet]
+	if !hasSelector && !hasClusterSet {
+		return labels.Nothing(), nil
+	}
+
+	selector, err := labels.Parse(selectorValue)
+	if err != nil {
+		return nil, err
+	}
+	if hasClusterSet {
+		requirement, err := labels.NewRequirement(clusterv1beta2.ClusterSetLabel, selection.Equals, []string{clusterSet})
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*requirement)
+	}
+	return selector, nil
+}
+
+// HasKlusterletConfigSelector checks if the KlusterletConfig has the managed cluster selector
+func HasKlusterletConfigSelector(kc *klusterletconfigv1alpha1.KlusterletConfig) bool {
+	_, hasSelector := kc.GetAnnotations()[constants.AnnotationKlusterletConfigManagedClusterSelector]
+	_, hasClusterSet := kc.GetAnnotations()[constants.AnnotationKlusterletConfigManagedClusterSet]
+	return hasSelector || hasClusterSet
+}
+
+// GetKlusterletConfigPriority returns the priority of the KlusterletConfig, the invalid priority is ignored and
+// the default priority 0 is used.
+func GetKlusterletConfigPriority(kc *klusterletconfigv1alpha1.KlusterletConfig) int {
+	value, ok := kc.GetAnnotations()[constants.AnnotationKlusterletConfigPriority]
+	if !ok {
+		return 0
+	}
+	priority, err := ValidateKlusterletConfigPriority(value)
+	if err != nil {
+		klog.Warningf("the priority of klusterletconfig %s is invalid: %v", kc.Name, err)
+		return 0
+	}
+	return priority
+}
+
+// ValidateKlusterletConfigPriority validates the priority of the KlusterletConfig is an integer and returns it
+func ValidateKlusterletConfigPriority(value string) (int, error) {
+	priority, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return int(priority), nil
+}
+
+// mergeKlusterletConfigs merges the KlusterletConfigs in order, the latter overrides the former.
+func MergeKlusterletConfigs(
+	klusterletconfigs ...*klusterletconfigv1alpha1.KlusterletConfig) (*klusterletconfigv1alpha1.KlusterletConfig, error) {
 	// The object get from a lister should be be modified directly.
-	return klusterletconfighelper.MergeKlusterletConfigs(globalKlusterletConfig.DeepCopy(), kc.DeepCopy())
+	copied := []*klusterletconfigv1alpha1.KlusterletConfig{}
+	for _, kc := range klusterletconfigs {
+		copied = append(copied, kc.DeepCopy())
+	}
+	merged, err := klusterletconfighelper.MergeKlusterletConfigs(copied...)
+	if err != nil || merged == nil {
+		return merged, err
+	}
+
+	// Only the spec is merged by the MergeKlusterletConfigs, the annotations are merged here as well so that the
+	// annotation based configurations can be overridden by the user assigned KlusterletConfig.
+	merged.Annotations = mergeAnnotations(klusterletconfigs...)
+	return merged, nil
+}
+
+func mergeAnnotations(klusterletconfigs ...*klusterletconfigv1alpha1.KlusterletConfig) map[string]string {
+	var annotations map[string]string
+	for _, kc := range klusterletconfigs {
+		if kc == nil {
+			continue
+		}
+		for key, value := range kc.Annotations {
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[key] = value
+		}
+	}
+	return annotations
 }
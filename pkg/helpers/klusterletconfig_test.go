@@ -1,13 +1,19 @@
 package helpers
 
 import (
+	"reflect"
 	"testing"
 
+	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
 	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	labels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	clusterv1beta2 "open-cluster-management.io/api/cluster/v1beta2"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
 )
 
 func TestGetMergedKlusterletConfigWithGlobal(t *testing.T) {
@@ -16,6 +22,7 @@ func TestGetMergedKlusterletConfigWithGlobal(t *testing.T) {
 		klusterletconfigName string
 		getFunc              func(string) (*klusterletconfigv1alpha1.KlusterletConfig, error)
 		wantErr              bool
+		wantAnnotations      map[string]string
 	}{
 		{
 			name:                 "return error when klusterletconfigLister.Get returns an error",
@@ -48,6 +55,24 @@ func TestGetMergedKlusterletConfigWithGlobal(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:                 "return merged annotations when both klusterletconfigs have annotations",
+			klusterletconfigName: "test",
+			getFunc: func(name string) (*klusterletconfigv1alpha1.KlusterletConfig, error) {
+				annotations := map[string]string{"a": name, "b": name}
+				if name == constants.GlobalKlusterletConfigName {
+					annotations = map[string]string{"b": name, "c": name}
+				}
+				return &klusterletconfigv1alpha1.KlusterletConfig{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        name,
+						Annotations: annotations,
+					},
+				}, nil
+			},
+			wantErr:         false,
+			wantAnnotations: map[string]string{"a": "test", "b": "test", "c": "global"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -56,7 +81,7 @@ func TestGetMergedKlusterletConfigWithGlobal(t *testing.T) {
 				GetFunc: tt.getFunc,
 			}
 
-			_, err := GetMergedKlusterletConfigWithGlobal(tt.klusterletconfigName, lister)
+			kc, err := GetMergedKlusterletConfigWithGlobal(tt.klusterletconfigName, lister)
 			if tt.wantErr {
 				if err == nil {
 					t.Errorf("expected error, got nil")
@@ -66,6 +91,298 @@ func TestGetMergedKlusterletConfigWithGlobal(t *testing.T) {
 					t.Errorf("expected nil error, got %v", err)
 				}
 			}
+			if tt.wantAnnotations != nil && !reflect.DeepEqual(kc.Annotations, tt.wantAnnotations) {
+				t.Errorf("expected annotations %v, got %v", tt.wantAnnotations, kc.Annotations)
+			}
+		})
+	}
+}
+
+func TestGetKlusterletConfigsOfManagedCluster(t *testing.T) {
+	newKC := func(name string, annotations map[string]string) *klusterletconfigv1alpha1.KlusterletConfig {
+		return &klusterletconfigv1alpha1.KlusterletConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+		}
+	}
+	klusterletconfigs := []*klusterletconfigv1alpha1.KlusterletConfig{
+		newKC(constants.GlobalKlusterletConfigName, nil),
+		newKC("no-selector", nil),
+		newKC("dev", map[string]string{
+			constants.AnnotationKlusterletConfigManagedClusterSelector: "env=dev",
+			constants.AnnotationKlusterletConfigPriority:               "10",
+		}),
+		newKC("dev-set", map[string]string{
+			constants.AnnotationKlusterletConfigManagedClusterSet: "dev",
+		}),
+		newKC("all", map[string]string{
+			constants.AnnotationKlusterletConfigManagedClusterSelector: "",
+		}),
+		newKC("invalid", map[string]string{
+			constants.AnnotationKlusterletConfigManagedClusterSelector: "env in dev",
+		}),
+		newKC("prod", map[string]string{
+			constants.AnnotationKlusterletConfigManagedClusterSelector: "env=prod",
+		}),
+	}
+	lister := &mockKlusterletConfigLister{
+		ListFunc: func(selector labels.Selector) ([]*klusterletconfigv1alpha1.KlusterletConfig, error) {
+			return klusterletconfigs, nil
+		},
+		GetFunc: func(name string) (*klusterletconfigv1alpha1.KlusterletConfig, error) {
+			for _, kc := range klusterletconfigs {
+				if kc.Name == name {
+					return kc, nil
+				}
+			}
+			return nil, errors.NewNotFound(klusterletconfigv1alpha1.Resource("klusterletconfigs"), name)
+		},
+	}
+
+	tests := []struct {
+		name          string
+		labels        map[string]string
+		annotations   map[string]string
+		expectedNames []string
+	}{
+		{
+			name:          "no labels",
+			expectedNames: []string{"global", "all"},
+		},
+		{
+			name: "selected by priority",
+			labels: map[string]string{
+				"env":                          "dev",
+				clusterv1beta2.ClusterSetLabel: "dev",
+			},
+			expectedNames: []string{"global", "all", "dev-set", "dev"},
+		},
+		{
+			name:          "annotation overrides the selected klusterletconfigs",
+			labels:        map[string]string{"env": "dev"},
+			annotations:   map[string]string{apiconstants.AnnotationKlusterletConfig: "all"},
+			expectedNames: []string{"global", "dev", "all"},
+		},
+		{
+			name:          "annotation klusterletconfig does not exist",
+			labels:        map[string]string{"env": "prod"},
+			annotations:   map[string]string{apiconstants.AnnotationKlusterletConfig: "missing"},
+			expectedNames: []string{"global", "all", "prod"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "cluster1",
+					Labels:      tt.labels,
+					Annotations: tt.annotations,
+				},
+			}
+			kcs, err := GetKlusterletConfigsOfManagedCluster(cluster, lister)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			names := []string{}
+			for _, kc := range kcs {
+				names = append(names, kc.Name)
+			}
+			if !reflect.DeepEqual(names, tt.expectedNames) {
+				t.Errorf("expected klusterletconfigs %v, got %v", tt.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestGetMergedKlusterletConfig(t *testing.T) {
+	klusterletconfigs := []*klusterletconfigv1alpha1.KlusterletConfig{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        constants.GlobalKlusterletConfigName,
+				Annotations: map[string]string{"a": "global", "b": "global", "c": "global"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "selected",
+				Annotations: map[string]string{
+					constants.AnnotationKlusterletConfigManagedClusterSelector: "env=dev",
+					"b": "selected",
+					"c": "selected",
+				},
+			},
+			Spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+				AppliedManifestWorkEvictionGracePeriod: "10m",
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "assigned",
+				Annotations: map[string]string{"c": "assigned"},
+			},
+		},
+	}
+	lister := &mockKlusterletConfigLister{
+		ListFunc: func(selector labels.Selector) ([]*klusterletconfigv1alpha1.KlusterletConfig, error) {
+			return klusterletconfigs, nil
+		},
+		GetFunc: func(name string) (*klusterletconfigv1alpha1.KlusterletConfig, error) {
+			for _, kc := range klusterletconfigs {
+				if kc.Name == name {
+					return kc, nil
+				}
+			}
+			return nil, errors.NewNotFound(klusterletconfigv1alpha1.Resource("klusterletconfigs"), name)
+		},
+	}
+
+	cluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "cluster1",
+			Labels:      map[string]string{"env": "dev"},
+			Annotations: map[string]string{apiconstants.AnnotationKlusterletConfig: "assigned"},
+		},
+	}
+	kc, err := GetMergedKlusterletConfig(cluster, lister)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if kc.Spec.AppliedManifestWorkEvictionGracePeriod != "10m" {
+		t.Errorf("expected the spec of the selected klusterletconfig, got %v", kc.Spec)
+	}
+	for key, expected := range map[string]string{"a": "global", "b": "selected", "c": "assigned"} {
+		if kc.Annotations[key] != expected {
+			t.Errorf("expected annotation %s=%s, got %s", key, expected, kc.Annotations[key])
+		}
+	}
+}
+
+func TestGetKlusterletConfigSources(t *testing.T) {
+	global := &klusterletconfigv1alpha1.KlusterletConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        constants.GlobalKlusterletConfigName,
+			Annotations: map[string]string{"a": "global"},
+		},
+		Spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+			AppliedManifestWorkEvictionGracePeriod: "10m",
+			FeatureGates: []operatorv1.FeatureGate{
+				{Feature: "AddonManagement", Mode: operatorv1.FeatureGateModeTypeEnable},
+			},
+		},
+	}
+	kc := &klusterletconfigv1alpha1.KlusterletConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Annotations: map[string]string{"a": "test"},
+		},
+		Spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+			AppliedManifestWorkEvictionGracePeriod: "20m",
+			FeatureGates: []operatorv1.FeatureGate{
+				{Feature: "ClusterClaim", Mode: operatorv1.FeatureGateModeTypeEnable},
+			},
+			Registries: []klusterletconfigv1alpha1.Registries{{Mirror: "quay.io/test"}},
+		},
+	}
+
+	expected := map[string][]string{
+		"appliedManifestWorkEvictionGracePeriod": {"test"},
+		"featureGates":                           {"global", "test"},
+		"registries":                             {"test"},
+		"a":                                      {"test"},
+	}
+	if sources := GetKlusterletConfigSources(global, nil, kc); !reflect.DeepEqual(sources, expected) {
+		t.Errorf("expected sources %v, got %v", expected, sources)
+	}
+}
+
+func TestGetKlusterletConfigSelector(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		labels      map[string]string
+		wantErr     bool
+		wantMatch   bool
+	}{
+		{
+			name:      "no selector",
+			labels:    map[string]string{"env": "dev"},
+			wantMatch: false,
+		},
+		{
+			name:        "empty selector selects everything",
+			annotations: map[string]string{constants.AnnotationKlusterletConfigManagedClusterSelector: ""},
+			wantMatch:   true,
+		},
+		{
+			name:        "invalid selector",
+			annotations: map[string]string{constants.AnnotationKlusterletConfigManagedClusterSelector: "env in dev"},
+			wantErr:     true,
+		},
+		{
+			name: "selector and cluster set",
+			annotations: map[string]string{
+				constants.AnnotationKlusterletConfigManagedClusterSelector: "env=dev",
+				constants.AnnotationKlusterletConfigManagedClusterSet:      "set1",
+			},
+			labels:    map[string]string{"env": "dev", clusterv1beta2.ClusterSetLabel: "set1"},
+			wantMatch: true,
+		},
+		{
+			name:        "cluster set does not match",
+			annotations: map[string]string{constants.AnnotationKlusterletConfigManagedClusterSet: "set1"},
+			labels:      map[string]string{clusterv1beta2.ClusterSetLabel: "set2"},
+			wantMatch:   false,
+		},
+		{
+			name:        "invalid cluster set",
+			annotations: map[string]string{constants.AnnotationKlusterletConfigManagedClusterSet: "set/1"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kc := &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: tt.annotations},
+			}
+			selector, err := GetKlusterletConfigSelector(kc)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if match := selector.Matches(labels.Set(tt.labels)); match != tt.wantMatch {
+				t.Errorf("expected match %v, got %v", tt.wantMatch, match)
+			}
+		})
+	}
+}
+
+func TestGetKlusterletConfigPriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		priority *string
+		expected int
+	}{
+		{name: "no priority", expected: 0},
+		{name: "positive priority", priority: ptr.To("100"), expected: 100},
+		{name: "negative priority", priority: ptr.To("-1"), expected: -1},
+		{name: "invalid priority", priority: ptr.To("high"), expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kc := &klusterletconfigv1alpha1.KlusterletConfig{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+			if tt.priority != nil {
+				kc.Annotations = map[string]string{constants.AnnotationKlusterletConfigPriority: *tt.priority}
+			}
+			if priority := GetKlusterletConfigPriority(kc); priority != tt.expected {
+				t.Errorf("expected priority %d, got %d", tt.expected, priority)
+			}
 		})
 	}
 }
@@ -0,0 +1,137 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const oidcTokenRequestTimeout = 30 * time.Second
+
+// oidcTokenResponse is the successful response of the token endpoint, see
+// https://datatracker.ietf.org/doc/html/rfc6749#section-5.1
+type oidcTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// oidcTokenErrorResponse is the error response of the token endpoint, see
+// https://datatracker.ietf.org/doc/html/rfc6749#section-5.2
+type oidcTokenErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// GenerateImportClientFromOIDCSecret generate a client from a given secret that contains the api server
+// address of the managed cluster and the OIDC client credentials. The client credentials are exchanged
+// for a short-lived bearer token with the token endpoint, the token is only kept in memory. The certificate of the
+// kube apiserver is verified in the same way as GenerateImportClientFromKubeTokenSecret.
+func GenerateImportClientFromOIDCSecret(secret *corev1.Secret, refuseInsecure bool) (
+	reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
+	server, ok := secret.Data[constants.AutoImportSecretKubeServerKey]
+	if !ok || len(server) == 0 {
+		return reconcile.Result{}, nil, nil, fmt.Errorf("server is missing")
+	}
+	tokenURL, ok := secret.Data[constants.AutoImportSecretOIDCConfigTokenURLKey]
+	if !ok || len(tokenURL) == 0 {
+		return reconcile.Result{}, nil, nil, fmt.Errorf("token_url is missing")
+	}
+	clientID, ok := secret.Data[constants.AutoImportSecretOIDCConfigClientIDKey]
+	if !ok || len(clientID) == 0 {
+		return reconcile.Result{}, nil, nil, fmt.Errorf("client_id is missing")
+	}
+	clientSecret, ok := secret.Data[constants.AutoImportSecretOIDCConfigClientSecretKey]
+	if !ok || len(clientSecret) == 0 {
+		return reconcile.Result{}, nil, nil, fmt.Errorf("client_secret is missing")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oidcTokenRequestTimeout)
+	defer cancel()
+
+	token, err := requestOIDCClientCredentialsToken(ctx, http.DefaultClient, string(tokenURL),
+		string(clientID), string(clientSecret),
+		string(secret.Data[constants.AutoImportSecretOIDCConfigScopeKey]),
+		string(secret.Data[constants.AutoImportSecretOIDCConfigAudienceKey]))
+	if err != nil {
+		return reconcile.Result{}, nil, nil, err
+	}
+
+	config := buildKubeConfigFileWithToken(string(server), token)
+	if err := setServerTLSVerification(config, secret, refuseInsecure); err != nil {
+		return reconcile.Result{}, nil, nil, err
+	}
+	return buildImportClient(config)
+}
+
+// requestOIDCClientCredentialsToken requests a token from the token endpoint with the client credentials
+// grant, see https://datatracker.ietf.org/doc/html/rfc6749#section-4.4
+func requestOIDCClientCredentialsToken(ctx context.Context, client *http.Client,
+	tokenURL, clientID, clientSecret, scope, audience string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	if len(scope) > 0 {
+		form.Set("scope", scope)
+	}
+	if len(audience) > 0 {
+		form.Set("audience", audience)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to build the token request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request token from %s: %v", tokenURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the token response from %s: %v", tokenURL, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		errResp := &oidcTokenErrorResponse{}
+		if err := json.Unmarshal(body, errResp); err == nil && len(errResp.Error) > 0 {
+			return "", fmt.Errorf("failed to request token from %s, status=%d, error=%s, description=%s",
+				tokenURL, resp.StatusCode, errResp.Error, errResp.ErrorDescription)
+		}
+		return "", fmt.Errorf("failed to request token from %s, status=%d", tokenURL, resp.StatusCode)
+	}
+
+	tokenResp := &oidcTokenResponse{}
+	if err := json.Unmarshal(body, tokenResp); err != nil {
+		return "", fmt.Errorf("failed to parse the token response from %s: %v", tokenURL, err)
+	}
+
+	// the kube api server with OIDC authentication may only accept the id token, prefer the access
+	// token and fall back to the id token if the access token is not issued.
+	if len(tokenResp.AccessToken) > 0 {
+		return tokenResp.AccessToken, nil
+	}
+	if len(tokenResp.IDToken) > 0 {
+		return tokenResp.IDToken, nil
+	}
+
+	return "", fmt.Errorf("no token is found in the token response from %s", tokenURL)
+}
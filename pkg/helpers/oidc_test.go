@@ -0,0 +1,182 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestOIDCTokenServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := req.ParseForm(); err != nil {
+			t.Fatalf("unexpected form error: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		clientID, clientSecret, ok := req.BasicAuth()
+		if !ok || clientID != "client" || clientSecret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"bad credentials"}`))
+			return
+		}
+
+		if req.Form.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"unsupported_grant_type"}`))
+			return
+		}
+
+		resp := oidcTokenResponse{TokenType: "Bearer", ExpiresIn: 300}
+		switch req.Form.Get("scope") {
+		case "id-only":
+			resp.IDToken = "id-token"
+		case "none":
+		default:
+			resp.AccessToken = "access-token"
+		}
+
+		output, err := json.Marshal(resp)
+		if err != nil {
+			t.Fatalf("unexpected encoding error: %v", err)
+		}
+		_, _ = w.Write(output)
+	}))
+}
+
+func TestRequestOIDCClientCredentialsToken(t *testing.T) {
+	server := newTestOIDCTokenServer(t)
+	defer server.Close()
+
+	cases := []struct {
+		name          string
+		clientSecret  string
+		scope         string
+		expectedToken string
+		expectErr     bool
+	}{
+		{
+			name:          "access token",
+			clientSecret:  "secret",
+			expectedToken: "access-token",
+		},
+		{
+			name:          "id token",
+			clientSecret:  "secret",
+			scope:         "id-only",
+			expectedToken: "id-token",
+		},
+		{
+			name:         "no token",
+			clientSecret: "secret",
+			scope:        "none",
+			expectErr:    true,
+		},
+		{
+			name:         "invalid client",
+			clientSecret: "wrong",
+			expectErr:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			token, err := requestOIDCClientCredentialsToken(context.TODO(), server.Client(), server.URL,
+				"client", c.clientSecret, c.scope, "")
+			if !c.expectErr && err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if c.expectErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if token != c.expectedToken {
+				t.Errorf("expected token %q, but got %q", c.expectedToken, token)
+			}
+		})
+	}
+}
+
+func TestGenerateImportClientFromOIDCSecret(t *testing.T) {
+	server := newTestOIDCTokenServer(t)
+	defer server.Close()
+
+	cases := []struct {
+		name      string
+		secret    *corev1.Secret
+		expectErr bool
+	}{
+		{
+			name: "missing server",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "auto-import-secret"},
+				Data: map[string][]byte{
+					"token_url":     []byte(server.URL),
+					"client_id":     []byte("client"),
+					"client_secret": []byte("secret"),
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "missing client secret",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "auto-import-secret"},
+				Data: map[string][]byte{
+					"server":    []byte("https://api.test.com:6443"),
+					"token_url": []byte(server.URL),
+					"client_id": []byte("client"),
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid client",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "auto-import-secret"},
+				Data: map[string][]byte{
+					"server":        []byte("https://api.test.com:6443"),
+					"token_url":     []byte(server.URL),
+					"client_id":     []byte("client"),
+					"client_secret": []byte("wrong"),
+				},
+			},
+			expectErr: true,
+		},
+		{
+			name: "oidc secret",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "auto-import-secret"},
+				Data: map[string][]byte{
+					"server":        []byte("https://api.test.com:6443"),
+					"token_url":     []byte(server.URL),
+					"client_id":     []byte("client"),
+					"client_secret": []byte("secret"),
+					"scope":         []byte("openid"),
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, clientHolder, _, err := GenerateImportClientFromOIDCSecret(c.secret, false)
+			if !c.expectErr && err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if c.expectErr && err == nil {
+				t.Errorf("expected error, but failed")
+			}
+			if !c.expectErr && clientHolder == nil {
+				t.Errorf("expected client holder, but got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	versionutil "k8s.io/apimachinery/pkg/util/version"
+	kevents "k8s.io/client-go/tools/events"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// the klusterlet requires the apiextensions.k8s.io/v1 which is supported since v1.16
+const preflightMinimumKubeVersion = "v1.16.0"
+
+// the verbs are required to apply the importing resources, see ApplyResources
+var preflightRequiredVerbs = []string{"get", "create", "update"}
+
+// IsImportPreflight returns true if the managed cluster requires to run the import preflight checks
+func IsImportPreflight(annotations map[string]string) bool {
+	return strings.EqualFold(annotations[constants.AnnotationImportPreflight], "true")
+}
+
+// NewManagedClusterImportPreflightCondition returns the import preflight condition with the preflight failures,
+// the condition status is true if there is no failure
+func NewManagedClusterImportPreflightCondition(failures []string) metav1.Condition {
+	if len(failures) == 0 {
+		return metav1.Condition{
+			Type:    constants.ConditionManagedClusterImportPreflight,
+			Status:  metav1.ConditionTrue,
+			Reason:  constants.ConditionReasonManagedClusterImportPreflightSucceeded,
+			Message: "The import preflight checks are passed",
+		}
+	}
+
+	return metav1.Condition{
+		Type:    constants.ConditionManagedClusterImportPreflight,
+		Status:  metav1.ConditionFalse,
+		Reason:  constants.ConditionReasonManagedClusterImportPreflightFailed,
+		Message: fmt.Sprintf("The import preflight checks are failed: %s", strings.Join(failures, "; ")),
+	}
+}
+
+// UpdateManagedClusterImportPreflightCondition update managed cluster import preflight condition and record the event
+func UpdateManagedClusterImportPreflightCondition(client client.Client, managedCluster *clusterv1.ManagedCluster,
+	cond metav1.Condition, recorder kevents.EventRecorder) error {
+	if cond.Type != constants.ConditionManagedClusterImportPreflight {
+		return fmt.Errorf("the condition type %s is not supported", cond.Type)
+	}
+
+	changed, err := updateManagedClusterStatus(client, managedCluster.Name, cond)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+
+	mc := managedCluster.DeepCopy()
+	mc.SetNamespace(mc.Name)
+	switch cond.Reason {
+	case constants.ConditionReasonManagedClusterImportPreflightSucceeded:
+		recorder.Eventf(mc, nil, corev1.EventTypeNormal,
+			constants.EventReasonManagedClusterImportPreflightSucceeded,
+			constants.EventReasonManagedClusterImportPreflightSucceeded,
+			"The import preflight checks of %s are passed", mc.Name)
+	case constants.ConditionReasonManagedClusterImportPreflightFailed:
+		recorder.Eventf(mc, nil, corev1.EventTypeWarning,
+			constants.EventReasonManagedClusterImportPreflightFailed,
+			constants.EventReasonManagedClusterImportPreflightFailed,
+			"The import preflight checks of %s are failed. %s", mc.Name, cond.Message)
+	default:
+		return fmt.Errorf("the condition reason %s is not supported", cond.Reason)
+	}
+
+	return nil
+}
+
+// Preflight uses the managedClusterKubeClientSecret to generate a managed cluster client, then use this client to
+// check whether the importing resources can be applied on the managed cluster, nothing is applied on the managed
+// cluster. It returns the import preflight condition.
+func (i *ImportHelper) Preflight(cluster *clusterv1.ManagedCluster,
+	managedClusterKubeClientSecret *corev1.Secret) (reconcile.Result, *metav1.Condition, error) {
+	if i.generateClientHolderFunc == nil {
+		return reconcile.Result{}, nil, fmt.Errorf("the generateClientHolderFunc in the ImportHelper is nil")
+	}
+
+	reqLogger := i.log.WithValues("Request.Name", cluster.Name)
+
+	importSecretName := fmt.Sprintf("%s-%s", cluster.Name, constants.ImportSecretNameSuffix)
+	importSecret, err := i.informerHolder.ImportSecretLister.Secrets(cluster.Name).Get(importSecretName)
+	if errors.IsNotFound(err) {
+		// the preflight will be triggered again once the import secret is created
+		reqLogger.Info("Wait for import secret to run the import preflight checks")
+		return reconcile.Result{}, nil, nil
+	}
+	if err != nil {
+		return reconcile.Result{}, nil, err
+	}
+
+	result, clientHolder, _, err := i.generateClientHolderFunc(managedClusterKubeClientSecret)
+	if err != nil {
+		condition := NewManagedClusterImportPreflightCondition([]string{
+			failureMessageOfInvalidAutoImportSecret(managedClusterKubeClientSecret, err)})
+		return result, &condition, nil
+	}
+
+	failures, err := RunImportPreflightChecks(context.TODO(), clientHolder, importSecret)
+	if err != nil {
+		return reconcile.Result{}, nil, err
+	}
+
+	condition := NewManagedClusterImportPreflightCondition(failures)
+	return reconcile.Result{}, &condition, nil
+}
+
+// RunImportPreflightChecks checks whether the importing resources of the import secret can be applied on the
+// managed cluster with the managed cluster client, it returns the failures of the checks.
+func RunImportPreflightChecks(ctx context.Context, clientHolder *ClientHolder,
+	importSecret *corev1.Secret) ([]string, error) {
+	if err := ValidateImportSecret(importSecret); err != nil {
+		return nil, err
+	}
+
+	objs := importSecretObjects(importSecret)
+
+	failures := []string{}
+	checks := []func(context.Context, *ClientHolder, []runtime.Object) ([]string, error){
+		preflightCheckKubeVersion,
+		preflightCheckPermissions,
+		preflightCheckExistingKlusterlet,
+		preflightCheckPriorityClass,
+	}
+	for _, check := range checks {
+		checkFailures, err := check(ctx, clientHolder, objs)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		failures = append(failures, checkFailures...)
+	}
+
+	return failures, nil
+}
+
+func preflightCheckKubeVersion(_ context.Context, clientHolder *ClientHolder, _ []runtime.Object) ([]string, error) {
+	serverVersion, err := clientHolder.KubeClient.Discovery().ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the kube version: %v", err)
+	}
+
+	kubeVersion, err := versionutil.ParseGeneric(serverVersion.GitVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the kube version %s: %v", serverVersion.GitVersion, err)
+	}
+
+	if !kubeVersion.AtLeast(versionutil.MustParseGeneric(preflightMinimumKubeVersion)) {
+		return []string{fmt.Sprintf("the kube version %s is not supported, the minimum supported version is %s",
+			serverVersion.GitVersion, preflightMinimumKubeVersion)}, nil
+	}
+
+	return nil, nil
+}
+
+func preflightCheckPermissions(ctx context.Context, clientHolder *ClientHolder,
+	objs []runtime.Object) ([]string, error) {
+	// the self subject rules review is namespaced, the rules of the cluster scoped resources are
+	// included in the review of any namespace
+	reviews := map[string]*authorizationv1.SelfSubjectRulesReview{}
+	getReview := func(namespace string) (*authorizationv1.SelfSubjectRulesReview, error) {
+		if review, ok := reviews[namespace]; ok {
+			return review, nil
+		}
+
+		review, err := clientHolder.KubeClient.AuthorizationV1().SelfSubjectRulesReviews().Create(ctx,
+			&authorizationv1.SelfSubjectRulesReview{
+				Spec: authorizationv1.SelfSubjectRulesReviewSpec{Namespace: namespace},
+			}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to review the permissions in namespace %s: %v", namespace, err)
+		}
+
+		reviews[namespace] = review
+		return review, nil
+	}
+
+	failures := []string{}
+	for _, obj := range objs {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+		gvk, err := apiutil.GVKForObject(obj, genericScheme)
+		if err != nil {
+			return nil, err
+		}
+		resource, _ := meta.UnsafeGuessKindToResource(gvk)
+
+		namespace := accessor.GetNamespace()
+		if len(namespace) == 0 {
+			namespace = metav1.NamespaceDefault
+		}
+
+		review, err := getReview(namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, verb := range preflightRequiredVerbs {
+			if rulesAllow(review.Status.ResourceRules, verb, resource.Group, resource.Resource, accessor.GetName()) {
+				continue
+			}
+
+			failure := fmt.Sprintf("no permission to %s %s %s", verb, resource.GroupResource().String(),
+				objectKey(accessor))
+			if review.Status.Incomplete {
+				failure = fmt.Sprintf("%s (the permission review is incomplete: %s)", failure,
+					review.Status.EvaluationError)
+			}
+			failures = append(failures, failure)
+		}
+	}
+
+	return failures, nil
+}
+
+func preflightCheckExistingKlusterlet(ctx context.Context, clientHolder *ClientHolder,
+	objs []runtime.Object) ([]string, error) {
+	klusterletName, anotherHubServer, err := GetAnotherRegisteredHubServer(ctx, clientHolder, objs)
+	if err != nil {
+		return nil, err
+	}
+	if len(anotherHubServer) == 0 {
+		return nil, nil
+	}
+
+	return []string{fmt.Sprintf("the klusterlet %s is registered to another hub %s", klusterletName,
+		anotherHubServer)}, nil
+}
+
+func preflightCheckPriorityClass(_ context.Context, clientHolder *ClientHolder,
+	objs []runtime.Object) ([]string, error) {
+	required := false
+	for _, obj := range objs {
+		if _, ok := obj.(*schedulingv1.PriorityClass); ok {
+			required = true
+			break
+		}
+	}
+	if !required {
+		return nil, nil
+	}
+
+	resources, err := clientHolder.KubeClient.Discovery().ServerResourcesForGroupVersion(
+		schedulingv1.SchemeGroupVersion.String())
+	if err != nil && !ResourceIsNotFound(err) {
+		return nil, fmt.Errorf("failed to discover %s: %v", schedulingv1.SchemeGroupVersion.String(), err)
+	}
+	if resources != nil {
+		for _, resource := range resources.APIResources {
+			if resource.Name == "priorityclasses" {
+				return nil, nil
+			}
+		}
+	}
+
+	return []string{fmt.Sprintf("the priorityclasses.%s is not supported", schedulingv1.SchemeGroupVersion.String())},
+		nil
+}
+
+func rulesAllow(rules []authorizationv1.ResourceRule, verb, group, resource, name string) bool {
+	for _, rule := range rules {
+		if !containsOrWildcard(rule.Verbs, verb) ||
+			!containsOrWildcard(rule.APIGroups, group) ||
+			!containsOrWildcard(rule.Resources, resource) {
+			continue
+		}
+
+		// the create request cannot be restricted by the resource name
+		if len(rule.ResourceNames) == 0 || (verb != "create" && containsOrWildcard(rule.ResourceNames, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsOrWildcard(values []string, value string) bool {
+	for _, v := range values {
+		if v == "*" || v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func objectKey(obj metav1.Object) string {
+	if len(obj.GetNamespace()) == 0 {
+		return obj.GetName()
+	}
+	return fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName())
+}
@@ -0,0 +1,192 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	operatorfake "open-cluster-management.io/api/client/operator/clientset/versioned/fake"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+)
+
+var preflightImportYaml = `
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: "open-cluster-management-agent"
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: "bootstrap-hub-kubeconfig"
+  namespace: "open-cluster-management-agent"
+type: Opaque
+data:
+  kubeconfig: "%s"
+---
+apiVersion: scheduling.k8s.io/v1
+kind: PriorityClass
+metadata:
+  name: klusterlet-critical
+value: 1000000
+---
+apiVersion: operator.open-cluster-management.io/v1
+kind: Klusterlet
+metadata:
+  name: klusterlet
+spec:
+  clusterName: "test"
+  namespace: "open-cluster-management-agent"
+`
+
+func newPreflightTestKubeconfig(t *testing.T, server string) []byte {
+	data, err := clientcmd.Write(clientcmdapi.Config{
+		Clusters:       map[string]*clientcmdapi.Cluster{"hub": {Server: server}},
+		Contexts:       map[string]*clientcmdapi.Context{"default": {Cluster: "hub"}},
+		CurrentContext: "default",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestRunImportPreflightChecks(t *testing.T) {
+	importSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-import", Namespace: "test"},
+		Data: map[string][]byte{
+			"import.yaml": []byte(fmt.Sprintf(preflightImportYaml,
+				base64.StdEncoding.EncodeToString(newPreflightTestKubeconfig(t, "https://hub:6443")))),
+		},
+	}
+
+	allowAll := []authorizationv1.ResourceRule{
+		{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}},
+	}
+
+	schedulingResources := &metav1.APIResourceList{
+		GroupVersion: "scheduling.k8s.io/v1",
+		APIResources: []metav1.APIResource{{Name: "priorityclasses", Kind: "PriorityClass"}},
+	}
+
+	klusterlet := &operatorv1.Klusterlet{
+		ObjectMeta: metav1.ObjectMeta{Name: "klusterlet"},
+		Spec:       operatorv1.KlusterletSpec{Namespace: "open-cluster-management-agent"},
+	}
+
+	hubKubeConfigSecret := func(server string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "hub-kubeconfig-secret", Namespace: "open-cluster-management-agent"},
+			Data:       map[string][]byte{"kubeconfig": newPreflightTestKubeconfig(t, server)},
+		}
+	}
+
+	cases := []struct {
+		name             string
+		kubeVersion      string
+		rules            []authorizationv1.ResourceRule
+		resources        []*metav1.APIResourceList
+		kubeObjs         []runtime.Object
+		operatorObjs     []runtime.Object
+		expectedFailures []string
+	}{
+		{
+			name:        "passed",
+			kubeVersion: "v1.28.3",
+			rules:       allowAll,
+			resources:   []*metav1.APIResourceList{schedulingResources},
+		},
+		{
+			name:             "unsupported kube version",
+			kubeVersion:      "v1.15.0",
+			rules:            allowAll,
+			resources:        []*metav1.APIResourceList{schedulingResources},
+			expectedFailures: []string{"the kube version v1.15.0 is not supported"},
+		},
+		{
+			name:        "no permission",
+			kubeVersion: "v1.28.3",
+			rules: []authorizationv1.ResourceRule{
+				{Verbs: []string{"*"}, APIGroups: []string{""}, Resources: []string{"namespaces", "secrets"}},
+				{Verbs: []string{"*"}, APIGroups: []string{"scheduling.k8s.io"}, Resources: []string{"*"}},
+				{Verbs: []string{"get", "update"}, APIGroups: []string{"operator.open-cluster-management.io"},
+					Resources: []string{"klusterlets"}, ResourceNames: []string{"klusterlet"}},
+			},
+			resources: []*metav1.APIResourceList{schedulingResources},
+			expectedFailures: []string{
+				"no permission to create klusterlets.operator.open-cluster-management.io klusterlet",
+			},
+		},
+		{
+			name:             "priorityclass is not supported",
+			kubeVersion:      "v1.28.3",
+			rules:            allowAll,
+			expectedFailures: []string{"the priorityclasses.scheduling.k8s.io/v1 is not supported"},
+		},
+		{
+			name:         "registered to the same hub",
+			kubeVersion:  "v1.28.3",
+			rules:        allowAll,
+			resources:    []*metav1.APIResourceList{schedulingResources},
+			kubeObjs:     []runtime.Object{hubKubeConfigSecret("https://hub:6443")},
+			operatorObjs: []runtime.Object{klusterlet},
+		},
+		{
+			name:             "registered to another hub",
+			kubeVersion:      "v1.28.3",
+			rules:            allowAll,
+			resources:        []*metav1.APIResourceList{schedulingResources},
+			kubeObjs:         []runtime.Object{hubKubeConfigSecret("https://another-hub:6443")},
+			operatorObjs:     []runtime.Object{klusterlet},
+			expectedFailures: []string{"the klusterlet klusterlet is registered to another hub https://another-hub:6443"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(c.kubeObjs...)
+			kubeClient.PrependReactor("create", "selfsubjectrulesreviews",
+				func(action clienttesting.Action) (bool, runtime.Object, error) {
+					return true, &authorizationv1.SelfSubjectRulesReview{
+						Status: authorizationv1.SubjectRulesReviewStatus{ResourceRules: c.rules},
+					}, nil
+				})
+			fakeDiscovery := kubeClient.Discovery().(*fakediscovery.FakeDiscovery)
+			fakeDiscovery.FakedServerVersion = &version.Info{GitVersion: c.kubeVersion}
+			fakeDiscovery.Resources = c.resources
+
+			failures, err := RunImportPreflightChecks(context.TODO(), &ClientHolder{
+				KubeClient:     kubeClient,
+				OperatorClient: operatorfake.NewSimpleClientset(c.operatorObjs...),
+			}, importSecret)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if len(failures) != len(c.expectedFailures) {
+				t.Fatalf("expected failures %v, but got %v", c.expectedFailures, failures)
+			}
+			for i := range failures {
+				if !strings.HasPrefix(failures[i], c.expectedFailures[i]) {
+					t.Errorf("expected failure %q, but got %q", c.expectedFailures[i], failures[i])
+				}
+			}
+		})
+	}
+}
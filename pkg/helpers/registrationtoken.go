@@ -0,0 +1,255 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	authv1 "k8s.io/api/authentication/v1"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+const (
+	// credentialIDExtraKey is the user extra key of the ID of the service account token in the CSR
+	credentialIDExtraKey = "authentication.kubernetes.io/credential-id"
+
+	// registrationTokenGracePeriod is the period to keep a registration token secret without the expiration, the
+	// expiration is recorded right after the token is requested, a secret without it is left by a failed request.
+	registrationTokenGracePeriod = 10 * time.Minute
+)
+
+// IssueRegistrationToken requests a token of the service account for the agent registration of a cluster. The
+// token is bound to a registration token secret in the service account namespace, the secret records the cluster
+// name and the token ID, so the CSR of the cluster can only be approved once with the token, and the token is
+// revoked once the secret is deleted. The token and the name of the secret are returned.
+func IssueRegistrationToken(ctx context.Context, kubeClient kubernetes.Interface, saName, namespace,
+	clusterName string, expirationSeconds int64) ([]byte, string, error) {
+	secret, err := kubeClient.CoreV1().Secrets(namespace).Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "registration-token-",
+			Namespace:    namespace,
+			Annotations: map[string]string{
+				constants.AnnotationRegistrationTokenCluster: clusterName,
+			},
+		},
+		Type: constants.RegistrationTokenSecretType,
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create the registration token secret: %v", err)
+	}
+
+	tokenRequest, err := kubeClient.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, saName, &authv1.TokenRequest{
+		Spec: authv1.TokenRequestSpec{
+			ExpirationSeconds: ptr.To(expirationSeconds),
+			BoundObjectRef: &authv1.BoundObjectReference{
+				Kind:       "Secret",
+				APIVersion: "v1",
+				Name:       secret.Name,
+				UID:        secret.UID,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		if err := RevokeRegistrationToken(ctx, kubeClient, namespace, secret.Name); err != nil {
+			klog.Warningf("failed to revoke the registration token %s/%s: %v", namespace, secret.Name, err)
+		}
+		return nil, "", fmt.Errorf("create token request failed: %v", err)
+	}
+
+	secret = secret.DeepCopy()
+	secret.Annotations[constants.AnnotationRegistrationTokenExpiration] =
+		tokenRequest.Status.ExpirationTimestamp.UTC().Format(time.RFC3339)
+	tokenID := tokenIDFromJWT(tokenRequest.Status.Token)
+	if len(tokenID) > 0 && len(validation.IsValidLabelValue(tokenID)) == 0 {
+		secret.Labels = map[string]string{constants.LabelRegistrationTokenID: tokenID}
+	} else {
+		// the kube apiserver does not add the ID to the token, the CSR cannot be matched with the token
+		klog.Warningf("the registration token %s/%s has no ID, its CSR will not be approved with it",
+			namespace, secret.Name)
+	}
+	if _, err := kubeClient.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		if err := RevokeRegistrationToken(ctx, kubeClient, namespace, secret.Name); err != nil {
+			klog.Warningf("failed to revoke the registration token %s/%s: %v", namespace, secret.Name, err)
+		}
+		return nil, "", fmt.Errorf("failed to update the registration token secret: %v", err)
+	}
+
+	return []byte(tokenRequest.Status.Token), secret.Name, nil
+}
+
+// RevokeRegistrationToken revokes a registration token by deleting its registration token secret
+func RevokeRegistrationToken(ctx context.Context, kubeClient kubernetes.Interface, namespace, secretName string) error {
+	err := kubeClient.CoreV1().Secrets(namespace).Delete(ctx, secretName, metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// CleanupRegistrationTokens deletes the registration token secrets which are no longer needed, the token of a
+// secret is expired, or it is consumed by a CSR which is approved, denied or deleted.
+func CleanupRegistrationTokens(ctx context.Context, kubeClient kubernetes.Interface, namespace string,
+	now time.Time) error {
+	secrets, err := kubeClient.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("type=%s", constants.RegistrationTokenSecretType),
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, secret := range secrets.Items {
+		if secret.Type != constants.RegistrationTokenSecretType || !secret.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		obsolete, err := isRegistrationTokenObsolete(ctx, kubeClient, &secret, now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !obsolete {
+			continue
+		}
+
+		klog.V(4).Infof("delete the registration token secret %s/%s", secret.Namespace, secret.Name)
+		if err := RevokeRegistrationToken(ctx, kubeClient, secret.Namespace, secret.Name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func isRegistrationTokenObsolete(ctx context.Context, kubeClient kubernetes.Interface, secret *corev1.Secret,
+	now time.Time) (bool, error) {
+	value, ok := secret.Annotations[constants.AnnotationRegistrationTokenExpiration]
+	if !ok {
+		return now.After(secret.CreationTimestamp.Add(registrationTokenGracePeriod)), nil
+	}
+	expiration, err := time.Parse(time.RFC3339, value)
+	if err != nil || now.After(expiration) {
+		// the token cannot be consumed with an invalid expiration, see ConsumeRegistrationToken
+		return true, nil
+	}
+
+	usedBy := secret.Annotations[constants.AnnotationRegistrationTokenUsedBy]
+	if len(usedBy) == 0 {
+		return false, nil
+	}
+	// the token is kept until the CSR is handled, so the CSR can still be approved with it
+	csr, err := kubeClient.CertificatesV1().CertificateSigningRequests().Get(ctx, usedBy, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, condition := range csr.Status.Conditions {
+		if condition.Type == certificatesv1.CertificateApproved || condition.Type == certificatesv1.CertificateDenied {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ConsumeRegistrationToken consumes the registration token of the CSR, the token is marked as used by the CSR if it is
+// issued for the cluster of the CSR, unexpired and not used by another CSR. The reason why the token is invalid is
+// returned if the token cannot be consumed, the CSR should be denied then.
+func ConsumeRegistrationToken(ctx context.Context, kubeClient kubernetes.Interface, namespace string,
+	csr *certificatesv1.CertificateSigningRequest) (string, error) {
+	tokenID := tokenIDFromCSR(csr)
+	if len(tokenID) == 0 {
+		return "the CSR is not created with a registration token", nil
+	}
+
+	secrets, err := kubeClient.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", constants.LabelRegistrationTokenID, tokenID),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, secret := range secrets.Items {
+		if secret.Type != constants.RegistrationTokenSecretType || !secret.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		clusterName := GetClusterName(csr)
+		if secret.Annotations[constants.AnnotationRegistrationTokenCluster] != clusterName {
+			return fmt.Sprintf("the registration token %s/%s is not issued for the cluster %s",
+				secret.Namespace, secret.Name, clusterName), nil
+		}
+
+		switch usedBy := secret.Annotations[constants.AnnotationRegistrationTokenUsedBy]; usedBy {
+		case csr.Name:
+			// the token was consumed by this CSR, but the CSR was not approved
+			return "", nil
+		case "":
+		default:
+			return fmt.Sprintf("the registration token %s/%s is already used by the CSR %s",
+				secret.Namespace, secret.Name, usedBy), nil
+		}
+
+		expiration, err := time.Parse(time.RFC3339, secret.Annotations[constants.AnnotationRegistrationTokenExpiration])
+		if err != nil || time.Now().After(expiration) {
+			return fmt.Sprintf("the registration token %s/%s is expired", secret.Namespace, secret.Name), nil
+		}
+
+		// the update fails with a conflict if the token is consumed by another CSR at the same time
+		secret := secret.DeepCopy()
+		secret.Annotations[constants.AnnotationRegistrationTokenUsedBy] = csr.Name
+		if _, err := kubeClient.CoreV1().Secrets(namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+			return "", err
+		}
+		return "", nil
+	}
+
+	return fmt.Sprintf("the registration token %s is not found, it may be revoked", tokenID), nil
+}
+
+// tokenIDFromCSR returns the ID of the service account token which is used to create the CSR
+func tokenIDFromCSR(csr *certificatesv1.CertificateSigningRequest) string {
+	for _, credentialID := range csr.Spec.Extra[credentialIDExtraKey] {
+		if tokenID, ok := strings.CutPrefix(credentialID, "JTI="); ok {
+			return tokenID
+		}
+	}
+	return ""
+}
+
+// tokenIDFromJWT returns the ID (JTI) of a service account token
+func tokenIDFromJWT(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+
+	claims := struct {
+		ID string `json:"jti"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	return claims.ID
+}
@@ -0,0 +1,317 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	authv1 "k8s.io/api/authentication/v1"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func newTestJWT(payload string) string {
+	return "header." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+}
+
+func newRegistrationTokenSecret(name, tokenID, clusterName, usedBy string, expiration time.Time) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "open-cluster-management",
+			Labels: map[string]string{
+				constants.LabelRegistrationTokenID: tokenID,
+			},
+			Annotations: map[string]string{
+				constants.AnnotationRegistrationTokenCluster:    clusterName,
+				constants.AnnotationRegistrationTokenExpiration: expiration.UTC().Format(time.RFC3339),
+			},
+		},
+		Type: constants.RegistrationTokenSecretType,
+	}
+	if len(usedBy) > 0 {
+		secret.Annotations[constants.AnnotationRegistrationTokenUsedBy] = usedBy
+	}
+	return secret
+}
+
+func newRegistrationCSR(name, clusterName, tokenID string) *certificatesv1.CertificateSigningRequest {
+	csr := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				constants.CSRClusterNameLabel: clusterName,
+			},
+		},
+	}
+	if len(tokenID) > 0 {
+		csr.Spec.Extra = map[string]certificatesv1.ExtraValue{
+			credentialIDExtraKey: {"JTI=" + tokenID},
+		}
+	}
+	return csr
+}
+
+func TestTokenIDFromJWT(t *testing.T) {
+	cases := []struct {
+		name     string
+		token    string
+		expected string
+	}{
+		{
+			name:     "token with id",
+			token:    newTestJWT(`{"jti":"0f6c4a8e-2f0b-4b8f-9c55-0d7f3b0ee2a1"}`),
+			expected: "0f6c4a8e-2f0b-4b8f-9c55-0d7f3b0ee2a1",
+		},
+		{
+			name:     "token without id",
+			token:    newTestJWT(`{"sub":"system:serviceaccount:ns:sa"}`),
+			expected: "",
+		},
+		{
+			name:     "invalid payload",
+			token:    "header.!!!.signature",
+			expected: "",
+		},
+		{
+			name:     "not a jwt",
+			token:    "token",
+			expected: "",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if tokenID := tokenIDFromJWT(c.token); tokenID != c.expected {
+				t.Errorf("expected %q, but got %q", c.expected, tokenID)
+			}
+		})
+	}
+}
+
+func TestIssueRegistrationToken(t *testing.T) {
+	token := newTestJWT(`{"jti":"token-1"}`)
+	kubeClient := kubefake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		// the fake client does not generate the name
+		secret := action.(clienttesting.CreateAction).GetObject().(*corev1.Secret)
+		if secret.Name == "" {
+			secret.Name = secret.GenerateName + "abcde"
+		}
+		return false, nil, nil
+	})
+	kubeClient.PrependReactor("create", "serviceaccounts", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		tokenRequest := action.(clienttesting.CreateAction).GetObject().(*authv1.TokenRequest)
+		if tokenRequest.Spec.BoundObjectRef == nil || tokenRequest.Spec.BoundObjectRef.Name != "registration-token-abcde" {
+			t.Errorf("expected the token is bound to the registration token secret, but got %v",
+				tokenRequest.Spec.BoundObjectRef)
+		}
+		return true, &authv1.TokenRequest{
+			Status: authv1.TokenRequestStatus{
+				Token:               token,
+				ExpirationTimestamp: metav1.NewTime(time.Now().Add(time.Hour)),
+			},
+		}, nil
+	})
+
+	issued, secretName, err := IssueRegistrationToken(context.TODO(), kubeClient, "agent-registration-bootstrap",
+		"open-cluster-management", "cluster1", 3600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(issued) != token {
+		t.Errorf("expected token %q, but got %q", token, string(issued))
+	}
+	if secretName != "registration-token-abcde" {
+		t.Errorf("expected secret registration-token-abcde, but got %q", secretName)
+	}
+
+	secret, err := kubeClient.CoreV1().Secrets("open-cluster-management").Get(context.TODO(),
+		"registration-token-abcde", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret.Type != constants.RegistrationTokenSecretType {
+		t.Errorf("unexpected secret type %s", secret.Type)
+	}
+	if secret.Labels[constants.LabelRegistrationTokenID] != "token-1" {
+		t.Errorf("expected the token id label, but got %v", secret.Labels)
+	}
+	if secret.Annotations[constants.AnnotationRegistrationTokenCluster] != "cluster1" {
+		t.Errorf("expected the cluster annotation, but got %v", secret.Annotations)
+	}
+	if _, ok := secret.Annotations[constants.AnnotationRegistrationTokenExpiration]; !ok {
+		t.Errorf("expected the expiration annotation, but got %v", secret.Annotations)
+	}
+}
+
+func TestConsumeRegistrationToken(t *testing.T) {
+	notExpired := time.Now().Add(time.Hour)
+	cases := []struct {
+		name           string
+		secrets        []runtime.Object
+		csr            *certificatesv1.CertificateSigningRequest
+		expectedValid  bool
+		expectedUsedBy string
+	}{
+		{
+			name:    "csr without token id",
+			secrets: []runtime.Object{newRegistrationTokenSecret("token", "token-1", "cluster1", "", notExpired)},
+			csr:     newRegistrationCSR("csr1", "cluster1", ""),
+		},
+		{
+			name:    "token not found",
+			secrets: []runtime.Object{newRegistrationTokenSecret("token", "token-1", "cluster1", "", notExpired)},
+			csr:     newRegistrationCSR("csr1", "cluster1", "token-2"),
+		},
+		{
+			name:           "valid token",
+			secrets:        []runtime.Object{newRegistrationTokenSecret("token", "token-1", "cluster1", "", notExpired)},
+			csr:            newRegistrationCSR("csr1", "cluster1", "token-1"),
+			expectedValid:  true,
+			expectedUsedBy: "csr1",
+		},
+		{
+			name:    "token of another cluster",
+			secrets: []runtime.Object{newRegistrationTokenSecret("token", "token-1", "cluster1", "", notExpired)},
+			csr:     newRegistrationCSR("csr1", "cluster2", "token-1"),
+		},
+		{
+			name:           "token used by another csr",
+			secrets:        []runtime.Object{newRegistrationTokenSecret("token", "token-1", "cluster1", "csr0", notExpired)},
+			csr:            newRegistrationCSR("csr1", "cluster1", "token-1"),
+			expectedUsedBy: "csr0",
+		},
+		{
+			name:           "token used by the same csr",
+			secrets:        []runtime.Object{newRegistrationTokenSecret("token", "token-1", "cluster1", "csr1", notExpired)},
+			csr:            newRegistrationCSR("csr1", "cluster1", "token-1"),
+			expectedValid:  true,
+			expectedUsedBy: "csr1",
+		},
+		{
+			name: "expired token",
+			secrets: []runtime.Object{
+				newRegistrationTokenSecret("token", "token-1", "cluster1", "", time.Now().Add(-time.Hour)),
+			},
+			csr: newRegistrationCSR("csr1", "cluster1", "token-1"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(c.secrets...)
+			invalidReason, err := ConsumeRegistrationToken(context.TODO(), kubeClient, "open-cluster-management", c.csr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if valid := len(invalidReason) == 0; valid != c.expectedValid {
+				t.Errorf("expected valid %v, but got %v, reason: %s", c.expectedValid, valid, invalidReason)
+			}
+
+			secret, err := kubeClient.CoreV1().Secrets("open-cluster-management").Get(context.TODO(), "token",
+				metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if usedBy := secret.Annotations[constants.AnnotationRegistrationTokenUsedBy]; usedBy != c.expectedUsedBy {
+				t.Errorf("expected the token is used by %q, but got %q", c.expectedUsedBy, usedBy)
+			}
+		})
+	}
+}
+
+func TestCleanupRegistrationTokens(t *testing.T) {
+	now := time.Now()
+	approvedCSR := newRegistrationCSR("approved", "cluster1", "token-1")
+	approvedCSR.Status.Conditions = []certificatesv1.CertificateSigningRequestCondition{
+		{Type: certificatesv1.CertificateApproved, Status: corev1.ConditionTrue},
+	}
+	pendingCSR := newRegistrationCSR("pending", "cluster1", "token-1")
+
+	withoutExpiration := func(secret *corev1.Secret, created time.Time) *corev1.Secret {
+		delete(secret.Annotations, constants.AnnotationRegistrationTokenExpiration)
+		secret.CreationTimestamp = metav1.NewTime(created)
+		return secret
+	}
+
+	cases := []struct {
+		name     string
+		secret   *corev1.Secret
+		expected bool
+	}{
+		{
+			name:     "unused token",
+			secret:   newRegistrationTokenSecret("token", "token-1", "cluster1", "", now.Add(time.Hour)),
+			expected: true,
+		},
+		{
+			name:     "expired token",
+			secret:   newRegistrationTokenSecret("token", "token-1", "cluster1", "", now.Add(-time.Minute)),
+			expected: false,
+		},
+		{
+			name: "token being issued",
+			secret: withoutExpiration(newRegistrationTokenSecret("token", "token-1", "cluster1", "",
+				now.Add(time.Hour)), now.Add(-time.Minute)),
+			expected: true,
+		},
+		{
+			name: "token left by a failed request",
+			secret: withoutExpiration(newRegistrationTokenSecret("token", "token-1", "cluster1", "",
+				now.Add(time.Hour)), now.Add(-time.Hour)),
+			expected: false,
+		},
+		{
+			name:     "token used by an approved csr",
+			secret:   newRegistrationTokenSecret("token", "token-1", "cluster1", "approved", now.Add(time.Hour)),
+			expected: false,
+		},
+		{
+			name:     "token used by a pending csr",
+			secret:   newRegistrationTokenSecret("token", "token-1", "cluster1", "pending", now.Add(time.Hour)),
+			expected: true,
+		},
+		{
+			name:     "token used by a deleted csr",
+			secret:   newRegistrationTokenSecret("token", "token-1", "cluster1", "deleted", now.Add(time.Hour)),
+			expected: false,
+		},
+		{
+			name: "other secret",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "token",
+					Namespace: "open-cluster-management",
+				},
+				Type: corev1.SecretTypeOpaque,
+			},
+			expected: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(c.secret, approvedCSR, pendingCSR)
+			if err := CleanupRegistrationTokens(context.TODO(), kubeClient, "open-cluster-management", now); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err := kubeClient.CoreV1().Secrets("open-cluster-management").Get(context.TODO(), "token",
+				metav1.GetOptions{})
+			if exists := err == nil; exists != c.expected {
+				t.Errorf("expected the secret exists %v, but got %v, error: %v", c.expected, exists, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// AutoImportRetryPolicy is the retry policy of the failed auto imports
+type AutoImportRetryPolicy struct {
+	// MaxAttempts is the maximum number of the failed attempts, the import is stopped once it is reached
+	MaxAttempts int
+	// BackoffBase is the delay before retrying the first failed attempt, it is doubled for each failed attempt
+	BackoffBase time.Duration
+	// BackoffMax is the maximum delay between the retries
+	BackoffMax time.Duration
+}
+
+// Backoff returns the delay before retrying the import after the given number of the failed attempts
+func (p AutoImportRetryPolicy) Backoff(attempts int) time.Duration {
+	backoff := p.BackoffBase
+	for i := 1; i < attempts && backoff < p.BackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > p.BackoffMax {
+		return p.BackoffMax
+	}
+	return backoff
+}
+
+type AutoImportRetryPolicyGetterFunc func(secret *corev1.Secret) (AutoImportRetryPolicy, error)
+
+// AutoImportRetryPolicyGetter returns the retry policy of an auto-import-secret. The policy is specified by the
+// autoImportMaxAttempts, autoImportBackoffBase and autoImportBackoffMax in the import-controller-config ConfigMap,
+// and the maximum attempts can be overridden by the autoImportRetry of the auto-import-secret, or the deprecated
+// retry_times of the auto-import/rosa auto-import-secret.
+func AutoImportRetryPolicyGetter(componentNamespace string, configMapLister corev1listers.ConfigMapLister,
+	log logr.Logger) AutoImportRetryPolicyGetterFunc {
+	return func(secret *corev1.Secret) (AutoImportRetryPolicy, error) {
+		policy := AutoImportRetryPolicy{
+			MaxAttempts: constants.DefaultAutoImportMaxAttempts,
+			BackoffBase: constants.DefaultAutoImportBackoffBase,
+			BackoffMax:  constants.DefaultAutoImportBackoffMax,
+		}
+
+		cm, err := configMapLister.ConfigMaps(componentNamespace).Get(constants.ControllerConfigConfigMapName)
+		if err != nil && !errors.IsNotFound(err) {
+			return policy, err
+		}
+		if err == nil {
+			if value, ok := cm.Data[constants.AutoImportMaxAttemptsKey]; ok {
+				maxAttempts, err := strconv.Atoi(value)
+				if err == nil && maxAttempts > 0 {
+					policy.MaxAttempts = maxAttempts
+				} else {
+					log.Info("Invalid config value found and use default instead.",
+						"configmap", constants.ControllerConfigConfigMapName,
+						constants.AutoImportMaxAttemptsKey, value,
+						"default", policy.MaxAttempts)
+				}
+			}
+
+			policy.BackoffBase = durationConfig(cm.Data, constants.AutoImportBackoffBaseKey, policy.BackoffBase, log)
+			policy.BackoffMax = durationConfig(cm.Data, constants.AutoImportBackoffMaxKey, policy.BackoffMax, log)
+		}
+
+		if secret != nil {
+			retryKey := constants.AutoImportSecretRetryKey
+			value, ok := secret.Data[retryKey]
+			if !ok && secret.Type == constants.AutoImportSecretRosaConfig {
+				// the deprecated retry_times of the rosa auto-import-secret is an alias of the autoImportRetry
+				retryKey = constants.AutoImportSecretRosaConfigRetryTimesKey
+				value, ok = secret.Data[retryKey]
+			}
+			if ok {
+				retry, err := strconv.Atoi(string(value))
+				if err == nil && retry >= 0 {
+					policy.MaxAttempts = retry + 1
+				} else {
+					log.Info("Invalid auto import retry found in the auto-import-secret and ignore it.",
+						"secret", secret.Namespace+"/"+secret.Name,
+						retryKey, string(value))
+				}
+			}
+		}
+
+		return policy, nil
+	}
+}
+
+func durationConfig(data map[string]string, key string, defaultValue time.Duration, log logr.Logger) time.Duration {
+	value, ok := data[key]
+	if !ok {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Info("Invalid config value found and use default instead.",
+			"configmap", constants.ControllerConfigConfigMapName,
+			key, value,
+			"default", defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// GetAutoImportAttempts returns the failed auto import attempts recorded on the auto-import-secret. The attempts
+// are counted against the data of the auto-import-secret, they are reset once the data is changed.
+func GetAutoImportAttempts(secret *corev1.Secret) int {
+	if secret.Annotations[constants.AnnotationAutoImportAttemptsSecretHash] != secretDataHash(secret) {
+		return 0
+	}
+	attempts, err := strconv.Atoi(secret.Annotations[constants.AnnotationAutoImportAttempts])
+	if err != nil || attempts < 0 {
+		return 0
+	}
+	return attempts
+}
+
+// RecordAutoImportAttempt records a failed auto import attempt on the auto-import-secret and returns the attempts.
+// The attempts are kept in the annotations of the auto-import-secret, so they survive the restart and the leader
+// election failover of the import controller.
+func RecordAutoImportAttempt(ctx context.Context, kubeClient kubernetes.Interface, secret *corev1.Secret) (int, error) {
+	attempts := GetAutoImportAttempts(secret) + 1
+	hash := secretDataHash(secret)
+	if err := patchAutoImportAttempts(ctx, kubeClient, secret, map[string]*string{
+		constants.AnnotationAutoImportAttempts:           ptr.To(strconv.Itoa(attempts)),
+		constants.AnnotationAutoImportAttemptsSecretHash: &hash,
+	}); err != nil {
+		return 0, fmt.Errorf("failed to record the auto import attempts on the secret %s/%s: %v",
+			secret.Namespace, secret.Name, err)
+	}
+	return attempts, nil
+}
+
+// ResetAutoImportAttempts removes the failed auto import attempts from the auto-import-secret
+func ResetAutoImportAttempts(ctx context.Context, kubeClient kubernetes.Interface, secret *corev1.Secret) error {
+	_, hasAttempts := secret.Annotations[constants.AnnotationAutoImportAttempts]
+	_, hasHash := secret.Annotations[constants.AnnotationAutoImportAttemptsSecretHash]
+	if !hasAttempts && !hasHash {
+		return nil
+	}
+	return patchAutoImportAttempts(ctx, kubeClient, secret, map[string]*string{
+		constants.AnnotationAutoImportAttempts:           nil,
+		constants.AnnotationAutoImportAttemptsSecretHash: nil,
+	})
+}
+
+// patchAutoImportAttempts patches the annotations of the auto-import-secret, the nil values remove the annotations
+func patchAutoImportAttempts(ctx context.Context, kubeClient kubernetes.Interface, secret *corev1.Secret,
+	annotations map[string]*string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = kubeClient.CoreV1().Secrets(secret.Namespace).Patch(ctx, secret.Name, types.MergePatchType, patch,
+		metav1.PatchOptions{})
+	return err
+}
+
+func secretDataHash(secret *corev1.Secret) string {
+	keys := make([]string, 0, len(secret.Data))
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%d:%s%d:", len(key), key, len(secret.Data[key]))
+		h.Write(secret.Data[key])
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
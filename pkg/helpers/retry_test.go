@@ -0,0 +1,181 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestAutoImportRetryPolicyBackoff(t *testing.T) {
+	policy := AutoImportRetryPolicy{
+		MaxAttempts: 10,
+		BackoffBase: 10 * time.Second,
+		BackoffMax:  time.Minute,
+	}
+
+	cases := []struct {
+		attempts        int
+		expectedBackoff time.Duration
+	}{
+		{attempts: 1, expectedBackoff: 10 * time.Second},
+		{attempts: 2, expectedBackoff: 20 * time.Second},
+		{attempts: 3, expectedBackoff: 40 * time.Second},
+		{attempts: 4, expectedBackoff: time.Minute},
+		{attempts: 100, expectedBackoff: time.Minute},
+	}
+
+	for _, c := range cases {
+		if backoff := policy.Backoff(c.attempts); backoff != c.expectedBackoff {
+			t.Errorf("expected backoff %s after %d attempts, but got %s", c.expectedBackoff, c.attempts, backoff)
+		}
+	}
+}
+
+func TestAutoImportRetryPolicyGetter(t *testing.T) {
+	cases := []struct {
+		name           string
+		data           map[string]string
+		secretType     corev1.SecretType
+		secretData     map[string][]byte
+		expectedPolicy AutoImportRetryPolicy
+	}{
+		{
+			name:           "no configmap",
+			expectedPolicy: AutoImportRetryPolicy{MaxAttempts: 10, BackoffBase: 10 * time.Second, BackoffMax: 10 * time.Minute},
+		},
+		{
+			name: "configmap",
+			data: map[string]string{
+				"autoImportMaxAttempts": "3",
+				"autoImportBackoffBase": "1m",
+				"autoImportBackoffMax":  "1h",
+			},
+			expectedPolicy: AutoImportRetryPolicy{MaxAttempts: 3, BackoffBase: time.Minute, BackoffMax: time.Hour},
+		},
+		{
+			name: "invalid configmap",
+			data: map[string]string{
+				"autoImportMaxAttempts": "0",
+				"autoImportBackoffBase": "invalid",
+				"autoImportBackoffMax":  "-1h",
+			},
+			expectedPolicy: AutoImportRetryPolicy{MaxAttempts: 10, BackoffBase: 10 * time.Second, BackoffMax: 10 * time.Minute},
+		},
+		{
+			name:           "auto import retry of the secret",
+			data:           map[string]string{"autoImportMaxAttempts": "3"},
+			secretData:     map[string][]byte{"autoImportRetry": []byte("0")},
+			expectedPolicy: AutoImportRetryPolicy{MaxAttempts: 1, BackoffBase: 10 * time.Second, BackoffMax: 10 * time.Minute},
+		},
+		{
+			name:           "deprecated retry times of the rosa secret",
+			data:           map[string]string{"autoImportMaxAttempts": "3"},
+			secretType:     "auto-import/rosa",
+			secretData:     map[string][]byte{"retry_times": []byte("20")},
+			expectedPolicy: AutoImportRetryPolicy{MaxAttempts: 21, BackoffBase: 10 * time.Second, BackoffMax: 10 * time.Minute},
+		},
+		{
+			name:       "auto import retry overrides the retry times of the rosa secret",
+			secretType: "auto-import/rosa",
+			secretData: map[string][]byte{
+				"autoImportRetry": []byte("1"),
+				"retry_times":     []byte("20"),
+			},
+			expectedPolicy: AutoImportRetryPolicy{MaxAttempts: 2, BackoffBase: 10 * time.Second, BackoffMax: 10 * time.Minute},
+		},
+		{
+			name:           "invalid auto import retry of the secret",
+			data:           map[string]string{"autoImportMaxAttempts": "3"},
+			secretData:     map[string][]byte{"autoImportRetry": []byte("-1")},
+			expectedPolicy: AutoImportRetryPolicy{MaxAttempts: 3, BackoffBase: 10 * time.Second, BackoffMax: 10 * time.Minute},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeInformerFactory := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 10*time.Minute)
+			if c.data != nil {
+				if err := kubeInformerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "import-controller-config", Namespace: "test"},
+					Data:       c.data,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			policy, err := AutoImportRetryPolicyGetter("test", kubeInformerFactory.Core().V1().ConfigMaps().Lister(),
+				logf.Log.WithName("auto-import-retry-policy-getter"))(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "auto-import-secret", Namespace: "cluster1"},
+				Type:       c.secretType,
+				Data:       c.secretData,
+			})
+			if err != nil {
+				t.Errorf("unexpected err %v", err)
+			}
+			if policy != c.expectedPolicy {
+				t.Errorf("expected policy %v, but got %v", c.expectedPolicy, policy)
+			}
+		})
+	}
+}
+
+func TestAutoImportAttempts(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "auto-import-secret", Namespace: "cluster1"},
+		Data:       map[string][]byte{"kubeconfig": []byte("kubeconfig")},
+	}
+	kubeClient := kubefake.NewSimpleClientset(secret)
+	ctx := context.TODO()
+	getSecret := func() *corev1.Secret {
+		secret, err := kubeClient.CoreV1().Secrets("cluster1").Get(ctx, "auto-import-secret", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return secret
+	}
+
+	if n := GetAutoImportAttempts(secret); n != 0 {
+		t.Errorf("expected 0 attempts, but got %d", n)
+	}
+
+	if _, err := RecordAutoImportAttempt(ctx, kubeClient, getSecret()); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := RecordAutoImportAttempt(ctx, kubeClient, getSecret()); err != nil || n != 2 {
+		t.Errorf("expected 2 attempts, but got %d, %v", n, err)
+	}
+	// the attempts are kept in the secret, e.g. after the import controller restarts
+	if n := GetAutoImportAttempts(getSecret()); n != 2 {
+		t.Errorf("expected 2 attempts, but got %d", n)
+	}
+
+	// the attempts are reset once the secret is changed
+	changedSecret := getSecret()
+	changedSecret.Data = map[string][]byte{"kubeconfig": []byte("changed")}
+	changedSecret, err := kubeClient.CoreV1().Secrets("cluster1").Update(ctx, changedSecret, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := GetAutoImportAttempts(changedSecret); n != 0 {
+		t.Errorf("expected 0 attempts with the changed secret, but got %d", n)
+	}
+	if n, err := RecordAutoImportAttempt(ctx, kubeClient, changedSecret); err != nil || n != 1 {
+		t.Errorf("expected 1 attempt with the changed secret, but got %d, %v", n, err)
+	}
+
+	if err := ResetAutoImportAttempts(ctx, kubeClient, getSecret()); err != nil {
+		t.Fatal(err)
+	}
+	if annotations := getSecret().Annotations; len(annotations) != 0 {
+		t.Errorf("expected the attempts are removed, but got %v", annotations)
+	}
+}
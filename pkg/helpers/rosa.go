@@ -6,8 +6,6 @@ package helpers
 import (
 	"fmt"
 	"net/http"
-	"strconv"
-	"time"
 
 	sdk "github.com/openshift-online/ocm-sdk-go"
 	clustersmgmtv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
@@ -29,31 +27,27 @@ const (
 
 	importHTPasswdIDProvider = "acm-import"
 	importHTPasswdUser       = "acm-import"
-
-	rosaImportRetryPeriod = 30 * time.Second
-	defaultRetryTimes     = 20 // default timeout will be `defaultRetryTimes*rosaImportRetryPeriod (10 mins)
 )
 
+// RosaKubeConfigGetter gets the kubeconfig of a rosa cluster with a temporary import user. The kubeconfig may not
+// be ready right after the import user is created, the retries are handled by the auto import retry policy.
 type RosaKubeConfigGetter struct {
-	apiServerURL      string
-	tokenURL          string
-	token             string
-	clientID          string
-	clientSecret      string
-	clusterID         string
-	importUserPasswd  string
-	totalRetryTimes   int
-	currentRetryTimes int
-	authMethod        string
+	apiServerURL     string
+	tokenURL         string
+	token            string
+	clientID         string
+	clientSecret     string
+	clusterID        string
+	importUserPasswd string
+	authMethod       string
+	insecureRefused  bool
 }
 
 func NewRosaKubeConfigGetter() *RosaKubeConfigGetter {
 	return &RosaKubeConfigGetter{
-		authMethod:        constants.AutoImportSecretRosaConfigAuthMethodOfflineToken,
-		apiServerURL:      defaultAPIServerURL,
-		tokenURL:          defaultTokenURL,
-		totalRetryTimes:   defaultRetryTimes,
-		currentRetryTimes: 0,
+		authMethod:   constants.AutoImportSecretRosaConfigAuthMethodOfflineToken,
+		apiServerURL: defaultAPIServerURL,
+		tokenURL:     defaultTokenURL,
 	}
 }
 
@@ -69,23 +63,14 @@ func (g *RosaKubeConfigGetter) SetToken(token string) {
 	g.token = token
 }
 
-func (g *RosaKubeConfigGetter) SetClusterID(clusterID string) {
-	g.clusterID = clusterID
+// SetInsecureRefused sets whether to refuse the import if the certificate of the rosa cluster kube apiserver
+// cannot be verified
+func (g *RosaKubeConfigGetter) SetInsecureRefused(refused bool) {
+	g.insecureRefused = refused
 }
 
-func (g *RosaKubeConfigGetter) SetRetryTimes(retryTimes string) {
-	retryTimesInt, err := strconv.Atoi(retryTimes)
-	if err != nil {
-		klog.Warningf("retry times is invalid, using default retry times (%d), %v", defaultRetryTimes, err)
-		return
-	}
-
-	if retryTimesInt <= 0 {
-		klog.Warningf("retry times is less than or equal to 0, using default retry times (%d), %v", defaultRetryTimes, err)
-		return
-	}
-
-	g.totalRetryTimes = retryTimesInt
+func (g *RosaKubeConfigGetter) SetClusterID(clusterID string) {
+	g.clusterID = clusterID
 }
 
 func (g *RosaKubeConfigGetter) SetAuthMethod(authMethod string) {
@@ -100,10 +85,11 @@ func (g *RosaKubeConfigGetter) SetClientSecret(clientSecret string) {
 	g.clientSecret = clientSecret
 }
 
-func (g *RosaKubeConfigGetter) KubeConfig() (bool, *clientcmdapi.Config, error) {
+// KubeConfig returns the kubeconfig of the rosa cluster, an error is returned if the kubeconfig is not ready
+func (g *RosaKubeConfigGetter) KubeConfig() (*clientcmdapi.Config, error) {
 	connection, err := g.newConnection()
 	if err != nil {
-		return false, nil, err
+		return nil, err
 	}
 	defer connection.Close()
 
@@ -111,24 +97,24 @@ func (g *RosaKubeConfigGetter) KubeConfig() (bool, *clientcmdapi.Config, error)
 	clusterClient := connection.ClustersMgmt().V1().Clusters().Cluster(g.clusterID)
 	resp, err := clusterClient.Get().Send()
 	if err != nil {
-		return false, nil, err
+		return nil, err
 	}
 
 	api, ok := resp.Body().GetAPI()
 	if !ok {
-		return false, nil, fmt.Errorf("rosa cluster api url is not found, clusterID: %s", g.clusterID)
+		return nil, fmt.Errorf("rosa cluster api url is not found, clusterID: %s", g.clusterID)
 	}
 
 	if len(g.importUserPasswd) == 0 {
 		importUserPassword, err := createImportUserWithHTPasswdIDProvider(clusterClient)
 		if err != nil {
-			return false, nil, err
+			return nil, err
 		}
 
 		// add the acm import user to cluster admin group
 		adminsClient := clusterClient.Groups().Group(clusterAdminGroup).Users()
 		if err := addImportUserToClusterAdminGroup(adminsClient); err != nil {
-			return false, nil, err
+			return nil, err
 		}
 
 		g.importUserPasswd = importUserPassword
@@ -140,18 +126,11 @@ func (g *RosaKubeConfigGetter) KubeConfig() (bool, *clientcmdapi.Config, error)
 		Password: g.importUserPasswd,
 	})
 	if err != nil {
-		if g.shouldRetry(clusterClient) {
-			klog.Infof("Failed to get kubeconfig for rosa cluster %s, retry after %d seconds, %v",
-				g.clusterID, rosaImportRetryPeriod/time.Second, err)
-			return true, nil, fmt.Errorf("kubeconfig for rosa cluster %s is not ready, retry after %d seconds",
-				g.clusterID, rosaImportRetryPeriod/time.Second)
-		}
-
-		return false, nil, fmt.Errorf("failed to get kubeconfig for rosa cluster %s after %d seconds, err",
-			g.clusterID, (rosaImportRetryPeriod*time.Duration(g.totalRetryTimes))/time.Second)
+		klog.Infof("Failed to get kubeconfig for rosa cluster %s, %v", g.clusterID, err)
+		return nil, fmt.Errorf("kubeconfig for rosa cluster %s is not ready", g.clusterID)
 	}
 
-	return false, buildKubeConfigFileWithToken(api.URL(), token), nil
+	return buildKubeConfigFileWithToken(api.URL(), token), nil
 }
 
 func (g *RosaKubeConfigGetter) Cleanup() error {
@@ -204,28 +183,6 @@ func (g *RosaKubeConfigGetter) newConnection() (*sdk.Connection, error) {
 	}
 }
 
-func (g *RosaKubeConfigGetter) shouldRetry(clusterClient *clustersmgmtv1.ClusterClient) bool {
-	if g.currentRetryTimes >= g.totalRetryTimes {
-		// request the cluster token timeout, delete its id provider and remove the import user from cluster admin group
-		klog.Warningf("stop to retry getting kube token for rosa cluster %s, reach the retry times limit (%d)",
-			g.clusterID, g.totalRetryTimes)
-		if err := deleteHTPasswdIDProvider(clusterClient.IdentityProviders(), g.clusterID); err != nil {
-			klog.Warningf("failed to delete the htPasswd id provider %s for rosa cluster %s, %v",
-				importHTPasswdIDProvider, g.clusterID, err)
-		}
-
-		if err := removeImportUserFromClusterAdminGroup(clusterClient.Groups().Group(clusterAdminGroup).Users()); err != nil {
-			klog.Warningf("failed to remove the import user %s from cluster admin group for rosa cluster %s, %v",
-				importHTPasswdIDProvider, g.clusterID, err)
-		}
-
-		return false
-	}
-
-	g.currentRetryTimes++
-	return true
-}
-
 func createImportUserWithHTPasswdIDProvider(clusterClient *clustersmgmtv1.ClusterClient) (string, error) {
 	// try to find a htPasswd provider for acm import user
 	idProvidersClient := clusterClient.IdentityProviders()
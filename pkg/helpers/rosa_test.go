@@ -115,7 +115,7 @@ func TestKubeConfig(t *testing.T) {
 					clustersmgmttesting.RespondWithJSON(http.StatusCreated, "{}"),
 				),
 			},
-			expectedErrMsg: "kubeconfig for rosa cluster 0002 is not ready, retry after 30 seconds",
+			expectedErrMsg: "kubeconfig for rosa cluster 0002 is not ready",
 		},
 		{
 			name:      "there is only a htpasswd provider id",
@@ -170,7 +170,7 @@ func TestKubeConfig(t *testing.T) {
 					clustersmgmttesting.RespondWithJSON(http.StatusCreated, "{}"),
 				),
 			},
-			expectedErrMsg: "kubeconfig for rosa cluster 0003 is not ready, retry after 30 seconds",
+			expectedErrMsg: "kubeconfig for rosa cluster 0003 is not ready",
 		},
 		{
 			name:      "there is an existed htpasswd user",
@@ -225,7 +225,7 @@ func TestKubeConfig(t *testing.T) {
 					clustersmgmttesting.RespondWithJSON(http.StatusCreated, "{}"),
 				),
 			},
-			expectedErrMsg: "kubeconfig for rosa cluster 0004 is not ready, retry after 30 seconds",
+			expectedErrMsg: "kubeconfig for rosa cluster 0004 is not ready",
 		},
 		{
 			name:      "the user is already in the admin group",
@@ -264,7 +264,7 @@ func TestKubeConfig(t *testing.T) {
 					clustersmgmttesting.RespondWithJSON(http.StatusOK, "{}"),
 				),
 			},
-			expectedErrMsg: "kubeconfig for rosa cluster 0005 is not ready, retry after 30 seconds",
+			expectedErrMsg: "kubeconfig for rosa cluster 0005 is not ready",
 		},
 	}
 
@@ -287,7 +287,7 @@ func TestKubeConfig(t *testing.T) {
 
 			apiServer.AppendHandlers(c.handlers...)
 
-			_, _, err := kubeConfigGetter.KubeConfig()
+			_, err := kubeConfigGetter.KubeConfig()
 			if len(c.expectedErrMsg) == 0 {
 				if err != nil {
 					t.Errorf("unexected error %v", err)
@@ -308,7 +308,7 @@ func TestKubeConfig(t *testing.T) {
 	}
 }
 
-func TestStopRetry(t *testing.T) {
+func TestCleanup(t *testing.T) {
 	gomega.RegisterTestingT(t)
 
 	accessToken := clustersmgmttesting.MakeTokenString("Bearer", 5*time.Minute)
@@ -320,19 +320,9 @@ func TestStopRetry(t *testing.T) {
 			clustersmgmttesting.RespondWithAccessAndRefreshTokens(accessToken, refreshToken),
 		),
 	)
-	oauthServer := clustersmgmttesting.MakeTCPServer()
-	oauthServer.AppendHandlers(
-		ghttp.CombineHandlers(
-			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				// just ensure the token request is received
-				w.WriteHeader(http.StatusNotImplemented)
-			}),
-		),
-	)
 	apiServer := clustersmgmttesting.MakeTCPServer()
 	defer func() {
 		oidServer.Close()
-		oauthServer.Close()
 		apiServer.Close()
 	}()
 
@@ -342,17 +332,9 @@ func TestStopRetry(t *testing.T) {
 		handlers  []http.HandlerFunc
 	}{
 		{
-			name:      "stop retry",
+			name:      "delete the import user",
 			clusterID: "test",
 			handlers: []http.HandlerFunc{
-				ghttp.CombineHandlers(
-					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-						if r.Method != http.MethodGet || r.URL.Path != "/api/clusters_mgmt/v1/clusters/test" {
-							t.Fatalf("unexpected request %s - %s", r.Method, r.URL.Path)
-						}
-					}),
-					clustersmgmttesting.RespondWithJSON(http.StatusOK, newRosaCluster(oauthServer.URL())),
-				),
 				ghttp.CombineHandlers(
 					http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 						if r.Method != http.MethodGet || r.URL.Path != "/api/clusters_mgmt/v1/clusters/test/identity_providers" {
@@ -391,18 +373,18 @@ func TestStopRetry(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			getter := &RosaKubeConfigGetter{
-				apiServerURL:      apiServer.URL(),
-				tokenURL:          oidServer.URL(),
-				totalRetryTimes:   defaultRetryTimes,
-				clusterID:         c.clusterID,
-				currentRetryTimes: 20,
-				importUserPasswd:  "1234",
-			}
+			getter := NewRosaKubeConfigGetter()
+			getter.SetAPIServerURL(apiServer.URL())
+			getter.SetTokenURL(oidServer.URL())
+			getter.SetToken(refreshToken)
+			getter.SetClusterID(c.clusterID)
 			apiServer.AppendHandlers(c.handlers...)
 
-			if retry, _, _ := getter.KubeConfig(); retry {
-				t.Errorf("expect stop to retry, but failed")
+			if err := getter.Cleanup(); err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if received := len(apiServer.ReceivedRequests()); received != len(c.handlers) {
+				t.Errorf("expected %d requests, but got %d", len(c.handlers), received)
 			}
 		})
 	}
@@ -0,0 +1,199 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+)
+
+const serverTLSDialTimeout = 10 * time.Second
+
+// setServerTLSVerification configures the clusters of the given kubeconfig to verify the certificate of the
+// managed cluster kube apiserver.
+//
+// - if the auto-import-secret has the ca.crt, the ca.crt is used to verify the kube apiserver.
+// - if the auto-import-secret has the ca_fingerprint, the certificate chain of the kube apiserver is fetched,
+// the certificate whose fingerprint matches the ca_fingerprint and its issuers are used to verify the kube
+// apiserver.
+// - otherwise, if the insecure import is refused, the system CAs are used to verify the kube apiserver,
+// or the verification is skipped for compatibility.
+func setServerTLSVerification(config *clientcmdapi.Config, secret *corev1.Secret, refuseInsecure bool) error {
+	caData := secret.Data[constants.AutoImportSecretCAKey]
+	fingerprint := string(secret.Data[constants.AutoImportSecretCAFingerprintKey])
+
+	if len(caData) == 0 && len(fingerprint) == 0 && !refuseInsecure {
+		klog.Warningf("the certificate of the kube apiserver is not verified for auto-import-secret %s/%s, "+
+			"set %s or %s in the secret to verify it", secret.Namespace, secret.Name,
+			constants.AutoImportSecretCAKey, constants.AutoImportSecretCAFingerprintKey)
+		return nil
+	}
+
+	for _, cluster := range config.Clusters {
+		serverCAData, err := verifyServerCertificate(cluster.Server, caData, fingerprint)
+		if err != nil {
+			return err
+		}
+
+		cluster.InsecureSkipTLSVerify = false
+		cluster.CertificateAuthorityData = serverCAData
+	}
+
+	return nil
+}
+
+// validateKubeConfigTLS checks that the clusters of the kubeconfig in an auto-import-secret verify the certificate of
+// the kube apiserver, it is used when the insecure import is refused.
+func validateKubeConfigTLS(config *clientcmdapi.Config) error {
+	for name, cluster := range config.Clusters {
+		if cluster.InsecureSkipTLSVerify {
+			return fmt.Errorf("the cluster %q of the kubeconfig skips the TLS verification, "+
+				"the insecure import is refused", name)
+		}
+		if len(cluster.CertificateAuthorityData) == 0 && len(cluster.CertificateAuthority) == 0 {
+			return fmt.Errorf("the cluster %q of the kubeconfig has no certificate authority, "+
+				"the insecure import is refused", name)
+		}
+	}
+	return nil
+}
+
+// verifyServerCertificate fetches the certificate chain of the server and verifies it with the given CA data
+// or the pinned fingerprint, the system CAs are used if both of them are not provided. It returns the CA data
+// which is used to verify the server, it is empty if the server is verified by the system CAs.
+func verifyServerCertificate(server string, caData []byte, fingerprint string) ([]byte, error) {
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("the server %q is invalid: %v", server, err)
+	}
+
+	certs, err := fetchServerCertificates(serverURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots *x509.CertPool
+	switch {
+	case len(caData) > 0:
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("the %s does not contain any valid PEM encoded certificate",
+				constants.AutoImportSecretCAKey)
+		}
+	case len(fingerprint) > 0:
+		index := -1
+		for i, cert := range certs {
+			if fingerprintEqual(certificateFingerprint(cert), fingerprint) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("the %s %s does not match any certificate served by %s, the fingerprints "+
+				"of the served certificates are [%s]", constants.AutoImportSecretCAFingerprintKey, fingerprint,
+				serverURL.Host, strings.Join(certificateFingerprints(certs), ", "))
+		}
+
+		caData = encodeCertificates(certs[index:])
+		roots = x509.NewCertPool()
+		roots.AppendCertsFromPEM(caData)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       serverURL.Hostname(),
+		Roots:         roots,
+		Intermediates: intermediates,
+	}); err != nil {
+		if roots == nil {
+			return nil, fmt.Errorf("the certificate of %s is not trusted: %v; set %s or %s in the "+
+				"auto-import-secret to verify it, the fingerprints of the served certificates are [%s]",
+				serverURL.Host, err, constants.AutoImportSecretCAKey, constants.AutoImportSecretCAFingerprintKey,
+				strings.Join(certificateFingerprints(certs), ", "))
+		}
+		return nil, fmt.Errorf("failed to verify the certificate of %s: %v", serverURL.Host, err)
+	}
+
+	return caData, nil
+}
+
+// fetchServerCertificates returns the certificate chain served by the server, the chain is not verified.
+func fetchServerCertificates(serverURL *url.URL) ([]*x509.Certificate, error) {
+	host := serverURL.Host
+	if len(serverURL.Port()) == 0 {
+		host = net.JoinHostPort(serverURL.Hostname(), "443")
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: serverTLSDialTimeout}, "tcp", host, &tls.Config{
+		ServerName: serverURL.Hostname(),
+		// the certificates will be verified by the caller
+		InsecureSkipVerify: true, // #nosec G402
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the certificates of %s: %v", host, err)
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate is served by %s", host)
+	}
+	return certs, nil
+}
+
+// certificateFingerprint returns the SHA-256 fingerprint of the certificate in the colon separated hex format
+func certificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	hexSum := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	parts := make([]string, 0, len(sum))
+	for i := 0; i < len(hexSum); i += 2 {
+		parts = append(parts, hexSum[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+func certificateFingerprints(certs []*x509.Certificate) []string {
+	fingerprints := make([]string, 0, len(certs))
+	for _, cert := range certs {
+		fingerprints = append(fingerprints, certificateFingerprint(cert))
+	}
+	return fingerprints
+}
+
+// fingerprintEqual compares two fingerprints, the "sha256:" prefix, the colons and the case are ignored
+func fingerprintEqual(a, b string) bool {
+	normalize := func(f string) string {
+		f = strings.ToLower(strings.TrimSpace(f))
+		f = strings.TrimPrefix(f, "sha256:")
+		return strings.ReplaceAll(f, ":", "")
+	}
+	return normalize(a) == normalize(b)
+}
+
+func encodeCertificates(certs []*x509.Certificate) []byte {
+	buf := &bytes.Buffer{}
+	for _, cert := range certs {
+		_ = pem.Encode(buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return buf.Bytes()
+}
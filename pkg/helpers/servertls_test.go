@@ -0,0 +1,187 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
+)
+
+func newTestTLSServer(t *testing.T) (*httptest.Server, []byte) {
+	caData, caKeyData, err := testinghelpers.NewRootCA("test-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	certData, keyData, err := testinghelpers.NewServerCertificate("test-server", caData, caKeyData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(append(certData, caData...), keyData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	return server, caData
+}
+
+func TestSetServerTLSVerification(t *testing.T) {
+	server, caData := newTestTLSServer(t)
+	defer server.Close()
+
+	otherCAData, _, err := testinghelpers.NewRootCA("other-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(caData)
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name             string
+		data             map[string][]byte
+		refuseInsecure   bool
+		expectedInsecure bool
+		expectedCAData   []byte
+		expectedErr      string
+	}{
+		{
+			name:             "insecure",
+			expectedInsecure: true,
+		},
+		{
+			name:           "insecure is refused",
+			refuseInsecure: true,
+			expectedErr:    "is not trusted",
+		},
+		{
+			name:           "ca",
+			data:           map[string][]byte{"ca.crt": caData},
+			refuseInsecure: true,
+			expectedCAData: caData,
+		},
+		{
+			name:        "invalid ca",
+			data:        map[string][]byte{"ca.crt": []byte("invalid")},
+			expectedErr: "does not contain any valid PEM encoded certificate",
+		},
+		{
+			name:        "mismatched ca",
+			data:        map[string][]byte{"ca.crt": otherCAData},
+			expectedErr: "failed to verify the certificate",
+		},
+		{
+			name: "fingerprint",
+			data: map[string][]byte{
+				"ca_fingerprint": []byte("sha256:" + strings.ToLower(certificateFingerprint(caCert))),
+			},
+			expectedCAData: caData,
+		},
+		{
+			name: "mismatched fingerprint",
+			data: map[string][]byte{
+				"ca_fingerprint": []byte("AA:BB:CC"),
+			},
+			expectedErr: "does not match any certificate",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := buildKubeConfigFileWithToken(server.URL, "test")
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "auto-import-secret", Namespace: "test"},
+				Data:       c.data,
+			}
+
+			err := setServerTLSVerification(config, secret, c.refuseInsecure)
+			if len(c.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), c.expectedErr) {
+					t.Errorf("expected error %q, but got %v", c.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			cluster := config.Clusters["default-cluster"]
+			if cluster.InsecureSkipTLSVerify != c.expectedInsecure {
+				t.Errorf("expected insecure %v, but got %v", c.expectedInsecure, cluster.InsecureSkipTLSVerify)
+			}
+			if string(cluster.CertificateAuthorityData) != string(c.expectedCAData) {
+				t.Errorf("expected ca data %s, but got %s", c.expectedCAData, cluster.CertificateAuthorityData)
+			}
+		})
+	}
+}
+
+func TestValidateKubeConfigTLS(t *testing.T) {
+	cases := []struct {
+		name        string
+		cluster     *clientcmdapi.Cluster
+		expectedErr string
+	}{
+		{
+			name:        "skip tls verification",
+			cluster:     &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443", InsecureSkipTLSVerify: true},
+			expectedErr: "skips the TLS verification",
+		},
+		{
+			name:        "no certificate authority",
+			cluster:     &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443"},
+			expectedErr: "has no certificate authority",
+		},
+		{
+			name:    "with certificate authority",
+			cluster: &clientcmdapi.Cluster{Server: "https://127.0.0.1:6443", CertificateAuthorityData: []byte("ca")},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := clientcmdapi.NewConfig()
+			config.Clusters["cluster"] = c.cluster
+			err := validateKubeConfigTLS(config)
+			if len(c.expectedErr) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.expectedErr) {
+				t.Errorf("expected error %q, but got %v", c.expectedErr, err)
+			}
+
+			kubeconfig, err := clientcmd.Write(*config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, _, err = GenerateImportClientFromKubeConfigSecret(&corev1.Secret{
+				Data: map[string][]byte{constants.AutoImportSecretKubeConfigKey: kubeconfig},
+			}, true)
+			if err == nil || !strings.Contains(err.Error(), c.expectedErr) {
+				t.Errorf("expected the insecure kubeconfig is refused with %q, but got %v", c.expectedErr, err)
+			}
+		})
+	}
+}
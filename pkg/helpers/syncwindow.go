@@ -0,0 +1,300 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// the windows are checked minute by minute, a window longer than this is meaningless
+const maxImportSyncWindowDuration = 7 * 24 * time.Hour
+
+// ImportSyncWindow is a maintenance window in which the importing resources are allowed to be synced to an
+// imported managed cluster. The window starts at each time matched by the cron schedule and lasts for the
+// duration.
+type ImportSyncWindow struct {
+	schedule *cronSchedule
+	duration time.Duration
+}
+
+// ParseImportSyncWindows parses the maintenance windows separated by semicolons, each window is a cron
+// expression with five fields (minute, hour, day of month, month and day of week, in UTC) followed by a
+// duration, e.g. "0 2 * * 6 4h; 0 22 * * 1-5 2h".
+func ParseImportSyncWindows(value string) ([]ImportSyncWindow, error) {
+	windows := []ImportSyncWindow{}
+	for _, item := range strings.Split(value, ";") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+
+		fields := strings.Fields(item)
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("the window %q is invalid, expected 5 cron fields and a duration", item)
+		}
+
+		schedule, err := parseCronSchedule(fields[:5])
+		if err != nil {
+			return nil, fmt.Errorf("the window %q is invalid: %v", item, err)
+		}
+
+		duration, err := time.ParseDuration(fields[5])
+		if err != nil {
+			return nil, fmt.Errorf("the window %q is invalid: %v", item, err)
+		}
+		if duration < time.Minute || duration > maxImportSyncWindowDuration {
+			return nil, fmt.Errorf("the window %q is invalid, the duration must be between %s and %s",
+				item, time.Minute, maxImportSyncWindowDuration)
+		}
+
+		windows = append(windows, ImportSyncWindow{schedule: schedule, duration: duration})
+	}
+	return windows, nil
+}
+
+// ImportSyncWindowsOpen returns whether one of the windows is open at the given time, if none is open, the start
+// time of the next window is returned as well. The sync is not restricted if there is no window.
+func ImportSyncWindowsOpen(windows []ImportSyncWindow, now time.Time) (bool, time.Time) {
+	if len(windows) == 0 {
+		return true, time.Time{}
+	}
+
+	now = now.UTC()
+	var next time.Time
+	for _, window := range windows {
+		// the window is open if it starts in (now - duration, now]
+		start := window.schedule.next(now.Add(-window.duration))
+		if !start.IsZero() && !start.After(now) {
+			return true, time.Time{}
+		}
+
+		start = window.schedule.next(now)
+		if !start.IsZero() && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+	return false, next
+}
+
+type ImportSyncWindowsGetterFunc func(cluster *clusterv1.ManagedCluster) (windows []ImportSyncWindow, err error)
+
+// ImportSyncWindowsGetter returns the maintenance windows of a managed cluster. The windows are determined by the
+// auto-import-sync-windows annotation of the managed cluster, and then the autoImportSyncWindows in the
+// import-controller-config ConfigMap.
+func ImportSyncWindowsGetter(componentNamespace string, configMapLister corev1listers.ConfigMapLister,
+	log logr.Logger) ImportSyncWindowsGetterFunc {
+	return func(cluster *clusterv1.ManagedCluster) ([]ImportSyncWindow, error) {
+		if cluster != nil {
+			if value, ok := cluster.Annotations[constants.AnnotationAutoImportSyncWindows]; ok {
+				windows, err := ParseImportSyncWindows(value)
+				if err == nil {
+					return windows, nil
+				}
+				log.Info("Invalid auto import sync windows annotation found and ignore it.",
+					"managedCluster", cluster.Name, "error", err.Error())
+			}
+		}
+
+		cm, err := configMapLister.ConfigMaps(componentNamespace).Get(constants.ControllerConfigConfigMapName)
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		windows, err := ParseImportSyncWindows(cm.Data[constants.AutoImportSyncWindowsKey])
+		if err != nil {
+			log.Info("Invalid config value found and use default instead.",
+				"configmap", constants.ControllerConfigConfigMapName,
+				constants.AutoImportSyncWindowsKey, cm.Data[constants.AutoImportSyncWindowsKey],
+				"error", err.Error())
+			return nil, nil
+		}
+		return windows, nil
+	}
+}
+
+// DeferImportSync checks whether the sync of the importing resources to an imported managed cluster should be
+// deferred to the next maintenance window. If it is deferred, the ManagedClusterImportSyncPending condition is set
+// to true and the duration to the next window is returned, the caller should requeue the managed cluster after it.
+func DeferImportSync(client client.Client, cluster *clusterv1.ManagedCluster,
+	windowsGetter ImportSyncWindowsGetterFunc) (time.Duration, bool, error) {
+	if windowsGetter == nil {
+		return 0, false, nil
+	}
+
+	windows, err := windowsGetter(cluster)
+	if err != nil {
+		return 0, false, err
+	}
+
+	now := time.Now()
+	open, next := ImportSyncWindowsOpen(windows, now)
+	if open {
+		if !meta.IsStatusConditionTrue(cluster.Status.Conditions, constants.ConditionManagedClusterImportSyncPending) {
+			return 0, false, nil
+		}
+
+		_, err := updateManagedClusterStatus(client, cluster.Name, metav1.Condition{
+			Type:    constants.ConditionManagedClusterImportSyncPending,
+			Status:  metav1.ConditionFalse,
+			Reason:  constants.ConditionReasonManagedClusterImportSyncWindowOpen,
+			Message: "The importing resources are synced in the maintenance window",
+		})
+		return 0, false, err
+	}
+
+	message := "The sync of the importing resources is deferred, no maintenance window is scheduled"
+	requeueAfter := time.Duration(0)
+	if !next.IsZero() {
+		message = fmt.Sprintf("The sync of the importing resources is deferred to the next maintenance window at %s",
+			next.Format(time.RFC3339))
+		requeueAfter = next.Sub(now)
+	}
+
+	if _, err := updateManagedClusterStatus(client, cluster.Name, metav1.Condition{
+		Type:    constants.ConditionManagedClusterImportSyncPending,
+		Status:  metav1.ConditionTrue,
+		Reason:  constants.ConditionReasonManagedClusterImportSyncPending,
+		Message: message,
+	}); err != nil {
+		return 0, true, err
+	}
+	return requeueAfter, true, nil
+}
+
+// cronSchedule is a standard cron schedule with the minute, hour, day of month, month and day of week fields
+type cronSchedule struct {
+	minutes, hours, doms, months, dows map[int]bool
+	// the day matches if either the day of month or the day of week matches when both of them are restricted
+	domRestricted, dowRestricted bool
+}
+
+var cronFieldRanges = [][2]int{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 7},  // day of week, both 0 and 7 are Sunday
+}
+
+func parseCronSchedule(fields []string) (*cronSchedule, error) {
+	sets := make([]map[int]bool, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFieldRanges[i][0], cronFieldRanges[i][1])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+
+	return &cronSchedule{
+		minutes:       sets[0],
+		hours:         sets[1],
+		doms:          sets[2],
+		months:        sets[3],
+		dows:          sets[4],
+		domRestricted: fields[2] != "*",
+		dowRestricted: fields[4] != "*",
+	}, nil
+}
+
+// parseCronField parses a cron field which is a list of "*", a value or a range with an optional step
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return nil, fmt.Errorf("the step of %q is invalid", part)
+			}
+			rangePart, step = part[:i], s
+		}
+
+		start, end := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("the range of %q is invalid", part)
+			}
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("the range of %q is invalid", part)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return nil, fmt.Errorf("the value of %q is invalid", part)
+			}
+			start = value
+			if step == 1 {
+				end = value
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("the %q is out of range [%d, %d]", part, min, max)
+		}
+		for v := start; v <= end; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom, dow := s.doms[t.Day()], s.dows[int(t.Weekday())]
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// next returns the first time matched by the schedule after the given time, it returns a zero time if there is
+// no matched time in the next five years, e.g. "0 0 30 2 *".
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !s.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
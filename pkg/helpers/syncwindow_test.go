@@ -0,0 +1,231 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestParseImportSyncWindows(t *testing.T) {
+	cases := []struct {
+		name            string
+		value           string
+		expectedWindows int
+		expectedErr     string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:            "multiple windows",
+			value:           "0 2 * * 6 4h; */30 22-23 1,15 * 1-5 30m;",
+			expectedWindows: 2,
+		},
+		{
+			name:        "missing duration",
+			value:       "0 2 * * 6",
+			expectedErr: "expected 5 cron fields and a duration",
+		},
+		{
+			name:        "invalid duration",
+			value:       "0 2 * * 6 4x",
+			expectedErr: "unknown unit",
+		},
+		{
+			name:        "too long duration",
+			value:       "0 2 * * 6 200h",
+			expectedErr: "the duration must be between",
+		},
+		{
+			name:        "out of range",
+			value:       "0 24 * * * 1h",
+			expectedErr: "out of range",
+		},
+		{
+			name:        "invalid step",
+			value:       "*/0 * * * * 1h",
+			expectedErr: "the step of",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			windows, err := ParseImportSyncWindows(c.value)
+			if len(c.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), c.expectedErr) {
+					t.Errorf("expected error %q, but got %v", c.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if len(windows) != c.expectedWindows {
+				t.Errorf("expected %d windows, but got %d", c.expectedWindows, len(windows))
+			}
+		})
+	}
+}
+
+func TestImportSyncWindowsOpen(t *testing.T) {
+	// 2024-06-01 is a Saturday
+	saturday := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name         string
+		value        string
+		now          time.Time
+		expectedOpen bool
+		expectedNext time.Time
+	}{
+		{
+			name:         "no window",
+			now:          saturday,
+			expectedOpen: true,
+		},
+		{
+			name:         "in the window",
+			value:        "0 2 * * 6 4h",
+			now:          saturday.Add(5 * time.Hour),
+			expectedOpen: true,
+		},
+		{
+			name:         "before the window",
+			value:        "0 2 * * 6 4h",
+			now:          saturday.Add(time.Hour),
+			expectedNext: saturday.Add(2 * time.Hour),
+		},
+		{
+			name:         "after the window",
+			value:        "0 2 * * 6 4h",
+			now:          saturday.Add(6 * time.Hour),
+			expectedNext: saturday.AddDate(0, 0, 7).Add(2 * time.Hour),
+		},
+		{
+			name:         "window across midnight",
+			value:        "0 22 * * 5 4h",
+			now:          saturday.Add(time.Hour),
+			expectedOpen: true,
+		},
+		{
+			name:         "the earliest next window",
+			value:        "0 2 * * 0 1h; 30 23 1 6 * 1h",
+			now:          saturday.Add(time.Hour),
+			expectedNext: saturday.Add(23*time.Hour + 30*time.Minute),
+		},
+		{
+			name:         "day of month or day of week",
+			value:        "0 3 15 * 0 1h",
+			now:          saturday.Add(time.Hour),
+			expectedNext: saturday.AddDate(0, 0, 1).Add(3 * time.Hour),
+		},
+		{
+			name:         "sunday is 7",
+			value:        "0 3 * * 7 1h",
+			now:          saturday.Add(time.Hour),
+			expectedNext: saturday.AddDate(0, 0, 1).Add(3 * time.Hour),
+		},
+		{
+			name:  "never",
+			value: "0 0 30 2 * 1h",
+			now:   saturday,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			windows, err := ParseImportSyncWindows(c.value)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			open, next := ImportSyncWindowsOpen(windows, c.now)
+			if open != c.expectedOpen {
+				t.Errorf("expected open %v, but got %v", c.expectedOpen, open)
+			}
+			if !next.Equal(c.expectedNext) {
+				t.Errorf("expected next %s, but got %s", c.expectedNext, next)
+			}
+		})
+	}
+}
+
+func TestImportSyncWindowsGetter(t *testing.T) {
+	cases := []struct {
+		name            string
+		data            map[string]string
+		annotations     map[string]string
+		expectedWindows int
+	}{
+		{
+			name: "no configmap",
+		},
+		{
+			name:            "configmap",
+			data:            map[string]string{"autoImportSyncWindows": "0 2 * * 6 4h"},
+			expectedWindows: 1,
+		},
+		{
+			name: "invalid configmap",
+			data: map[string]string{"autoImportSyncWindows": "invalid"},
+		},
+		{
+			name: "annotation overrides configmap",
+			data: map[string]string{"autoImportSyncWindows": "0 2 * * 6 4h"},
+			annotations: map[string]string{
+				"import.open-cluster-management.io/auto-import-sync-windows": "0 2 * * 6 4h; 0 2 * * 0 4h",
+			},
+			expectedWindows: 2,
+		},
+		{
+			name: "empty annotation",
+			data: map[string]string{"autoImportSyncWindows": "0 2 * * 6 4h"},
+			annotations: map[string]string{
+				"import.open-cluster-management.io/auto-import-sync-windows": "",
+			},
+		},
+		{
+			name: "invalid annotation",
+			data: map[string]string{"autoImportSyncWindows": "0 2 * * 6 4h"},
+			annotations: map[string]string{
+				"import.open-cluster-management.io/auto-import-sync-windows": "invalid",
+			},
+			expectedWindows: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeInformerFactory := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 10*time.Minute)
+			if c.data != nil {
+				if err := kubeInformerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "import-controller-config", Namespace: "test"},
+					Data:       c.data,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			windows, err := ImportSyncWindowsGetter("test", kubeInformerFactory.Core().V1().ConfigMaps().Lister(),
+				logf.Log.WithName("import-sync-windows-getter"))(&clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: c.annotations},
+			})
+			if err != nil {
+				t.Errorf("unexpected err %v", err)
+			}
+			if len(windows) != c.expectedWindows {
+				t.Errorf("expected %d windows, but got %d", c.expectedWindows, len(windows))
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+type ClusterTakeoverPolicyGetterFunc func() (policy string, err error)
+
+// ClusterTakeoverPolicyGetter returns the policy of importing a managed cluster which is registered to another hub
+func ClusterTakeoverPolicyGetter(componentNamespace string, configMapLister corev1listers.ConfigMapLister,
+	log logr.Logger) ClusterTakeoverPolicyGetterFunc {
+	return func() (string, error) {
+		cm, err := configMapLister.ConfigMaps(componentNamespace).Get(constants.ControllerConfigConfigMapName)
+		if errors.IsNotFound(err) {
+			return constants.DefaultClusterTakeoverPolicy, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		policy := cm.Data[constants.ClusterTakeoverPolicyKey]
+		switch policy {
+		case constants.ClusterTakeoverPolicyRefuse, constants.ClusterTakeoverPolicyWarn,
+			constants.ClusterTakeoverPolicyTakeover:
+			return policy, nil
+		case "":
+			return constants.DefaultClusterTakeoverPolicy, nil
+		default:
+			log.Info("Invalid config value found and use default instead.",
+				"configmap", constants.ControllerConfigConfigMapName,
+				constants.ClusterTakeoverPolicyKey, policy,
+				"default", constants.DefaultClusterTakeoverPolicy)
+			return constants.DefaultClusterTakeoverPolicy, nil
+		}
+	}
+}
+
+// checkClusterTakeover detects whether the managed cluster is registered to another hub before applying the
+// importing resources, the detection is handled by the cluster takeover policy. It returns the message of the
+// detection if the managed cluster is registered to another hub, and whether the import is refused.
+func (i *ImportHelper) checkClusterTakeover(cluster *clusterv1.ManagedCluster, clientHolder *ClientHolder,
+	importSecret *corev1.Secret) (string, bool, error) {
+	if i.clusterTakeoverPolicyGetter == nil {
+		return "", false, nil
+	}
+
+	policy, err := i.clusterTakeoverPolicyGetter()
+	if err != nil {
+		return "", false, err
+	}
+
+	klusterletName, anotherHubServer, err := GetAnotherRegisteredHubServer(context.TODO(), clientHolder,
+		importSecretObjects(importSecret))
+	if err != nil {
+		if policy == constants.ClusterTakeoverPolicyRefuse {
+			return "", false, err
+		}
+
+		i.log.Info("Failed to detect whether the managed cluster is registered to another hub",
+			"managedCluster", cluster.Name, "error", err.Error())
+		return "", false, nil
+	}
+	if len(anotherHubServer) == 0 {
+		return "", false, nil
+	}
+
+	message := fmt.Sprintf("The klusterlet %s is registered to another hub %s", klusterletName, anotherHubServer)
+	i.log.Info(message, "managedCluster", cluster.Name, "clusterTakeoverPolicy", policy)
+
+	if i.mcRecorder != nil {
+		mc := cluster.DeepCopy()
+		mc.SetNamespace(mc.Name)
+		switch policy {
+		case constants.ClusterTakeoverPolicyRefuse:
+			i.mcRecorder.Eventf(mc, nil, corev1.EventTypeWarning,
+				constants.EventReasonManagedClusterRegisteredToAnotherHub, constants.EventActionTakeoverRefused,
+				"%s, the import of %s is refused", message, mc.Name)
+		case constants.ClusterTakeoverPolicyWarn:
+			i.mcRecorder.Eventf(mc, nil, corev1.EventTypeWarning,
+				constants.EventReasonManagedClusterRegisteredToAnotherHub, constants.EventActionTakeover,
+				"%s, the %s is taken over", message, mc.Name)
+		default:
+			i.mcRecorder.Eventf(mc, nil, corev1.EventTypeNormal,
+				constants.EventReasonManagedClusterRegisteredToAnotherHub, constants.EventActionTakeover,
+				"%s, the %s is taken over", message, mc.Name)
+		}
+	}
+
+	return message, policy == constants.ClusterTakeoverPolicyRefuse, nil
+}
+
+// GetAnotherRegisteredHubServer finds the klusterlet and the bootstrap hub kubeconfig from the importing resources,
+// and checks whether the klusterlet on the managed cluster is registered to another hub. It returns the name of the
+// klusterlet and the server address of another hub, the server address is empty if the klusterlet is not registered
+// or is registered to current hub.
+func GetAnotherRegisteredHubServer(ctx context.Context, clientHolder *ClientHolder,
+	objs []runtime.Object) (string, string, error) {
+	var required *operatorv1.Klusterlet
+	var bootstrapSecret *corev1.Secret
+	// the bootstrap kubeconfig secrets of the additional hub kube apiserver URLs
+	endpointBootstrapSecrets := []*corev1.Secret{}
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *operatorv1.Klusterlet:
+			required = o
+		case *corev1.Secret:
+			if o.Name == constants.DefaultBootstrapHubKubeConfigSecretName {
+				bootstrapSecret = o
+			}
+			if strings.HasPrefix(o.Name, constants.DefaultBootstrapHubKubeConfigSecretName+"-endpoint-") {
+				endpointBootstrapSecrets = append(endpointBootstrapSecrets, o)
+			}
+		}
+	}
+	if required == nil || bootstrapSecret == nil {
+		return "", "", nil
+	}
+
+	hub := newHubIdentity()
+	for _, secret := range append([]*corev1.Secret{bootstrapSecret}, endpointBootstrapSecrets...) {
+		if err := hub.add(secret.Data["kubeconfig"]); err != nil {
+			return "", "", fmt.Errorf("failed to get the hub server from the bootstrap hub kubeconfig %s: %v",
+				secret.Name, err)
+		}
+	}
+
+	registered, registeredHub, err := getKlusterletRegisteredHub(ctx, clientHolder, required)
+	if err != nil {
+		return "", "", err
+	}
+	if !registered || hub.matches(registeredHub) {
+		return required.Name, "", nil
+	}
+
+	return required.Name, registeredHub.Server, nil
+}
+
+// hubIdentity identifies the current hub with the servers and the CA certificates of the bootstrap hub kubeconfig.
+// The hub kubeconfig of the agent keeps the server which the agent registered with, so the CA certificates are
+// compared as well, otherwise the current hub is treated as another hub once its kube apiserver URL is changed.
+type hubIdentity struct {
+	servers sets.Set[string]
+	caCerts sets.Set[string]
+}
+
+func newHubIdentity() *hubIdentity {
+	return &hubIdentity{servers: sets.New[string](), caCerts: sets.New[string]()}
+}
+
+// add adds the servers and the CA certificates of all of the clusters in the bootstrap hub kubeconfig, it includes
+// the clusters of the additional hub kube apiserver URLs, which the agent may fail over to.
+func (h *hubIdentity) add(kubeconfig []byte) error {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return err
+	}
+	if len(config.Clusters) == 0 {
+		return fmt.Errorf("no cluster is found")
+	}
+	for _, cluster := range config.Clusters {
+		h.servers.Insert(cluster.Server)
+		h.caCerts.Insert(caCertificates(cluster.CertificateAuthorityData)...)
+	}
+	return nil
+}
+
+// matches checks whether the cluster of a hub kubeconfig is the current hub, it has one of the servers or trusts
+// one of the CA certificates of the current hub.
+func (h *hubIdentity) matches(cluster *clientcmdapi.Cluster) bool {
+	if h.servers.Has(cluster.Server) {
+		return true
+	}
+	return h.caCerts.HasAny(caCertificates(cluster.CertificateAuthorityData)...)
+}
+
+// caCertificates returns the raw data of the certificates in the CA bundle, the invalid CA bundle is ignored
+func caCertificates(caData []byte) []string {
+	if len(caData) == 0 {
+		return nil
+	}
+	certs, err := certutil.ParseCertsPEM(caData)
+	if err != nil {
+		return nil
+	}
+	raws := []string{}
+	for _, cert := range certs {
+		raws = append(raws, string(cert.Raw))
+	}
+	return raws
+}
+
+// getKlusterletRegisteredHub returns whether the klusterlet on the managed cluster is registered to a hub and the
+// cluster of the hub in the hub kubeconfig. The klusterlet is considered as registered if the klusterlet exists and
+// its hub kubeconfig secret is created.
+func getKlusterletRegisteredHub(ctx context.Context, clientHolder *ClientHolder,
+	required *operatorv1.Klusterlet) (bool, *clientcmdapi.Cluster, error) {
+	klusterlet, err := clientHolder.OperatorClient.OperatorV1().Klusterlets().Get(ctx, required.Name, metav1.GetOptions{})
+	if ResourceIsNotFound(err) {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get the klusterlet %s: %v", required.Name, err)
+	}
+
+	agentNamespace := klusterlet.Spec.Namespace
+	if len(agentNamespace) == 0 {
+		agentNamespace = constants.DefaultKlusterletNamespace
+	}
+
+	hubKubeConfigSecret, err := clientHolder.KubeClient.CoreV1().Secrets(agentNamespace).Get(ctx,
+		constants.HubKubeConfigSecretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get the hub kubeconfig secret %s/%s: %v",
+			agentNamespace, constants.HubKubeConfigSecretName, err)
+	}
+
+	kubeconfig, ok := hubKubeConfigSecret.Data["kubeconfig"]
+	if !ok {
+		return false, nil, nil
+	}
+
+	cluster, err := kubeconfigCluster(kubeconfig)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to get the hub server from the hub kubeconfig secret %s/%s: %v",
+			agentNamespace, constants.HubKubeConfigSecretName, err)
+	}
+	return true, cluster, nil
+}
+
+// importSecretObjects returns the importing resources of the import secret
+func importSecretObjects(importSecret *corev1.Secret) []runtime.Object {
+	objs := []runtime.Object{}
+	if val, ok := importSecret.Data[constants.ImportSecretCRDSYamlKey]; ok && len(val) > 0 {
+		objs = append(objs, MustCreateObject(val))
+	}
+	for _, yaml := range SplitYamls(importSecret.Data[constants.ImportSecretImportYamlKey]) {
+		if obj := MustCreateObject(yaml); obj != nil {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
+
+// kubeconfigCluster returns the cluster of the current context of the kubeconfig
+func kubeconfigCluster(kubeconfig []byte) (*clientcmdapi.Cluster, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	currentContext, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, fmt.Errorf("the current context %q is not found", config.CurrentContext)
+	}
+	cluster, ok := config.Clusters[currentContext.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("the cluster %q is not found", currentContext.Cluster)
+	}
+	return cluster, nil
+}
@@ -0,0 +1,304 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	kevents "k8s.io/client-go/tools/events"
+	operatorfake "open-cluster-management.io/api/client/operator/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
+)
+
+func TestClusterTakeoverPolicyGetter(t *testing.T) {
+	cases := []struct {
+		name           string
+		data           map[string]string
+		expectedPolicy string
+	}{
+		{
+			name:           "no configmap",
+			expectedPolicy: constants.DefaultClusterTakeoverPolicy,
+		},
+		{
+			name:           "invalid policy",
+			data:           map[string]string{"clusterTakeoverPolicy": "invalid"},
+			expectedPolicy: constants.DefaultClusterTakeoverPolicy,
+		},
+		{
+			name:           "refuse",
+			data:           map[string]string{"clusterTakeoverPolicy": "Refuse"},
+			expectedPolicy: constants.ClusterTakeoverPolicyRefuse,
+		},
+		{
+			name:           "takeover",
+			data:           map[string]string{"clusterTakeoverPolicy": "Takeover"},
+			expectedPolicy: constants.ClusterTakeoverPolicyTakeover,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeInformerFactory := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 10*time.Minute)
+			if c.data != nil {
+				if err := kubeInformerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "import-controller-config", Namespace: "test"},
+					Data:       c.data,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			policy, err := ClusterTakeoverPolicyGetter("test", kubeInformerFactory.Core().V1().ConfigMaps().Lister(),
+				logf.Log.WithName("cluster-takeover-policy-getter"))()
+			if err != nil {
+				t.Errorf("unexpected err %v", err)
+			}
+			if policy != c.expectedPolicy {
+				t.Errorf("expect %s, but got %s", c.expectedPolicy, policy)
+			}
+		})
+	}
+}
+
+func newTakeoverTestKubeconfig(t *testing.T, server string, caData []byte) []byte {
+	data, err := clientcmd.Write(clientcmdapi.Config{
+		Clusters:       map[string]*clientcmdapi.Cluster{"hub": {Server: server, CertificateAuthorityData: caData}},
+		Contexts:       map[string]*clientcmdapi.Context{"default": {Cluster: "hub"}},
+		CurrentContext: "default",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestCheckClusterTakeover(t *testing.T) {
+	hubCA, _, err := testinghelpers.NewRootCA("hub-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	anotherHubCA, _, err := testinghelpers.NewRootCA("another-hub-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	importSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-import", Namespace: "test"},
+		Data: map[string][]byte{
+			"import.yaml": []byte(fmt.Sprintf(preflightImportYaml,
+				base64.StdEncoding.EncodeToString(newTakeoverTestKubeconfig(t, "https://hub:6443", hubCA)))),
+		},
+	}
+
+	klusterlet := &operatorv1.Klusterlet{
+		ObjectMeta: metav1.ObjectMeta{Name: "klusterlet"},
+		Spec:       operatorv1.KlusterletSpec{Namespace: "open-cluster-management-agent"},
+	}
+	hubKubeConfigSecret := func(server string, caData []byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "hub-kubeconfig-secret", Namespace: "open-cluster-management-agent"},
+			Data:       map[string][]byte{"kubeconfig": newTakeoverTestKubeconfig(t, server, caData)},
+		}
+	}
+
+	cases := []struct {
+		name            string
+		policy          string
+		kubeObjs        []runtime.Object
+		operatorObjs    []runtime.Object
+		expectedMessage string
+		expectedRefused bool
+		expectedEvent   string
+	}{
+		{
+			name:   "no klusterlet",
+			policy: constants.ClusterTakeoverPolicyRefuse,
+		},
+		{
+			name:         "registered to current hub",
+			policy:       constants.ClusterTakeoverPolicyRefuse,
+			kubeObjs:     []runtime.Object{hubKubeConfigSecret("https://hub:6443", hubCA)},
+			operatorObjs: []runtime.Object{klusterlet},
+		},
+		{
+			name:         "registered to current hub with the previous server",
+			policy:       constants.ClusterTakeoverPolicyRefuse,
+			kubeObjs:     []runtime.Object{hubKubeConfigSecret("https://previous-hub:6443", hubCA)},
+			operatorObjs: []runtime.Object{klusterlet},
+		},
+		{
+			name:   "registered to another hub without ca",
+			policy: constants.ClusterTakeoverPolicyRefuse,
+			kubeObjs: []runtime.Object{
+				hubKubeConfigSecret("https://another-hub:6443", nil),
+			},
+			operatorObjs:    []runtime.Object{klusterlet},
+			expectedMessage: "The klusterlet klusterlet is registered to another hub https://another-hub:6443",
+			expectedRefused: true,
+			expectedEvent:   "Warning RegisteredToAnotherHub",
+		},
+		{
+			name:            "refuse",
+			policy:          constants.ClusterTakeoverPolicyRefuse,
+			kubeObjs:        []runtime.Object{hubKubeConfigSecret("https://another-hub:6443", anotherHubCA)},
+			operatorObjs:    []runtime.Object{klusterlet},
+			expectedMessage: "The klusterlet klusterlet is registered to another hub https://another-hub:6443",
+			expectedRefused: true,
+			expectedEvent:   "Warning RegisteredToAnotherHub",
+		},
+		{
+			name:            "warn",
+			policy:          constants.ClusterTakeoverPolicyWarn,
+			kubeObjs:        []runtime.Object{hubKubeConfigSecret("https://another-hub:6443", anotherHubCA)},
+			operatorObjs:    []runtime.Object{klusterlet},
+			expectedMessage: "The klusterlet klusterlet is registered to another hub https://another-hub:6443",
+			expectedEvent:   "Warning RegisteredToAnotherHub",
+		},
+		{
+			name:            "takeover",
+			policy:          constants.ClusterTakeoverPolicyTakeover,
+			kubeObjs:        []runtime.Object{hubKubeConfigSecret("https://another-hub:6443", anotherHubCA)},
+			operatorObjs:    []runtime.Object{klusterlet},
+			expectedMessage: "The klusterlet klusterlet is registered to another hub https://another-hub:6443",
+			expectedEvent:   "Normal RegisteredToAnotherHub",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mcRecorder := kevents.NewFakeRecorder(10)
+			importHelper := NewImportHelper(nil, nil, logf.Log.WithName("cluster-takeover")).
+				WithClusterTakeoverPolicy(func() (string, error) { return c.policy, nil }, mcRecorder)
+
+			message, refused, err := importHelper.checkClusterTakeover(
+				&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "test"}},
+				&ClientHolder{
+					KubeClient:     kubefake.NewSimpleClientset(c.kubeObjs...),
+					OperatorClient: operatorfake.NewSimpleClientset(c.operatorObjs...),
+				},
+				importSecret,
+			)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if message != c.expectedMessage {
+				t.Errorf("expected message %q, but got %q", c.expectedMessage, message)
+			}
+			if refused != c.expectedRefused {
+				t.Errorf("expected refused %v, but got %v", c.expectedRefused, refused)
+			}
+
+			select {
+			case event := <-mcRecorder.Events:
+				if !strings.HasPrefix(event, c.expectedEvent) || len(c.expectedEvent) == 0 {
+					t.Errorf("expected event %q, but got %q", c.expectedEvent, event)
+				}
+			default:
+				if len(c.expectedEvent) > 0 {
+					t.Errorf("expected event %q, but got none", c.expectedEvent)
+				}
+			}
+		})
+	}
+}
+
+func TestGetAnotherRegisteredHubServer(t *testing.T) {
+	klusterlet := &operatorv1.Klusterlet{
+		ObjectMeta: metav1.ObjectMeta{Name: "klusterlet"},
+		Spec:       operatorv1.KlusterletSpec{Namespace: "open-cluster-management-agent"},
+	}
+	hubKubeConfigSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "hub-kubeconfig-secret", Namespace: "open-cluster-management-agent"},
+		Data:       map[string][]byte{"kubeconfig": newTakeoverTestKubeconfig(t, "https://hub-2:6443", nil)},
+	}
+	bootstrapSecret := func(name string, kubeconfig []byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "open-cluster-management-agent"},
+			Data:       map[string][]byte{"kubeconfig": kubeconfig},
+		}
+	}
+
+	// the bootstrap kubeconfig with additional hub kube apiserver URLs in the default-context-N contexts
+	multipleURLsKubeconfig, err := clientcmd.Write(clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"hub":   {Server: "https://hub:6443"},
+			"hub-1": {Server: "https://hub-2:6443"},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			"default-context":   {Cluster: "hub"},
+			"default-context-1": {Cluster: "hub-1"},
+		},
+		CurrentContext: "default-context",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name           string
+		objs           []runtime.Object
+		expectedServer string
+	}{
+		{
+			name: "registered to another hub",
+			objs: []runtime.Object{
+				klusterlet,
+				bootstrapSecret("bootstrap-hub-kubeconfig", newTakeoverTestKubeconfig(t, "https://hub:6443", nil)),
+			},
+			expectedServer: "https://hub-2:6443",
+		},
+		{
+			name: "registered to an additional url of the bootstrap kubeconfig",
+			objs: []runtime.Object{
+				klusterlet,
+				bootstrapSecret("bootstrap-hub-kubeconfig", multipleURLsKubeconfig),
+			},
+		},
+		{
+			name: "registered to the url of an endpoint bootstrap kubeconfig",
+			objs: []runtime.Object{
+				klusterlet,
+				bootstrapSecret("bootstrap-hub-kubeconfig", newTakeoverTestKubeconfig(t, "https://hub:6443", nil)),
+				bootstrapSecret("bootstrap-hub-kubeconfig-endpoint-1",
+					newTakeoverTestKubeconfig(t, "https://hub-2:6443", nil)),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			name, server, err := GetAnotherRegisteredHubServer(context.TODO(), &ClientHolder{
+				KubeClient:     kubefake.NewSimpleClientset(hubKubeConfigSecret),
+				OperatorClient: operatorfake.NewSimpleClientset(klusterlet),
+			}, c.objs)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if name != "klusterlet" {
+				t.Errorf("expected klusterlet, but got %q", name)
+			}
+			if server != c.expectedServer {
+				t.Errorf("expected server %q, but got %q", c.expectedServer, server)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// BootstrapTokenPolicy is the policy of the tokens in the bootstrap kubeconfigs
+type BootstrapTokenPolicy struct {
+	// Lifetime is the requested lifetime of the token
+	Lifetime time.Duration
+	// RefreshRatio is the ratio of the token lifetime, the token is refreshed when the ratio of its lifetime remains
+	RefreshRatio float64
+	// Ephemeral means the token is revoked once the managed cluster is imported
+	Ephemeral bool
+}
+
+// RefreshThreshold returns how long before the token expires to refresh it. If the lifetime of the token is
+// unknown, the lifetime of the policy is used.
+func (p BootstrapTokenPolicy) RefreshThreshold(lifetime time.Duration) time.Duration {
+	if lifetime <= 0 {
+		lifetime = p.Lifetime
+	}
+	return time.Duration(float64(lifetime) * p.RefreshRatio)
+}
+
+// DefaultBootstrapTokenPolicy returns the policy which requests the tokens with 360 days lifetime and refreshes
+// them when 1/5 of their lifetime remains.
+func DefaultBootstrapTokenPolicy() BootstrapTokenPolicy {
+	return BootstrapTokenPolicy{
+		Lifetime:     constants.DefaultSecretTokenExpirationSecond * time.Second,
+		RefreshRatio: constants.DefaultSecretTokenRefreshRatio,
+	}
+}
+
+type BootstrapTokenPolicyGetterFunc func(
+	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig) (BootstrapTokenPolicy, error)
+
+// BootstrapTokenPolicyGetter returns the bootstrap token policy of a KlusterletConfig. The policy is specified by
+// the bootstrapTokenLifetime, bootstrapTokenRefreshRatio and bootstrapTokenEphemeral in the import-controller-config
+// ConfigMap, and they can be overridden by the annotations of the KlusterletConfig.
+func BootstrapTokenPolicyGetter(componentNamespace string, configMapLister corev1listers.ConfigMapLister,
+	log logr.Logger) BootstrapTokenPolicyGetterFunc {
+	return func(klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig) (BootstrapTokenPolicy, error) {
+		policy := DefaultBootstrapTokenPolicy()
+
+		cm, err := configMapLister.ConfigMaps(componentNamespace).Get(constants.ControllerConfigConfigMapName)
+		if err != nil && !errors.IsNotFound(err) {
+			return policy, err
+		}
+		if err == nil {
+			policy = overrideBootstrapTokenPolicy(policy, cm.Data, map[string]string{
+				constants.BootstrapTokenLifetimeKey:     constants.BootstrapTokenLifetimeKey,
+				constants.BootstrapTokenRefreshRatioKey: constants.BootstrapTokenRefreshRatioKey,
+				constants.BootstrapTokenEphemeralKey:    constants.BootstrapTokenEphemeralKey,
+			}, log.WithValues("configmap", constants.ControllerConfigConfigMapName))
+		}
+
+		if klusterletConfig != nil {
+			policy = overrideBootstrapTokenPolicy(policy, klusterletConfig.Annotations, map[string]string{
+				constants.BootstrapTokenLifetimeKey:     constants.AnnotationBootstrapTokenLifetime,
+				constants.BootstrapTokenRefreshRatioKey: constants.AnnotationBootstrapTokenRefreshRatio,
+				constants.BootstrapTokenEphemeralKey:    constants.AnnotationBootstrapTokenEphemeral,
+			}, log.WithValues("klusterletconfig", klusterletConfig.Name))
+		}
+
+		return policy, nil
+	}
+}
+
+// overrideBootstrapTokenPolicy overrides the policy with the values of the given keys, the invalid values are
+// ignored.
+func overrideBootstrapTokenPolicy(policy BootstrapTokenPolicy, data map[string]string, keys map[string]string,
+	log logr.Logger) BootstrapTokenPolicy {
+	if value, ok := data[keys[constants.BootstrapTokenLifetimeKey]]; ok {
+		if err := ValidateBootstrapTokenLifetime(value); err == nil {
+			policy.Lifetime, _ = time.ParseDuration(value)
+		} else {
+			log.Info("Invalid bootstrap token config value found and ignore it.",
+				keys[constants.BootstrapTokenLifetimeKey], value, "error", err.Error())
+		}
+	}
+
+	if value, ok := data[keys[constants.BootstrapTokenRefreshRatioKey]]; ok {
+		if err := ValidateBootstrapTokenRefreshRatio(value); err == nil {
+			policy.RefreshRatio, _ = strconv.ParseFloat(value, 64)
+		} else {
+			log.Info("Invalid bootstrap token config value found and ignore it.",
+				keys[constants.BootstrapTokenRefreshRatioKey], value, "error", err.Error())
+		}
+	}
+
+	if value, ok := data[keys[constants.BootstrapTokenEphemeralKey]]; ok {
+		if ephemeral, err := strconv.ParseBool(value); err == nil {
+			policy.Ephemeral = ephemeral
+		} else {
+			log.Info("Invalid bootstrap token config value found and ignore it.",
+				keys[constants.BootstrapTokenEphemeralKey], value, "error", err.Error())
+		}
+	}
+
+	return policy
+}
+
+// ValidateBootstrapTokenLifetime validates the lifetime of the bootstrap token is a duration which is at least
+// 10 minutes, the minimum of the token request.
+func ValidateBootstrapTokenLifetime(value string) error {
+	lifetime, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if lifetime < constants.MinSecretTokenLifetime {
+		return fmt.Errorf("the bootstrap token lifetime %s is less than %s", value, constants.MinSecretTokenLifetime)
+	}
+	return nil
+}
+
+// ValidateBootstrapTokenRefreshRatio validates the refresh ratio of the bootstrap token is a number which is
+// greater than 0 and less than 1.
+func ValidateBootstrapTokenRefreshRatio(value string) error {
+	ratio, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	if ratio <= 0 || ratio >= 1 {
+		return fmt.Errorf("the bootstrap token refresh ratio %s is not greater than 0 and less than 1", value)
+	}
+	return nil
+}
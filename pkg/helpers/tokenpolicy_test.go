@@ -0,0 +1,122 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestBootstrapTokenPolicyGetter(t *testing.T) {
+	cases := []struct {
+		name             string
+		configMap        *corev1.ConfigMap
+		klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig
+		expectedPolicy   BootstrapTokenPolicy
+	}{
+		{
+			name:           "default policy",
+			expectedPolicy: DefaultBootstrapTokenPolicy(),
+		},
+		{
+			name: "controller config",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: constants.ControllerConfigConfigMapName, Namespace: "test"},
+				Data: map[string]string{
+					constants.BootstrapTokenLifetimeKey:     "720h",
+					constants.BootstrapTokenRefreshRatioKey: "0.5",
+					constants.BootstrapTokenEphemeralKey:    "true",
+				},
+			},
+			expectedPolicy: BootstrapTokenPolicy{Lifetime: 720 * time.Hour, RefreshRatio: 0.5, Ephemeral: true},
+		},
+		{
+			name: "klusterletconfig overrides controller config",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: constants.ControllerConfigConfigMapName, Namespace: "test"},
+				Data: map[string]string{
+					constants.BootstrapTokenLifetimeKey:  "720h",
+					constants.BootstrapTokenEphemeralKey: "true",
+				},
+			},
+			klusterletConfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+					Annotations: map[string]string{
+						constants.AnnotationBootstrapTokenLifetime:  "24h",
+						constants.AnnotationBootstrapTokenEphemeral: "false",
+					},
+				},
+			},
+			expectedPolicy: BootstrapTokenPolicy{
+				Lifetime:     24 * time.Hour,
+				RefreshRatio: constants.DefaultSecretTokenRefreshRatio,
+			},
+		},
+		{
+			name: "invalid values",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: constants.ControllerConfigConfigMapName, Namespace: "test"},
+				Data: map[string]string{
+					constants.BootstrapTokenLifetimeKey:     "1m",
+					constants.BootstrapTokenRefreshRatioKey: "1",
+				},
+			},
+			klusterletConfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+					Annotations: map[string]string{
+						constants.AnnotationBootstrapTokenRefreshRatio: "abc",
+						constants.AnnotationBootstrapTokenEphemeral:    "abc",
+					},
+				},
+			},
+			expectedPolicy: DefaultBootstrapTokenPolicy(),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactory(kubeClient, 0)
+			if c.configMap != nil {
+				if err := informerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(c.configMap); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			policy, err := BootstrapTokenPolicyGetter("test", informerFactory.Core().V1().ConfigMaps().Lister(),
+				logf.Log)(c.klusterletConfig)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if policy != c.expectedPolicy {
+				t.Errorf("expected %v, but got %v", c.expectedPolicy, policy)
+			}
+		})
+	}
+}
+
+func TestBootstrapTokenPolicyRefreshThreshold(t *testing.T) {
+	policy := BootstrapTokenPolicy{Lifetime: 10 * time.Hour, RefreshRatio: 0.5}
+	if threshold := policy.RefreshThreshold(2 * time.Hour); threshold != time.Hour {
+		t.Errorf("expected 1h, but got %s", threshold)
+	}
+	if threshold := policy.RefreshThreshold(0); threshold != 5*time.Hour {
+		t.Errorf("expected 5h, but got %s", threshold)
+	}
+	if threshold := DefaultBootstrapTokenPolicy().RefreshThreshold(0); threshold != 72*24*time.Hour {
+		t.Errorf("expected 72 days, but got %s", threshold)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+const (
+	// BootstrapCredentialToken is the bootstrap token in the bootstrap kubeconfig of the import secret
+	BootstrapCredentialToken = "token"
+	// BootstrapCredentialCA is the CA bundle in the bootstrap kubeconfig of the import secret
+	BootstrapCredentialCA = "ca"
+
+	bootstrapCredentialStateValid    = "valid"
+	bootstrapCredentialStateExpiring = "expiring"
+	bootstrapCredentialStateExpired  = "expired"
+)
+
+var (
+	bootstrapCredentialClustersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "bootstrap_credential_clusters"),
+		"Number of managed clusters by bootstrap credential and expiry state.",
+		[]string{"credential", "state"}, nil,
+	)
+	bootstrapCredentialEarliestExpirationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "bootstrap_credential_earliest_expiration_timestamp_seconds"),
+		"The earliest expiration time of the bootstrap credential among the managed clusters.",
+		[]string{"credential"}, nil,
+	)
+
+	bootstrapCredentials = newBootstrapCredentialCollector()
+)
+
+// bootstrapCredentialCollector summarizes the expiration of the bootstrap credentials of the managed clusters when
+// the metrics are collected, so a credential moves to the expiring and expired states without being reconciled.
+type bootstrapCredentialCollector struct {
+	lock        sync.RWMutex
+	expirations map[string]map[string]time.Time
+	now         func() time.Time
+	// expiringWindow returns how long before the expiry a bootstrap credential is counted as expiring
+	expiringWindow func() time.Duration
+}
+
+func newBootstrapCredentialCollector() *bootstrapCredentialCollector {
+	return &bootstrapCredentialCollector{
+		expirations:    map[string]map[string]time.Time{},
+		now:            time.Now,
+		expiringWindow: func() time.Duration { return constants.DefaultCAExpiryWarningThreshold },
+	}
+}
+
+func (c *bootstrapCredentialCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- bootstrapCredentialClustersDesc
+	ch <- bootstrapCredentialEarliestExpirationDesc
+}
+
+func (c *bootstrapCredentialCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	now := c.now()
+	expiringWindow := c.expiringWindow()
+	for _, credential := range []string{BootstrapCredentialToken, BootstrapCredentialCA} {
+		clusters := map[string]int{
+			bootstrapCredentialStateValid:    0,
+			bootstrapCredentialStateExpiring: 0,
+			bootstrapCredentialStateExpired:  0,
+		}
+		var earliest time.Time
+		for _, expirations := range c.expirations {
+			expiration, ok := expirations[credential]
+			if !ok {
+				continue
+			}
+
+			switch {
+			case !now.Before(expiration):
+				clusters[bootstrapCredentialStateExpired]++
+			case expiration.Sub(now) <= expiringWindow:
+				clusters[bootstrapCredentialStateExpiring]++
+			default:
+				clusters[bootstrapCredentialStateValid]++
+			}
+			if earliest.IsZero() || expiration.Before(earliest) {
+				earliest = expiration
+			}
+		}
+
+		for state, count := range clusters {
+			ch <- prometheus.MustNewConstMetric(bootstrapCredentialClustersDesc, prometheus.GaugeValue,
+				float64(count), credential, state)
+		}
+		if !earliest.IsZero() {
+			ch <- prometheus.MustNewConstMetric(bootstrapCredentialEarliestExpirationDesc, prometheus.GaugeValue,
+				float64(earliest.Unix()), credential)
+		}
+	}
+}
+
+func (c *bootstrapCredentialCollector) set(clusterName, credential string, expiration time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if expiration.IsZero() {
+		delete(c.expirations[clusterName], credential)
+		if len(c.expirations[clusterName]) == 0 {
+			delete(c.expirations, clusterName)
+		}
+		return
+	}
+
+	if _, ok := c.expirations[clusterName]; !ok {
+		c.expirations[clusterName] = map[string]time.Time{}
+	}
+	c.expirations[clusterName][credential] = expiration
+}
+
+func (c *bootstrapCredentialCollector) setExpiringWindow(expiringWindow func() time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.expiringWindow = expiringWindow
+}
+
+func (c *bootstrapCredentialCollector) delete(clusterName string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.expirations, clusterName)
+}
+
+// RecordBootstrapCredentialExpiration records when the bootstrap credential of the managed cluster expires, a zero
+// expiration means the credential does not expire and it is not counted.
+func RecordBootstrapCredentialExpiration(clusterName, credential string, expiration time.Time) {
+	bootstrapCredentials.set(clusterName, credential, expiration)
+}
+
+// SetBootstrapCredentialExpiringWindow sets how long before the expiry a bootstrap credential is counted as
+// expiring, it is called when the metrics are collected so the configuration changes take effect without restarting.
+func SetBootstrapCredentialExpiringWindow(expiringWindow func() time.Duration) {
+	bootstrapCredentials.setExpiringWindow(expiringWindow)
+}
+
+// DeleteBootstrapCredentialExpirations stops counting the bootstrap credentials of the managed cluster
+func DeleteBootstrapCredentialExpirations(clusterName string) {
+	bootstrapCredentials.delete(clusterName)
+}
@@ -0,0 +1,104 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "managedcluster_import"
+
+const (
+	// AutoImportResultSucceeded means the importing resources are applied on the managed cluster
+	AutoImportResultSucceeded = "succeeded"
+	// AutoImportResultFailed means the auto import secret is invalid or failed to apply the importing resources
+	AutoImportResultFailed = "failed"
+	// AutoImportResultRequeued means the import is not finished and will be retried
+	AutoImportResultRequeued = "requeued"
+)
+
+var (
+	// importConditionTransitions counts the ManagedClusterImportSucceeded condition transitions of the
+	// managed clusters by the condition reason. The ManagedClusterImporting, ManagedClusterImported and
+	// ManagedClusterImportFailed reasons represent the import attempts, successes and failures.
+	importConditionTransitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "condition_transitions_total",
+			Help:      "Number of ManagedClusterImportSucceeded condition transitions by reason.",
+		},
+		[]string{"reason"},
+	)
+
+	// importDuration observes the time from a managed cluster waiting for importing to imported.
+	importDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "duration_seconds",
+			Help:      "Time from a managed cluster waiting for importing to the managed cluster is imported.",
+			// 5s ~ 1.4h
+			Buckets: prometheus.ExponentialBuckets(5, 2, 11),
+		},
+	)
+
+	// autoImportTotal counts the auto import attempts by the auto-import-secret type and the result.
+	autoImportTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "auto_import_total",
+			Help:      "Number of auto import attempts by auto-import-secret type and result.",
+		},
+		[]string{"secret_type", "result"},
+	)
+
+	// detachDuration observes the time from a managed cluster is deleted to its resources are cleaned up.
+	detachDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "detach_duration_seconds",
+			Help:      "Time from a managed cluster is deleted to its resources are cleaned up.",
+			// 1s ~ 1.1h
+			Buckets: prometheus.ExponentialBuckets(1, 2, 13),
+		},
+	)
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		importConditionTransitions,
+		importDuration,
+		autoImportTotal,
+		detachDuration,
+		bootstrapCredentials,
+	)
+}
+
+// RecordImportConditionTransition records the managed cluster import condition is changed to the given reason
+func RecordImportConditionTransition(reason string) {
+	importConditionTransitions.WithLabelValues(reason).Inc()
+}
+
+// RecordImportDuration records the duration of importing a managed cluster since the given start time
+func RecordImportDuration(start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	importDuration.Observe(time.Since(start).Seconds())
+}
+
+// RecordAutoImport records an auto import attempt with the auto-import-secret type and the result
+func RecordAutoImport(secretType, result string) {
+	autoImportTotal.WithLabelValues(secretType, result).Inc()
+}
+
+// RecordDetachDuration records the duration of detaching a managed cluster since the given start time
+func RecordDetachDuration(start time.Time) {
+	if start.IsZero() {
+		return
+	}
+	detachDuration.Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,176 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package metrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	m := &dto.Metric{}
+	if err := c.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func histogramCount(t *testing.T, h prometheus.Histogram) uint64 {
+	m := &dto.Metric{}
+	if err := h.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestRecordImportConditionTransition(t *testing.T) {
+	reason := "ManagedClusterImportFailed"
+	before := counterValue(t, importConditionTransitions.WithLabelValues(reason))
+
+	RecordImportConditionTransition(reason)
+	RecordImportConditionTransition(reason)
+
+	if after := counterValue(t, importConditionTransitions.WithLabelValues(reason)); after-before != 2 {
+		t.Errorf("expected 2 transitions recorded, but got %v", after-before)
+	}
+}
+
+func TestRecordAutoImport(t *testing.T) {
+	secretType := "auto-import/kubeconfig"
+	before := counterValue(t, autoImportTotal.WithLabelValues(secretType, AutoImportResultSucceeded))
+
+	RecordAutoImport(secretType, AutoImportResultSucceeded)
+	RecordAutoImport(secretType, AutoImportResultFailed)
+
+	if after := counterValue(t, autoImportTotal.WithLabelValues(secretType, AutoImportResultSucceeded)); after-before != 1 {
+		t.Errorf("expected 1 succeeded auto import recorded, but got %v", after-before)
+	}
+}
+
+func TestRecordDuration(t *testing.T) {
+	cases := []struct {
+		name          string
+		record        func(time.Time)
+		histogram     prometheus.Histogram
+		start         time.Time
+		expectedCount uint64
+	}{
+		{
+			name:          "import duration",
+			record:        RecordImportDuration,
+			histogram:     importDuration,
+			start:         time.Now().Add(-1 * time.Minute),
+			expectedCount: 1,
+		},
+		{
+			name:          "import duration without start time",
+			record:        RecordImportDuration,
+			histogram:     importDuration,
+			expectedCount: 0,
+		},
+		{
+			name:          "detach duration",
+			record:        RecordDetachDuration,
+			histogram:     detachDuration,
+			start:         time.Now().Add(-10 * time.Second),
+			expectedCount: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			before := histogramCount(t, c.histogram)
+			c.record(c.start)
+			if after := histogramCount(t, c.histogram); after-before != c.expectedCount {
+				t.Errorf("expected %d observations, but got %d", c.expectedCount, after-before)
+			}
+		})
+	}
+}
+
+func TestBootstrapCredentialCollector(t *testing.T) {
+	now := time.Now()
+	collector := newBootstrapCredentialCollector()
+	collector.now = func() time.Time { return now }
+
+	collector.set("cluster1", BootstrapCredentialToken, now.Add(90*24*time.Hour))
+	collector.set("cluster1", BootstrapCredentialCA, now.Add(10*24*time.Hour))
+	collector.set("cluster2", BootstrapCredentialToken, now.Add(-1*time.Hour))
+	collector.set("cluster2", BootstrapCredentialCA, time.Time{})
+	collector.set("cluster3", BootstrapCredentialToken, now.Add(24*time.Hour))
+	collector.delete("cluster3")
+
+	clusters, earliest := collectBootstrapCredentials(t, collector)
+	expectedClusters := map[string]float64{
+		"token/valid":    1,
+		"token/expiring": 0,
+		"token/expired":  1,
+		"ca/valid":       0,
+		"ca/expiring":    1,
+		"ca/expired":     0,
+	}
+	if !reflect.DeepEqual(clusters, expectedClusters) {
+		t.Errorf("expected %v, but got %v", expectedClusters, clusters)
+	}
+	expectedEarliest := map[string]float64{
+		BootstrapCredentialToken: float64(now.Add(-1 * time.Hour).Unix()),
+		BootstrapCredentialCA:    float64(now.Add(10 * 24 * time.Hour).Unix()),
+	}
+	if !reflect.DeepEqual(earliest, expectedEarliest) {
+		t.Errorf("expected %v, but got %v", expectedEarliest, earliest)
+	}
+}
+
+func TestBootstrapCredentialCollectorExpiringWindow(t *testing.T) {
+	now := time.Now()
+	collector := newBootstrapCredentialCollector()
+	collector.now = func() time.Time { return now }
+	collector.setExpiringWindow(func() time.Duration { return 7 * 24 * time.Hour })
+
+	collector.set("cluster1", BootstrapCredentialCA, now.Add(10*24*time.Hour))
+	collector.set("cluster2", BootstrapCredentialCA, now.Add(5*24*time.Hour))
+
+	clusters, _ := collectBootstrapCredentials(t, collector)
+	expectedClusters := map[string]float64{
+		"token/valid":    0,
+		"token/expiring": 0,
+		"token/expired":  0,
+		"ca/valid":       1,
+		"ca/expiring":    1,
+		"ca/expired":     0,
+	}
+	if !reflect.DeepEqual(clusters, expectedClusters) {
+		t.Errorf("expected %v, but got %v", expectedClusters, clusters)
+	}
+}
+
+func collectBootstrapCredentials(t *testing.T, collector *bootstrapCredentialCollector) (map[string]float64,
+	map[string]float64) {
+	ch := make(chan prometheus.Metric, 10)
+	collector.Collect(ch)
+	close(ch)
+
+	clusters := map[string]float64{}
+	earliest := map[string]float64{}
+	for metric := range ch {
+		m := &dto.Metric{}
+		if err := metric.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		labels := map[string]string{}
+		for _, label := range m.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		if state, ok := labels["state"]; ok {
+			clusters[labels["credential"]+"/"+state] = m.GetGauge().GetValue()
+			continue
+		}
+		earliest[labels["credential"]] = m.GetGauge().GetValue()
+	}
+	return clusters, earliest
+}
@@ -0,0 +1,118 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+// KlusterletConfigValidator rejects the KlusterletConfigs whose spec or annotations cannot be used to generate the
+// klusterlet manifests.
+type KlusterletConfigValidator struct{}
+
+var _ admission.CustomValidator = &KlusterletConfigValidator{}
+
+func (v *KlusterletConfigValidator) ValidateCreate(ctx context.Context,
+	obj runtime.Object) (admission.Warnings, error) {
+	kc, ok := obj.(*klusterletconfigv1alpha1.KlusterletConfig)
+	if !ok {
+		return nil, fmt.Errorf("not a klusterletconfig object")
+	}
+	return nil, validateKlusterletConfig(nil, kc)
+}
+
+func (v *KlusterletConfigValidator) ValidateUpdate(ctx context.Context,
+	oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldKC, ok := oldObj.(*klusterletconfigv1alpha1.KlusterletConfig)
+	if !ok {
+		return nil, fmt.Errorf("not a klusterletconfig object")
+	}
+	newKC, ok := newObj.(*klusterletconfigv1alpha1.KlusterletConfig)
+	if !ok {
+		return nil, fmt.Errorf("not a klusterletconfig object")
+	}
+	return nil, validateKlusterletConfig(oldKC, newKC)
+}
+
+func (v *KlusterletConfigValidator) ValidateDelete(ctx context.Context,
+	obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateKlusterletConfig validates the spec and the annotations of the KlusterletConfig. On update, only the
+// changed spec and annotations are validated, so an existing invalid KlusterletConfig can still be updated, e.g.
+// its status is reported or its finalizers are removed.
+func validateKlusterletConfig(oldKC, newKC *klusterletconfigv1alpha1.KlusterletConfig) error {
+	errs := []error{}
+	if oldKC == nil || !equality.Semantic.DeepEqual(oldKC.Spec, newKC.Spec) {
+		if err := bootstrap.ValidateKlusterletConfigSpec(newKC.Spec); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	var oldAnnotations map[string]string
+	if oldKC != nil {
+		oldAnnotations = oldKC.Annotations
+	}
+	validators := []struct {
+		key      string
+		validate func(value string) error
+	}{
+		{key: constants.AnnotationBootstrapTokenLifetime, validate: helpers.ValidateBootstrapTokenLifetime},
+		{key: constants.AnnotationBootstrapTokenRefreshRatio, validate: helpers.ValidateBootstrapTokenRefreshRatio},
+		{key: constants.AnnotationBootstrapTokenEphemeral, validate: func(value string) error {
+			_, err := strconv.ParseBool(value)
+			return err
+		}},
+		{key: constants.AnnotationHubKubeAPIServerAdditionalURLs, validate: func(value string) error {
+			_, err := bootstrap.GetAdditionalKubeAPIServerURLs(newKC)
+			return err
+		}},
+		{key: constants.AnnotationKlusterletConfigManagedClusterSelector, validate: func(value string) error {
+			_, err := helpers.GetKlusterletConfigSelector(newKC)
+			return err
+		}},
+		{key: constants.AnnotationKlusterletConfigManagedClusterSet, validate: func(value string) error {
+			_, err := helpers.GetKlusterletConfigSelector(newKC)
+			return err
+		}},
+		{key: constants.AnnotationKlusterletConfigPriority, validate: func(value string) error {
+			_, err := helpers.ValidateKlusterletConfigPriority(value)
+			return err
+		}},
+	}
+	for _, v := range validators {
+		if !annotationChanged(oldAnnotations, newKC.Annotations, v.key) {
+			continue
+		}
+		if err := v.validate(newKC.Annotations[v.key]); err != nil {
+			errs = append(errs, fmt.Errorf("invalid annotation %s: %v", v.key, err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// annotationChanged returns true if the annotation is set in the new annotations and it is changed
+func annotationChanged(oldAnnotations, newAnnotations map[string]string, key string) bool {
+	newValue, ok := newAnnotations[key]
+	if !ok {
+		return false
+	}
+	oldValue, ok := oldAnnotations[key]
+	return !ok || oldValue != newValue
+}
@@ -0,0 +1,136 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package webhook
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestValidateKlusterletConfig(t *testing.T) {
+	invalidSpec := klusterletconfigv1alpha1.KlusterletConfigSpec{
+		AppliedManifestWorkEvictionGracePeriod: "1 day",
+	}
+
+	cases := []struct {
+		name          string
+		oldKC         *klusterletconfigv1alpha1.KlusterletConfig
+		newKC         *klusterletconfigv1alpha1.KlusterletConfig
+		expectedError string
+	}{
+		{
+			name: "create a valid klusterletconfig",
+			newKC: newKlusterletConfig(klusterletconfigv1alpha1.KlusterletConfigSpec{}, map[string]string{
+				constants.AnnotationBootstrapTokenLifetime:         "1h",
+				constants.AnnotationBootstrapTokenRefreshRatio:     "0.5",
+				constants.AnnotationBootstrapTokenEphemeral:        "true",
+				constants.AnnotationHubKubeAPIServerAdditionalURLs: "https://api.example.com:6443",
+			}),
+		},
+		{
+			name:          "create a klusterletconfig with invalid spec",
+			newKC:         newKlusterletConfig(invalidSpec, nil),
+			expectedError: "parse appliedManifestWorkEvictionGracePeriod 1 day failed",
+		},
+		{
+			name: "create a klusterletconfig with invalid annotations",
+			newKC: newKlusterletConfig(klusterletconfigv1alpha1.KlusterletConfigSpec{}, map[string]string{
+				constants.AnnotationBootstrapTokenLifetime:         "1m",
+				constants.AnnotationBootstrapTokenRefreshRatio:     "2",
+				constants.AnnotationBootstrapTokenEphemeral:        "yes",
+				constants.AnnotationHubKubeAPIServerAdditionalURLs: "http://api.example.com:6443",
+			}),
+			expectedError: "invalid annotation " + constants.AnnotationBootstrapTokenLifetime,
+		},
+		{
+			name: "create a klusterletconfig with managed cluster selector",
+			newKC: newKlusterletConfig(klusterletconfigv1alpha1.KlusterletConfigSpec{}, map[string]string{
+				constants.AnnotationKlusterletConfigManagedClusterSelector: "env in (dev,test),!legacy",
+				constants.AnnotationKlusterletConfigManagedClusterSet:      "dev",
+				constants.AnnotationKlusterletConfigPriority:               "-10",
+			}),
+		},
+		{
+			name: "create a klusterletconfig with invalid managed cluster selector",
+			newKC: newKlusterletConfig(klusterletconfigv1alpha1.KlusterletConfigSpec{}, map[string]string{
+				constants.AnnotationKlusterletConfigManagedClusterSelector: "env in dev",
+			}),
+			expectedError: "invalid annotation " + constants.AnnotationKlusterletConfigManagedClusterSelector,
+		},
+		{
+			name: "create a klusterletconfig with invalid managed cluster set",
+			newKC: newKlusterletConfig(klusterletconfigv1alpha1.KlusterletConfigSpec{}, map[string]string{
+				constants.AnnotationKlusterletConfigManagedClusterSet: "dev/test",
+			}),
+			expectedError: "invalid annotation " + constants.AnnotationKlusterletConfigManagedClusterSet,
+		},
+		{
+			name: "create a klusterletconfig with invalid priority",
+			newKC: newKlusterletConfig(klusterletconfigv1alpha1.KlusterletConfigSpec{}, map[string]string{
+				constants.AnnotationKlusterletConfigPriority: "high",
+			}),
+			expectedError: "invalid annotation " + constants.AnnotationKlusterletConfigPriority,
+		},
+		{
+			name:  "update the status of an invalid klusterletconfig",
+			oldKC: newKlusterletConfig(invalidSpec, nil),
+			newKC: newKlusterletConfig(invalidSpec, map[string]string{
+				constants.AnnotationKlusterletConfigValidationErrors: "invalid",
+			}),
+		},
+		{
+			name: "update an invalid annotation",
+			oldKC: newKlusterletConfig(klusterletconfigv1alpha1.KlusterletConfigSpec{}, map[string]string{
+				constants.AnnotationBootstrapTokenLifetime: "1m",
+			}),
+			newKC: newKlusterletConfig(klusterletconfigv1alpha1.KlusterletConfigSpec{}, map[string]string{
+				constants.AnnotationBootstrapTokenLifetime: "2m",
+			}),
+			expectedError: "invalid annotation " + constants.AnnotationBootstrapTokenLifetime,
+		},
+	}
+
+	validator := &KlusterletConfigValidator{}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var err error
+			if c.oldKC == nil {
+				_, err = validator.ValidateCreate(context.TODO(), c.newKC)
+			} else {
+				_, err = validator.ValidateUpdate(context.TODO(), c.oldKC, c.newKC)
+			}
+			assertError(t, err, c.expectedError)
+		})
+	}
+}
+
+func newKlusterletConfig(spec klusterletconfigv1alpha1.KlusterletConfigSpec,
+	annotations map[string]string) *klusterletconfigv1alpha1.KlusterletConfig {
+	return &klusterletconfigv1alpha1.KlusterletConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Annotations: annotations,
+		},
+		Spec: spec,
+	}
+}
+
+func assertError(t *testing.T, err error, expectedError string) {
+	if len(expectedError) == 0 {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		return
+	}
+	if err == nil || !strings.Contains(err.Error(), expectedError) {
+		t.Errorf("expected error %q, but got %v", expectedError, err)
+	}
+}
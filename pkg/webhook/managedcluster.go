@@ -0,0 +1,121 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+)
+
+// klusterletNamespacePrefix is the required prefix of the klusterlet namespace annotation
+const klusterletNamespacePrefix = "open-cluster-management-"
+
+// ManagedClusterValidator rejects the ManagedClusters whose import annotations cannot be used to generate the
+// klusterlet manifests.
+type ManagedClusterValidator struct{}
+
+var _ admission.CustomValidator = &ManagedClusterValidator{}
+
+func (v *ManagedClusterValidator) ValidateCreate(ctx context.Context,
+	obj runtime.Object) (admission.Warnings, error) {
+	cluster, ok := obj.(*clusterv1.ManagedCluster)
+	if !ok {
+		return nil, fmt.Errorf("not a managedcluster object")
+	}
+	return nil, validateManagedClusterAnnotations(nil, cluster)
+}
+
+func (v *ManagedClusterValidator) ValidateUpdate(ctx context.Context,
+	oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldCluster, ok := oldObj.(*clusterv1.ManagedCluster)
+	if !ok {
+		return nil, fmt.Errorf("not a managedcluster object")
+	}
+	newCluster, ok := newObj.(*clusterv1.ManagedCluster)
+	if !ok {
+		return nil, fmt.Errorf("not a managedcluster object")
+	}
+	return nil, validateManagedClusterAnnotations(oldCluster, newCluster)
+}
+
+func (v *ManagedClusterValidator) ValidateDelete(ctx context.Context,
+	obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateManagedClusterAnnotations validates the import annotations of the ManagedCluster. On update, only the
+// changed annotations are validated, so an existing ManagedCluster with invalid annotations can still be updated.
+func validateManagedClusterAnnotations(oldCluster, newCluster *clusterv1.ManagedCluster) error {
+	var oldAnnotations map[string]string
+	if oldCluster != nil {
+		oldAnnotations = oldCluster.Annotations
+	}
+
+	validators := []struct {
+		key      string
+		validate func(value string) error
+	}{
+		{key: constants.KlusterletDeployModeAnnotation, validate: func(value string) error {
+			mode := helpers.DetermineKlusterletMode(newCluster)
+			if mode == "Unknown" {
+				return fmt.Errorf("the klusterlet deploy mode %q is not supported", value)
+			}
+			return helpers.ValidateKlusterletMode(mode)
+		}},
+		{key: constants.KlusterletNamespaceAnnotation, validate: validateKlusterletNamespace},
+		{key: helpers.NodeSelectorAnnotation, validate: func(value string) error {
+			nodeSelector, err := helpers.GetNodeSelectorFromManagedClusterAnnotations(newCluster.Annotations)
+			if err != nil {
+				return err
+			}
+			return helpers.ValidateNodeSelector(nodeSelector)
+		}},
+		{key: helpers.TolerationsAnnotation, validate: func(value string) error {
+			tolerations, err := helpers.GetTolerationsFromManagedClusterAnnotations(newCluster.Annotations)
+			if err != nil {
+				return err
+			}
+			return helpers.ValidateTolerations(tolerations)
+		}},
+		{key: imageregistry.ClusterImageRegistriesAnnotation, validate: func(value string) error {
+			return json.Unmarshal([]byte(value), &imageregistry.ImageRegistries{})
+		}},
+	}
+
+	errs := []error{}
+	for _, v := range validators {
+		if !annotationChanged(oldAnnotations, newCluster.Annotations, v.key) {
+			continue
+		}
+		if err := v.validate(newCluster.Annotations[v.key]); err != nil {
+			errs = append(errs, fmt.Errorf("invalid annotation %s: %v", v.key, err))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// validateKlusterletNamespace validates the klusterlet namespace has the prefix of "open-cluster-management-" and
+// it is a valid namespace name.
+func validateKlusterletNamespace(namespace string) error {
+	if !strings.HasPrefix(namespace, klusterletNamespacePrefix) {
+		return fmt.Errorf("the namespace %q does not have the prefix %q", namespace, klusterletNamespacePrefix)
+	}
+	if errMsgs := validation.IsDNS1123Label(namespace); len(errMsgs) != 0 {
+		return fmt.Errorf("%s", strings.Join(errMsgs, ";"))
+	}
+	return nil
+}
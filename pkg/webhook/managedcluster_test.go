@@ -0,0 +1,120 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+)
+
+func TestValidateManagedClusterAnnotations(t *testing.T) {
+	cases := []struct {
+		name          string
+		oldCluster    *clusterv1.ManagedCluster
+		newCluster    *clusterv1.ManagedCluster
+		expectedError string
+	}{
+		{
+			name: "valid annotations",
+			newCluster: newManagedCluster(map[string]string{
+				constants.KlusterletDeployModeAnnotation:       "Hosted",
+				constants.KlusterletNamespaceAnnotation:        "open-cluster-management-agent-test",
+				helpers.NodeSelectorAnnotation:                 `{"kubernetes.io/os":"linux"}`,
+				helpers.TolerationsAnnotation:                  `[{"key":"foo","operator":"Exists"}]`,
+				imageregistry.ClusterImageRegistriesAnnotation: `{"registries":[{"mirror":"quay.io/test"}]}`,
+			}),
+		},
+		{
+			name: "unknown deploy mode",
+			newCluster: newManagedCluster(map[string]string{
+				constants.KlusterletDeployModeAnnotation: "Unknown",
+			}),
+			expectedError: "invalid annotation " + constants.KlusterletDeployModeAnnotation,
+		},
+		{
+			name: "invalid klusterlet namespace prefix",
+			newCluster: newManagedCluster(map[string]string{
+				constants.KlusterletNamespaceAnnotation: "agent",
+			}),
+			expectedError: `the namespace "agent" does not have the prefix "open-cluster-management-"`,
+		},
+		{
+			name: "invalid klusterlet namespace",
+			newCluster: newManagedCluster(map[string]string{
+				constants.KlusterletNamespaceAnnotation: "open-cluster-management-Agent",
+			}),
+			expectedError: "invalid annotation " + constants.KlusterletNamespaceAnnotation,
+		},
+		{
+			name: "malformed node selector",
+			newCluster: newManagedCluster(map[string]string{
+				helpers.NodeSelectorAnnotation: `{"kubernetes.io/os":`,
+			}),
+			expectedError: "invalid annotation " + helpers.NodeSelectorAnnotation,
+		},
+		{
+			name: "invalid tolerations",
+			newCluster: newManagedCluster(map[string]string{
+				helpers.TolerationsAnnotation: `[{"key":"foo","operator":"In"}]`,
+			}),
+			expectedError: `the operator "In" is not supported`,
+		},
+		{
+			name: "bad image registries",
+			newCluster: newManagedCluster(map[string]string{
+				imageregistry.ClusterImageRegistriesAnnotation: `{"registries":"quay.io"}`,
+			}),
+			expectedError: "invalid annotation " + imageregistry.ClusterImageRegistriesAnnotation,
+		},
+		{
+			name: "update a cluster with unchanged invalid annotations",
+			oldCluster: newManagedCluster(map[string]string{
+				helpers.NodeSelectorAnnotation: `{"kubernetes.io/os":`,
+			}),
+			newCluster: newManagedCluster(map[string]string{
+				helpers.NodeSelectorAnnotation: `{"kubernetes.io/os":`,
+				"test":                         "test",
+			}),
+		},
+		{
+			name: "update an invalid annotation",
+			oldCluster: newManagedCluster(map[string]string{
+				helpers.TolerationsAnnotation: `[{"key":"foo","operator":"In"}]`,
+			}),
+			newCluster: newManagedCluster(map[string]string{
+				helpers.TolerationsAnnotation: `[{"key":"foo","operator":"NotIn"}]`,
+			}),
+			expectedError: `the operator "NotIn" is not supported`,
+		},
+	}
+
+	validator := &ManagedClusterValidator{}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var err error
+			if c.oldCluster == nil {
+				_, err = validator.ValidateCreate(context.TODO(), c.newCluster)
+			} else {
+				_, err = validator.ValidateUpdate(context.TODO(), c.oldCluster, c.newCluster)
+			}
+			assertError(t, err, c.expectedError)
+		})
+	}
+}
+
+func newManagedCluster(annotations map[string]string) *clusterv1.ManagedCluster {
+	return &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Annotations: annotations,
+		},
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package webhook
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+const (
+	// KlusterletConfigValidatingPath is the path of the validating webhook of KlusterletConfig
+	KlusterletConfigValidatingPath = "/validate-config-open-cluster-management-io-v1alpha1-klusterletconfig"
+	// ManagedClusterValidatingPath is the path of the validating webhook of ManagedCluster
+	ManagedClusterValidatingPath = "/validate-cluster-open-cluster-management-io-v1-managedcluster"
+)
+
+// Add registers the validating webhooks to the webhook server of the manager, the webhook server is started with
+// the manager.
+func Add(mgr manager.Manager) error {
+	server := mgr.GetWebhookServer()
+	server.Register(KlusterletConfigValidatingPath, admission.WithCustomValidator(mgr.GetScheme(),
+		&klusterletconfigv1alpha1.KlusterletConfig{}, &KlusterletConfigValidator{}))
+	server.Register(ManagedClusterValidatingPath, admission.WithCustomValidator(mgr.GetScheme(),
+		&clusterv1.ManagedCluster{}, &ManagedClusterValidator{}))
+	return nil
+}